@@ -24,14 +24,130 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type EnvMode int32
+
+const (
+	// legacy behavior: env replaces the server environment if not empty,
+	// otherwise the server environment and default env are inherited.
+	// Default env is not added to a non empty env either, clients wanting
+	// it under their env use ENV_INHERIT_OVERRIDE
+	EnvMode_ENV_DEFAULT EnvMode = 0
+	// server environment and default env, env of the command is ignored
+	EnvMode_ENV_INHERIT EnvMode = 1
+	// server environment and default env, overridden by env of the command
+	EnvMode_ENV_INHERIT_OVERRIDE EnvMode = 2
+	// only env of the command
+	EnvMode_ENV_CLEAN EnvMode = 3
+	// /etc/environment and the login profile, default env,
+	// overridden by env of the command
+	EnvMode_ENV_LOGIN EnvMode = 4
+)
+
+var EnvMode_name = map[int32]string{
+	0: "ENV_DEFAULT",
+	1: "ENV_INHERIT",
+	2: "ENV_INHERIT_OVERRIDE",
+	3: "ENV_CLEAN",
+	4: "ENV_LOGIN",
+}
+
+var EnvMode_value = map[string]int32{
+	"ENV_DEFAULT":          0,
+	"ENV_INHERIT":          1,
+	"ENV_INHERIT_OVERRIDE": 2,
+	"ENV_CLEAN":            3,
+	"ENV_LOGIN":            4,
+}
+
+func (x EnvMode) String() string {
+	return proto.EnumName(EnvMode_name, int32(x))
+}
+
+func (EnvMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{0}
+}
+
+// IoClass is the io scheduling class of ioprio_set
+type IoClass int32
+
+const (
+	// inherited, io priority follows the nice value
+	IoClass_IO_CLASS_NONE        IoClass = 0
+	IoClass_IO_CLASS_REALTIME    IoClass = 1
+	IoClass_IO_CLASS_BEST_EFFORT IoClass = 2
+	// io only when the disk is idle
+	IoClass_IO_CLASS_IDLE IoClass = 3
+)
+
+var IoClass_name = map[int32]string{
+	0: "IO_CLASS_NONE",
+	1: "IO_CLASS_REALTIME",
+	2: "IO_CLASS_BEST_EFFORT",
+	3: "IO_CLASS_IDLE",
+}
+
+var IoClass_value = map[string]int32{
+	"IO_CLASS_NONE":        0,
+	"IO_CLASS_REALTIME":    1,
+	"IO_CLASS_BEST_EFFORT": 2,
+	"IO_CLASS_IDLE":        3,
+}
+
+func (x IoClass) String() string {
+	return proto.EnumName(IoClass_name, int32(x))
+}
+
+func (IoClass) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{1}
+}
+
+type RestartPolicy int32
+
+const (
+	RestartPolicy_RESTART_NEVER  RestartPolicy = 0
+	RestartPolicy_RESTART_ALWAYS RestartPolicy = 1
+	// restart when exited non-zero, killed or failed to start
+	RestartPolicy_RESTART_ON_FAILURE RestartPolicy = 2
+)
+
+var RestartPolicy_name = map[int32]string{
+	0: "RESTART_NEVER",
+	1: "RESTART_ALWAYS",
+	2: "RESTART_ON_FAILURE",
+}
+
+var RestartPolicy_value = map[string]int32{
+	"RESTART_NEVER":      0,
+	"RESTART_ALWAYS":     1,
+	"RESTART_ON_FAILURE": 2,
+}
+
+func (x RestartPolicy) String() string {
+	return proto.EnumName(RestartPolicy_name, int32(x))
+}
+
+func (RestartPolicy) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{2}
+}
+
 type Command struct {
-	Path                 []byte   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Args                 [][]byte `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
-	Env                  [][]byte `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty"`
-	Dir                  []byte   `protobuf:"bytes,4,opt,name=dir,proto3" json:"dir,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Path    []byte   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Args    [][]byte `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	Env     [][]byte `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty"`
+	Dir     []byte   `protobuf:"bytes,4,opt,name=dir,proto3" json:"dir,omitempty"`
+	EnvMode EnvMode  `protobuf:"varint,5,opt,name=env_mode,json=envMode,proto3,enum=apis.EnvMode" json:"env_mode,omitempty"`
+	// name of a seccomp profile of the server, e.g. no-network or
+	// read-only-fs-syscalls, installed with no_new_privs before exec
+	SeccompProfile string `protobuf:"bytes,6,opt,name=seccomp_profile,json=seccompProfile,proto3" json:"seccomp_profile,omitempty"`
+	// user name or uid to run as, with its primary group and groups
+	User                 string        `protobuf:"bytes,7,opt,name=user,proto3" json:"user,omitempty"`
+	Capabilities         *Capabilities `protobuf:"bytes,8,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Sched                *Sched        `protobuf:"bytes,9,opt,name=sched,proto3" json:"sched,omitempty"`
+	Isolation            *Isolation    `protobuf:"bytes,10,opt,name=isolation,proto3" json:"isolation,omitempty"`
+	Landlock             *Landlock     `protobuf:"bytes,11,opt,name=landlock,proto3" json:"landlock,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *Command) Reset()         { *m = Command{} }
@@ -87,308 +203,768 @@ func (m *Command) GetDir() []byte {
 	return nil
 }
 
-type Input struct {
-	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
-	Input                []byte   `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *Command) GetEnvMode() EnvMode {
+	if m != nil {
+		return m.EnvMode
+	}
+	return EnvMode_ENV_DEFAULT
 }
 
-func (m *Input) Reset()         { *m = Input{} }
-func (m *Input) String() string { return proto.CompactTextString(m) }
-func (*Input) ProtoMessage()    {}
-func (*Input) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{1}
+func (m *Command) GetSeccompProfile() string {
+	if m != nil {
+		return m.SeccompProfile
+	}
+	return ""
 }
 
-func (m *Input) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Input.Unmarshal(m, b)
-}
-func (m *Input) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Input.Marshal(b, m, deterministic)
-}
-func (m *Input) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Input.Merge(m, src)
-}
-func (m *Input) XXX_Size() int {
-	return xxx_messageInfo_Input.Size(m)
+func (m *Command) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
 }
-func (m *Input) XXX_DiscardUnknown() {
-	xxx_messageInfo_Input.DiscardUnknown(m)
+
+func (m *Command) GetCapabilities() *Capabilities {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
 }
 
-var xxx_messageInfo_Input proto.InternalMessageInfo
+func (m *Command) GetSched() *Sched {
+	if m != nil {
+		return m.Sched
+	}
+	return nil
+}
 
-func (m *Input) GetSn() uint32 {
+func (m *Command) GetIsolation() *Isolation {
 	if m != nil {
-		return m.Sn
+		return m.Isolation
 	}
-	return 0
+	return nil
 }
 
-func (m *Input) GetInput() []byte {
+func (m *Command) GetLandlock() *Landlock {
 	if m != nil {
-		return m.Input
+		return m.Landlock
 	}
 	return nil
 }
 
-type Stdout struct {
-	Stdout               []byte   `protobuf:"bytes,1,opt,name=stdout,proto3" json:"stdout,omitempty"`
-	Closed               bool     `protobuf:"varint,2,opt,name=closed,proto3" json:"closed,omitempty"`
-	RuntimeError         []byte   `protobuf:"bytes,3,opt,name=runtime_error,json=runtimeError,proto3" json:"runtime_error,omitempty"`
-	Start                bool     `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+// Landlock limits file system access of commands with no_new_privs
+// set, files opened before exec, like stdin and stdout, are not limited
+type Landlock struct {
+	// read and execute below these paths
+	ReadPaths []string `protobuf:"bytes,1,rep,name=read_paths,json=readPaths,proto3" json:"read_paths,omitempty"`
+	// read, write, create and remove below these paths
+	WritePaths []string `protobuf:"bytes,2,rep,name=write_paths,json=writePaths,proto3" json:"write_paths,omitempty"`
+	// read and execute of system directories like /usr and /lib, and
+	// write of /dev/null, commands usually need them
+	SystemPaths bool `protobuf:"varint,3,opt,name=system_paths,json=systemPaths,proto3" json:"system_paths,omitempty"`
+	// fail to start unless enforced, instead of best effort
+	Required             bool     `protobuf:"varint,4,opt,name=required,proto3" json:"required,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Stdout) Reset()         { *m = Stdout{} }
-func (m *Stdout) String() string { return proto.CompactTextString(m) }
-func (*Stdout) ProtoMessage()    {}
-func (*Stdout) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{2}
+func (m *Landlock) Reset()         { *m = Landlock{} }
+func (m *Landlock) String() string { return proto.CompactTextString(m) }
+func (*Landlock) ProtoMessage()    {}
+func (*Landlock) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{1}
 }
 
-func (m *Stdout) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Stdout.Unmarshal(m, b)
+func (m *Landlock) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Landlock.Unmarshal(m, b)
 }
-func (m *Stdout) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Stdout.Marshal(b, m, deterministic)
+func (m *Landlock) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Landlock.Marshal(b, m, deterministic)
 }
-func (m *Stdout) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Stdout.Merge(m, src)
+func (m *Landlock) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Landlock.Merge(m, src)
 }
-func (m *Stdout) XXX_Size() int {
-	return xxx_messageInfo_Stdout.Size(m)
+func (m *Landlock) XXX_Size() int {
+	return xxx_messageInfo_Landlock.Size(m)
 }
-func (m *Stdout) XXX_DiscardUnknown() {
-	xxx_messageInfo_Stdout.DiscardUnknown(m)
+func (m *Landlock) XXX_DiscardUnknown() {
+	xxx_messageInfo_Landlock.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Stdout proto.InternalMessageInfo
+var xxx_messageInfo_Landlock proto.InternalMessageInfo
 
-func (m *Stdout) GetStdout() []byte {
+func (m *Landlock) GetReadPaths() []string {
 	if m != nil {
-		return m.Stdout
+		return m.ReadPaths
 	}
 	return nil
 }
 
-func (m *Stdout) GetClosed() bool {
+func (m *Landlock) GetWritePaths() []string {
 	if m != nil {
-		return m.Closed
+		return m.WritePaths
 	}
-	return false
+	return nil
 }
 
-func (m *Stdout) GetRuntimeError() []byte {
+func (m *Landlock) GetSystemPaths() bool {
 	if m != nil {
-		return m.RuntimeError
+		return m.SystemPaths
 	}
-	return nil
+	return false
 }
 
-func (m *Stdout) GetStart() bool {
+func (m *Landlock) GetRequired() bool {
 	if m != nil {
-		return m.Start
+		return m.Required
 	}
 	return false
 }
 
-type Stderr struct {
-	Stderr               []byte   `protobuf:"bytes,1,opt,name=stderr,proto3" json:"stderr,omitempty"`
-	Closed               bool     `protobuf:"varint,2,opt,name=closed,proto3" json:"closed,omitempty"`
-	RuntimeError         []byte   `protobuf:"bytes,3,opt,name=runtime_error,json=runtimeError,proto3" json:"runtime_error,omitempty"`
-	Start                bool     `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+type LandlockStatus struct {
+	// all restrictions enforced, false for best effort
+	Enforced bool `protobuf:"varint,1,opt,name=enforced,proto3" json:"enforced,omitempty"`
+	// landlock abi of the kernel, 0 if unsupported
+	Abi int32 `protobuf:"varint,2,opt,name=abi,proto3" json:"abi,omitempty"`
+	// what isn't restricted if not enforced
+	Reason               string   `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Stderr) Reset()         { *m = Stderr{} }
-func (m *Stderr) String() string { return proto.CompactTextString(m) }
-func (*Stderr) ProtoMessage()    {}
-func (*Stderr) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{3}
+func (m *LandlockStatus) Reset()         { *m = LandlockStatus{} }
+func (m *LandlockStatus) String() string { return proto.CompactTextString(m) }
+func (*LandlockStatus) ProtoMessage()    {}
+func (*LandlockStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{2}
 }
 
-func (m *Stderr) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Stderr.Unmarshal(m, b)
+func (m *LandlockStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LandlockStatus.Unmarshal(m, b)
 }
-func (m *Stderr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Stderr.Marshal(b, m, deterministic)
+func (m *LandlockStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LandlockStatus.Marshal(b, m, deterministic)
 }
-func (m *Stderr) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Stderr.Merge(m, src)
+func (m *LandlockStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LandlockStatus.Merge(m, src)
 }
-func (m *Stderr) XXX_Size() int {
-	return xxx_messageInfo_Stderr.Size(m)
+func (m *LandlockStatus) XXX_Size() int {
+	return xxx_messageInfo_LandlockStatus.Size(m)
 }
-func (m *Stderr) XXX_DiscardUnknown() {
-	xxx_messageInfo_Stderr.DiscardUnknown(m)
+func (m *LandlockStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_LandlockStatus.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Stderr proto.InternalMessageInfo
-
-func (m *Stderr) GetStderr() []byte {
-	if m != nil {
-		return m.Stderr
-	}
-	return nil
-}
+var xxx_messageInfo_LandlockStatus proto.InternalMessageInfo
 
-func (m *Stderr) GetClosed() bool {
+func (m *LandlockStatus) GetEnforced() bool {
 	if m != nil {
-		return m.Closed
+		return m.Enforced
 	}
 	return false
 }
 
-func (m *Stderr) GetRuntimeError() []byte {
+func (m *LandlockStatus) GetAbi() int32 {
 	if m != nil {
-		return m.RuntimeError
+		return m.Abi
 	}
-	return nil
+	return 0
 }
 
-func (m *Stderr) GetStart() bool {
+func (m *LandlockStatus) GetReason() string {
 	if m != nil {
-		return m.Start
+		return m.Reason
 	}
-	return false
-}
-
-type StartResponse struct {
-	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Error                []byte   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	return ""
+}
+
+// Isolation runs commands in new namespaces of their own
+type Isolation struct {
+	// network namespace with only loopback up
+	Net bool `protobuf:"varint,1,opt,name=net,proto3" json:"net,omitempty"`
+	// mount namespace, implied by pid, read_only_paths and private_tmp
+	Mount bool `protobuf:"varint,2,opt,name=mount,proto3" json:"mount,omitempty"`
+	// pid namespace with /proc remounted, the command is pid 1 and gets
+	// signals other than SIGKILL only if it handles them
+	Pid bool `protobuf:"varint,3,opt,name=pid,proto3" json:"pid,omitempty"`
+	// remounted read-only with the mounts below them, e.g. /
+	ReadOnlyPaths []string `protobuf:"bytes,4,rep,name=read_only_paths,json=readOnlyPaths,proto3" json:"read_only_paths,omitempty"`
+	// empty tmpfs on /tmp
+	PrivateTmp           bool     `protobuf:"varint,5,opt,name=private_tmp,json=privateTmp,proto3" json:"private_tmp,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StartResponse) Reset()         { *m = StartResponse{} }
-func (m *StartResponse) String() string { return proto.CompactTextString(m) }
-func (*StartResponse) ProtoMessage()    {}
-func (*StartResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{4}
+func (m *Isolation) Reset()         { *m = Isolation{} }
+func (m *Isolation) String() string { return proto.CompactTextString(m) }
+func (*Isolation) ProtoMessage()    {}
+func (*Isolation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{3}
 }
 
-func (m *StartResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StartResponse.Unmarshal(m, b)
+func (m *Isolation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Isolation.Unmarshal(m, b)
 }
-func (m *StartResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StartResponse.Marshal(b, m, deterministic)
+func (m *Isolation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Isolation.Marshal(b, m, deterministic)
 }
-func (m *StartResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StartResponse.Merge(m, src)
+func (m *Isolation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Isolation.Merge(m, src)
 }
-func (m *StartResponse) XXX_Size() int {
-	return xxx_messageInfo_StartResponse.Size(m)
+func (m *Isolation) XXX_Size() int {
+	return xxx_messageInfo_Isolation.Size(m)
 }
-func (m *StartResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_StartResponse.DiscardUnknown(m)
+func (m *Isolation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Isolation.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StartResponse proto.InternalMessageInfo
+var xxx_messageInfo_Isolation proto.InternalMessageInfo
 
-func (m *StartResponse) GetSuccess() bool {
+func (m *Isolation) GetNet() bool {
 	if m != nil {
-		return m.Success
+		return m.Net
 	}
 	return false
 }
 
-func (m *StartResponse) GetError() []byte {
+func (m *Isolation) GetMount() bool {
 	if m != nil {
-		return m.Error
+		return m.Mount
+	}
+	return false
+}
+
+func (m *Isolation) GetPid() bool {
+	if m != nil {
+		return m.Pid
+	}
+	return false
+}
+
+func (m *Isolation) GetReadOnlyPaths() []string {
+	if m != nil {
+		return m.ReadOnlyPaths
 	}
 	return nil
 }
 
-type WaitCommand struct {
-	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+func (m *Isolation) GetPrivateTmp() bool {
+	if m != nil {
+		return m.PrivateTmp
+	}
+	return false
+}
+
+// Sched sets scheduling attributes of commands before exec, fields
+// not set are inherited from the server
+type Sched struct {
+	HasNice bool `protobuf:"varint,1,opt,name=has_nice,json=hasNice,proto3" json:"has_nice,omitempty"`
+	// -20 to 19, lower than the server needs CAP_SYS_NICE
+	Nice    int32   `protobuf:"varint,2,opt,name=nice,proto3" json:"nice,omitempty"`
+	IoClass IoClass `protobuf:"varint,3,opt,name=io_class,json=ioClass,proto3,enum=apis.IoClass" json:"io_class,omitempty"`
+	// 0 highest to 7 lowest for realtime and best effort
+	IoPriority int32 `protobuf:"varint,4,opt,name=io_priority,json=ioPriority,proto3" json:"io_priority,omitempty"`
+	// cpus the command runs on, all cpus of the server if empty
+	Cpus           []uint32 `protobuf:"varint,5,rep,packed,name=cpus,proto3" json:"cpus,omitempty"`
+	HasOomScoreAdj bool     `protobuf:"varint,6,opt,name=has_oom_score_adj,json=hasOomScoreAdj,proto3" json:"has_oom_score_adj,omitempty"`
+	// -1000 to 1000, 1000 is killed first on out of memory
+	OomScoreAdj          int32    `protobuf:"varint,7,opt,name=oom_score_adj,json=oomScoreAdj,proto3" json:"oom_score_adj,omitempty"`
+	HasUmask             bool     `protobuf:"varint,8,opt,name=has_umask,json=hasUmask,proto3" json:"has_umask,omitempty"`
+	Umask                uint32   `protobuf:"varint,9,opt,name=umask,proto3" json:"umask,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *WaitCommand) Reset()         { *m = WaitCommand{} }
-func (m *WaitCommand) String() string { return proto.CompactTextString(m) }
-func (*WaitCommand) ProtoMessage()    {}
-func (*WaitCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{5}
+func (m *Sched) Reset()         { *m = Sched{} }
+func (m *Sched) String() string { return proto.CompactTextString(m) }
+func (*Sched) ProtoMessage()    {}
+func (*Sched) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{4}
 }
 
-func (m *WaitCommand) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_WaitCommand.Unmarshal(m, b)
+func (m *Sched) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Sched.Unmarshal(m, b)
 }
-func (m *WaitCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_WaitCommand.Marshal(b, m, deterministic)
+func (m *Sched) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Sched.Marshal(b, m, deterministic)
 }
-func (m *WaitCommand) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_WaitCommand.Merge(m, src)
+func (m *Sched) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Sched.Merge(m, src)
 }
-func (m *WaitCommand) XXX_Size() int {
-	return xxx_messageInfo_WaitCommand.Size(m)
+func (m *Sched) XXX_Size() int {
+	return xxx_messageInfo_Sched.Size(m)
 }
-func (m *WaitCommand) XXX_DiscardUnknown() {
-	xxx_messageInfo_WaitCommand.DiscardUnknown(m)
+func (m *Sched) XXX_DiscardUnknown() {
+	xxx_messageInfo_Sched.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_WaitCommand proto.InternalMessageInfo
+var xxx_messageInfo_Sched proto.InternalMessageInfo
 
-func (m *WaitCommand) GetSn() uint32 {
+func (m *Sched) GetHasNice() bool {
 	if m != nil {
-		return m.Sn
+		return m.HasNice
 	}
-	return 0
-}
-
-type WaitResponse struct {
-	ExitStatus           uint32   `protobuf:"varint,1,opt,name=exit_status,json=exitStatus,proto3" json:"exit_status,omitempty"`
-	ErrContent           []byte   `protobuf:"bytes,2,opt,name=err_content,json=errContent,proto3" json:"err_content,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	return false
 }
 
-func (m *WaitResponse) Reset()         { *m = WaitResponse{} }
-func (m *WaitResponse) String() string { return proto.CompactTextString(m) }
-func (*WaitResponse) ProtoMessage()    {}
-func (*WaitResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{6}
+func (m *Sched) GetNice() int32 {
+	if m != nil {
+		return m.Nice
+	}
+	return 0
 }
 
-func (m *WaitResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_WaitResponse.Unmarshal(m, b)
-}
-func (m *WaitResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_WaitResponse.Marshal(b, m, deterministic)
-}
-func (m *WaitResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_WaitResponse.Merge(m, src)
-}
-func (m *WaitResponse) XXX_Size() int {
-	return xxx_messageInfo_WaitResponse.Size(m)
-}
-func (m *WaitResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_WaitResponse.DiscardUnknown(m)
+func (m *Sched) GetIoClass() IoClass {
+	if m != nil {
+		return m.IoClass
+	}
+	return IoClass_IO_CLASS_NONE
 }
 
-var xxx_messageInfo_WaitResponse proto.InternalMessageInfo
-
-func (m *WaitResponse) GetExitStatus() uint32 {
+func (m *Sched) GetIoPriority() int32 {
 	if m != nil {
-		return m.ExitStatus
+		return m.IoPriority
 	}
 	return 0
 }
 
-func (m *WaitResponse) GetErrContent() []byte {
+func (m *Sched) GetCpus() []uint32 {
 	if m != nil {
-		return m.ErrContent
+		return m.Cpus
+	}
+	return nil
+}
+
+func (m *Sched) GetHasOomScoreAdj() bool {
+	if m != nil {
+		return m.HasOomScoreAdj
+	}
+	return false
+}
+
+func (m *Sched) GetOomScoreAdj() int32 {
+	if m != nil {
+		return m.OomScoreAdj
+	}
+	return 0
+}
+
+func (m *Sched) GetHasUmask() bool {
+	if m != nil {
+		return m.HasUmask
+	}
+	return false
+}
+
+func (m *Sched) GetUmask() uint32 {
+	if m != nil {
+		return m.Umask
+	}
+	return 0
+}
+
+// Capabilities restrict commands, names are like CAP_NET_ADMIN or NET_ADMIN
+type Capabilities struct {
+	// only these are kept in the bounding set, all if empty
+	Keep []string `protobuf:"bytes,1,rep,name=keep,proto3" json:"keep,omitempty"`
+	// removed from the bounding set
+	Drop []string `protobuf:"bytes,2,rep,name=drop,proto3" json:"drop,omitempty"`
+	// raised in the ambient set, non-root users keep them across exec
+	Ambient              []string `protobuf:"bytes,3,rep,name=ambient,proto3" json:"ambient,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Capabilities) Reset()         { *m = Capabilities{} }
+func (m *Capabilities) String() string { return proto.CompactTextString(m) }
+func (*Capabilities) ProtoMessage()    {}
+func (*Capabilities) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{5}
+}
+
+func (m *Capabilities) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Capabilities.Unmarshal(m, b)
+}
+func (m *Capabilities) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Capabilities.Marshal(b, m, deterministic)
+}
+func (m *Capabilities) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Capabilities.Merge(m, src)
+}
+func (m *Capabilities) XXX_Size() int {
+	return xxx_messageInfo_Capabilities.Size(m)
+}
+func (m *Capabilities) XXX_DiscardUnknown() {
+	xxx_messageInfo_Capabilities.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Capabilities proto.InternalMessageInfo
+
+func (m *Capabilities) GetKeep() []string {
+	if m != nil {
+		return m.Keep
+	}
+	return nil
+}
+
+func (m *Capabilities) GetDrop() []string {
+	if m != nil {
+		return m.Drop
+	}
+	return nil
+}
+
+func (m *Capabilities) GetAmbient() []string {
+	if m != nil {
+		return m.Ambient
+	}
+	return nil
+}
+
+type Input struct {
+	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+	Input                []byte   `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Input) Reset()         { *m = Input{} }
+func (m *Input) String() string { return proto.CompactTextString(m) }
+func (*Input) ProtoMessage()    {}
+func (*Input) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{6}
+}
+
+func (m *Input) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Input.Unmarshal(m, b)
+}
+func (m *Input) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Input.Marshal(b, m, deterministic)
+}
+func (m *Input) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Input.Merge(m, src)
+}
+func (m *Input) XXX_Size() int {
+	return xxx_messageInfo_Input.Size(m)
+}
+func (m *Input) XXX_DiscardUnknown() {
+	xxx_messageInfo_Input.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Input proto.InternalMessageInfo
+
+func (m *Input) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+func (m *Input) GetInput() []byte {
+	if m != nil {
+		return m.Input
+	}
+	return nil
+}
+
+type Stdout struct {
+	Stdout               []byte   `protobuf:"bytes,1,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Closed               bool     `protobuf:"varint,2,opt,name=closed,proto3" json:"closed,omitempty"`
+	RuntimeError         []byte   `protobuf:"bytes,3,opt,name=runtime_error,json=runtimeError,proto3" json:"runtime_error,omitempty"`
+	Start                bool     `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Stdout) Reset()         { *m = Stdout{} }
+func (m *Stdout) String() string { return proto.CompactTextString(m) }
+func (*Stdout) ProtoMessage()    {}
+func (*Stdout) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{7}
+}
+
+func (m *Stdout) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Stdout.Unmarshal(m, b)
+}
+func (m *Stdout) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Stdout.Marshal(b, m, deterministic)
+}
+func (m *Stdout) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Stdout.Merge(m, src)
+}
+func (m *Stdout) XXX_Size() int {
+	return xxx_messageInfo_Stdout.Size(m)
+}
+func (m *Stdout) XXX_DiscardUnknown() {
+	xxx_messageInfo_Stdout.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Stdout proto.InternalMessageInfo
+
+func (m *Stdout) GetStdout() []byte {
+	if m != nil {
+		return m.Stdout
+	}
+	return nil
+}
+
+func (m *Stdout) GetClosed() bool {
+	if m != nil {
+		return m.Closed
+	}
+	return false
+}
+
+func (m *Stdout) GetRuntimeError() []byte {
+	if m != nil {
+		return m.RuntimeError
+	}
+	return nil
+}
+
+func (m *Stdout) GetStart() bool {
+	if m != nil {
+		return m.Start
+	}
+	return false
+}
+
+type Stderr struct {
+	Stderr               []byte   `protobuf:"bytes,1,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	Closed               bool     `protobuf:"varint,2,opt,name=closed,proto3" json:"closed,omitempty"`
+	RuntimeError         []byte   `protobuf:"bytes,3,opt,name=runtime_error,json=runtimeError,proto3" json:"runtime_error,omitempty"`
+	Start                bool     `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Stderr) Reset()         { *m = Stderr{} }
+func (m *Stderr) String() string { return proto.CompactTextString(m) }
+func (*Stderr) ProtoMessage()    {}
+func (*Stderr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{8}
+}
+
+func (m *Stderr) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Stderr.Unmarshal(m, b)
+}
+func (m *Stderr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Stderr.Marshal(b, m, deterministic)
+}
+func (m *Stderr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Stderr.Merge(m, src)
+}
+func (m *Stderr) XXX_Size() int {
+	return xxx_messageInfo_Stderr.Size(m)
+}
+func (m *Stderr) XXX_DiscardUnknown() {
+	xxx_messageInfo_Stderr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Stderr proto.InternalMessageInfo
+
+func (m *Stderr) GetStderr() []byte {
+	if m != nil {
+		return m.Stderr
+	}
+	return nil
+}
+
+func (m *Stderr) GetClosed() bool {
+	if m != nil {
+		return m.Closed
+	}
+	return false
+}
+
+func (m *Stderr) GetRuntimeError() []byte {
+	if m != nil {
+		return m.RuntimeError
+	}
+	return nil
+}
+
+func (m *Stderr) GetStart() bool {
+	if m != nil {
+		return m.Start
+	}
+	return false
+}
+
+type StartResponse struct {
+	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error   []byte `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Sn      uint32 `protobuf:"varint,3,opt,name=sn,proto3" json:"sn,omitempty"`
+	// set for commands with landlock
+	Landlock *LandlockStatus `protobuf:"bytes,4,opt,name=landlock,proto3" json:"landlock,omitempty"`
+	// window granted on Exec streams for each of stdin, stdout and
+	// stderr, 0 if the server has no flow control
+	Window uint32 `protobuf:"varint,5,opt,name=window,proto3" json:"window,omitempty"`
+	// bytes of stdin written to the process, stdin sent after them is
+	// sent again on resume
+	StdinOffset          uint64   `protobuf:"varint,6,opt,name=stdin_offset,json=stdinOffset,proto3" json:"stdin_offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StartResponse) Reset()         { *m = StartResponse{} }
+func (m *StartResponse) String() string { return proto.CompactTextString(m) }
+func (*StartResponse) ProtoMessage()    {}
+func (*StartResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{9}
+}
+
+func (m *StartResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartResponse.Unmarshal(m, b)
+}
+func (m *StartResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartResponse.Marshal(b, m, deterministic)
+}
+func (m *StartResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartResponse.Merge(m, src)
+}
+func (m *StartResponse) XXX_Size() int {
+	return xxx_messageInfo_StartResponse.Size(m)
+}
+func (m *StartResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartResponse proto.InternalMessageInfo
+
+func (m *StartResponse) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *StartResponse) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *StartResponse) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+func (m *StartResponse) GetLandlock() *LandlockStatus {
+	if m != nil {
+		return m.Landlock
+	}
+	return nil
+}
+
+func (m *StartResponse) GetWindow() uint32 {
+	if m != nil {
+		return m.Window
+	}
+	return 0
+}
+
+func (m *StartResponse) GetStdinOffset() uint64 {
+	if m != nil {
+		return m.StdinOffset
+	}
+	return 0
+}
+
+type WaitCommand struct {
+	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WaitCommand) Reset()         { *m = WaitCommand{} }
+func (m *WaitCommand) String() string { return proto.CompactTextString(m) }
+func (*WaitCommand) ProtoMessage()    {}
+func (*WaitCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{10}
+}
+
+func (m *WaitCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WaitCommand.Unmarshal(m, b)
+}
+func (m *WaitCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WaitCommand.Marshal(b, m, deterministic)
+}
+func (m *WaitCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WaitCommand.Merge(m, src)
+}
+func (m *WaitCommand) XXX_Size() int {
+	return xxx_messageInfo_WaitCommand.Size(m)
+}
+func (m *WaitCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_WaitCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WaitCommand proto.InternalMessageInfo
+
+func (m *WaitCommand) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+type WaitResponse struct {
+	ExitStatus           uint32   `protobuf:"varint,1,opt,name=exit_status,json=exitStatus,proto3" json:"exit_status,omitempty"`
+	ErrContent           []byte   `protobuf:"bytes,2,opt,name=err_content,json=errContent,proto3" json:"err_content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WaitResponse) Reset()         { *m = WaitResponse{} }
+func (m *WaitResponse) String() string { return proto.CompactTextString(m) }
+func (*WaitResponse) ProtoMessage()    {}
+func (*WaitResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{11}
+}
+
+func (m *WaitResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WaitResponse.Unmarshal(m, b)
+}
+func (m *WaitResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WaitResponse.Marshal(b, m, deterministic)
+}
+func (m *WaitResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WaitResponse.Merge(m, src)
+}
+func (m *WaitResponse) XXX_Size() int {
+	return xxx_messageInfo_WaitResponse.Size(m)
+}
+func (m *WaitResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WaitResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WaitResponse proto.InternalMessageInfo
+
+func (m *WaitResponse) GetExitStatus() uint32 {
+	if m != nil {
+		return m.ExitStatus
+	}
+	return 0
+}
+
+func (m *WaitResponse) GetErrContent() []byte {
+	if m != nil {
+		return m.ErrContent
 	}
 	return nil
 }
@@ -400,142 +976,1566 @@ type Sn struct {
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Sn) Reset()         { *m = Sn{} }
-func (m *Sn) String() string { return proto.CompactTextString(m) }
-func (*Sn) ProtoMessage()    {}
-func (*Sn) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{7}
+func (m *Sn) Reset()         { *m = Sn{} }
+func (m *Sn) String() string { return proto.CompactTextString(m) }
+func (*Sn) ProtoMessage()    {}
+func (*Sn) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{12}
+}
+
+func (m *Sn) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Sn.Unmarshal(m, b)
+}
+func (m *Sn) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Sn.Marshal(b, m, deterministic)
+}
+func (m *Sn) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Sn.Merge(m, src)
+}
+func (m *Sn) XXX_Size() int {
+	return xxx_messageInfo_Sn.Size(m)
+}
+func (m *Sn) XXX_DiscardUnknown() {
+	xxx_messageInfo_Sn.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Sn proto.InternalMessageInfo
+
+func (m *Sn) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+type StartInput struct {
+	Sn        uint32 `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+	HasStdin  bool   `protobuf:"varint,2,opt,name=has_stdin,json=hasStdin,proto3" json:"has_stdin,omitempty"`
+	HasStdout bool   `protobuf:"varint,3,opt,name=has_stdout,json=hasStdout,proto3" json:"has_stdout,omitempty"`
+	HasStderr bool   `protobuf:"varint,4,opt,name=has_stderr,json=hasStderr,proto3" json:"has_stderr,omitempty"`
+	// stderr shares the stdout pipe, keeping the order between them
+	CombinedOutput       bool     `protobuf:"varint,5,opt,name=combined_output,json=combinedOutput,proto3" json:"combined_output,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StartInput) Reset()         { *m = StartInput{} }
+func (m *StartInput) String() string { return proto.CompactTextString(m) }
+func (*StartInput) ProtoMessage()    {}
+func (*StartInput) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{13}
+}
+
+func (m *StartInput) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartInput.Unmarshal(m, b)
+}
+func (m *StartInput) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartInput.Marshal(b, m, deterministic)
+}
+func (m *StartInput) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartInput.Merge(m, src)
+}
+func (m *StartInput) XXX_Size() int {
+	return xxx_messageInfo_StartInput.Size(m)
+}
+func (m *StartInput) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartInput.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartInput proto.InternalMessageInfo
+
+func (m *StartInput) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+func (m *StartInput) GetHasStdin() bool {
+	if m != nil {
+		return m.HasStdin
+	}
+	return false
+}
+
+func (m *StartInput) GetHasStdout() bool {
+	if m != nil {
+		return m.HasStdout
+	}
+	return false
+}
+
+func (m *StartInput) GetHasStderr() bool {
+	if m != nil {
+		return m.HasStderr
+	}
+	return false
+}
+
+func (m *StartInput) GetCombinedOutput() bool {
+	if m != nil {
+		return m.CombinedOutput
+	}
+	return false
+}
+
+type Error struct {
+	Error                []byte   `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Error) Reset()         { *m = Error{} }
+func (m *Error) String() string { return proto.CompactTextString(m) }
+func (*Error) ProtoMessage()    {}
+func (*Error) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{14}
+}
+
+func (m *Error) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Error.Unmarshal(m, b)
+}
+func (m *Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Error.Marshal(b, m, deterministic)
+}
+func (m *Error) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Error.Merge(m, src)
+}
+func (m *Error) XXX_Size() int {
+	return xxx_messageInfo_Error.Size(m)
+}
+func (m *Error) XXX_DiscardUnknown() {
+	xxx_messageInfo_Error.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Error proto.InternalMessageInfo
+
+func (m *Error) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type ExecStart struct {
+	Command   *Command `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	HasStdin  bool     `protobuf:"varint,2,opt,name=has_stdin,json=hasStdin,proto3" json:"has_stdin,omitempty"`
+	HasStdout bool     `protobuf:"varint,3,opt,name=has_stdout,json=hasStdout,proto3" json:"has_stdout,omitempty"`
+	HasStderr bool     `protobuf:"varint,4,opt,name=has_stderr,json=hasStderr,proto3" json:"has_stderr,omitempty"`
+	// stderr shares the stdout pipe, keeping the order between them
+	CombinedOutput bool `protobuf:"varint,5,opt,name=combined_output,json=combinedOutput,proto3" json:"combined_output,omitempty"`
+	// resume_sn continues the stream of a command handed over by an
+	// upgraded server instead of starting command
+	ResumeSn uint32 `protobuf:"varint,6,opt,name=resume_sn,json=resumeSn,proto3" json:"resume_sn,omitempty"`
+	// bytes of stdout and stderr received before the stream broke,
+	// output after them is sent again on resume
+	StdoutOffset uint64 `protobuf:"varint,7,opt,name=stdout_offset,json=stdoutOffset,proto3" json:"stdout_offset,omitempty"`
+	StderrOffset uint64 `protobuf:"varint,8,opt,name=stderr_offset,json=stderrOffset,proto3" json:"stderr_offset,omitempty"`
+	// bytes of each stream the client lets be in flight unacknowledged,
+	// 0 without flow control
+	Window uint32 `protobuf:"varint,9,opt,name=window,proto3" json:"window,omitempty"`
+	// files passed to the command, stdin, stdout and stderr passed are
+	// not streamed
+	Files                *PassedFiles `protobuf:"bytes,10,opt,name=files,proto3" json:"files,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ExecStart) Reset()         { *m = ExecStart{} }
+func (m *ExecStart) String() string { return proto.CompactTextString(m) }
+func (*ExecStart) ProtoMessage()    {}
+func (*ExecStart) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{15}
+}
+
+func (m *ExecStart) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExecStart.Unmarshal(m, b)
+}
+func (m *ExecStart) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExecStart.Marshal(b, m, deterministic)
+}
+func (m *ExecStart) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExecStart.Merge(m, src)
+}
+func (m *ExecStart) XXX_Size() int {
+	return xxx_messageInfo_ExecStart.Size(m)
+}
+func (m *ExecStart) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExecStart.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExecStart proto.InternalMessageInfo
+
+func (m *ExecStart) GetCommand() *Command {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *ExecStart) GetHasStdin() bool {
+	if m != nil {
+		return m.HasStdin
+	}
+	return false
+}
+
+func (m *ExecStart) GetHasStdout() bool {
+	if m != nil {
+		return m.HasStdout
+	}
+	return false
+}
+
+func (m *ExecStart) GetHasStderr() bool {
+	if m != nil {
+		return m.HasStderr
+	}
+	return false
+}
+
+func (m *ExecStart) GetCombinedOutput() bool {
+	if m != nil {
+		return m.CombinedOutput
+	}
+	return false
+}
+
+func (m *ExecStart) GetResumeSn() uint32 {
+	if m != nil {
+		return m.ResumeSn
+	}
+	return 0
+}
+
+func (m *ExecStart) GetStdoutOffset() uint64 {
+	if m != nil {
+		return m.StdoutOffset
+	}
+	return 0
+}
+
+func (m *ExecStart) GetStderrOffset() uint64 {
+	if m != nil {
+		return m.StderrOffset
+	}
+	return 0
+}
+
+func (m *ExecStart) GetWindow() uint32 {
+	if m != nil {
+		return m.Window
+	}
+	return 0
+}
+
+func (m *ExecStart) GetFiles() *PassedFiles {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+// PassedFiles names files a client sent on the unix socket with
+// SCM_RIGHTS, in the order stdin, stdout and stderr if set, followed
+// by the extra files which become fd 3 and up of the command
+type PassedFiles struct {
+	// token the server answered the files with
+	Token                string   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Stdin                bool     `protobuf:"varint,2,opt,name=stdin,proto3" json:"stdin,omitempty"`
+	Stdout               bool     `protobuf:"varint,3,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr               bool     `protobuf:"varint,4,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	Extra                uint32   `protobuf:"varint,5,opt,name=extra,proto3" json:"extra,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PassedFiles) Reset()         { *m = PassedFiles{} }
+func (m *PassedFiles) String() string { return proto.CompactTextString(m) }
+func (*PassedFiles) ProtoMessage()    {}
+func (*PassedFiles) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{16}
+}
+
+func (m *PassedFiles) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PassedFiles.Unmarshal(m, b)
+}
+func (m *PassedFiles) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PassedFiles.Marshal(b, m, deterministic)
+}
+func (m *PassedFiles) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PassedFiles.Merge(m, src)
+}
+func (m *PassedFiles) XXX_Size() int {
+	return xxx_messageInfo_PassedFiles.Size(m)
+}
+func (m *PassedFiles) XXX_DiscardUnknown() {
+	xxx_messageInfo_PassedFiles.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PassedFiles proto.InternalMessageInfo
+
+func (m *PassedFiles) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *PassedFiles) GetStdin() bool {
+	if m != nil {
+		return m.Stdin
+	}
+	return false
+}
+
+func (m *PassedFiles) GetStdout() bool {
+	if m != nil {
+		return m.Stdout
+	}
+	return false
+}
+
+func (m *PassedFiles) GetStderr() bool {
+	if m != nil {
+		return m.Stderr
+	}
+	return false
+}
+
+func (m *PassedFiles) GetExtra() uint32 {
+	if m != nil {
+		return m.Extra
+	}
+	return 0
+}
+
+// OutputAck acknowledges stdout and stderr consumed by client, both
+// are totals since the command started
+type OutputAck struct {
+	Stdout               uint64   `protobuf:"varint,1,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr               uint64   `protobuf:"varint,2,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OutputAck) Reset()         { *m = OutputAck{} }
+func (m *OutputAck) String() string { return proto.CompactTextString(m) }
+func (*OutputAck) ProtoMessage()    {}
+func (*OutputAck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{17}
+}
+
+func (m *OutputAck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OutputAck.Unmarshal(m, b)
+}
+func (m *OutputAck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OutputAck.Marshal(b, m, deterministic)
+}
+func (m *OutputAck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OutputAck.Merge(m, src)
+}
+func (m *OutputAck) XXX_Size() int {
+	return xxx_messageInfo_OutputAck.Size(m)
+}
+func (m *OutputAck) XXX_DiscardUnknown() {
+	xxx_messageInfo_OutputAck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OutputAck proto.InternalMessageInfo
+
+func (m *OutputAck) GetStdout() uint64 {
+	if m != nil {
+		return m.Stdout
+	}
+	return 0
+}
+
+func (m *OutputAck) GetStderr() uint64 {
+	if m != nil {
+		return m.Stderr
+	}
+	return 0
+}
+
+// ExecRequest is sent by client on Exec stream, the first one
+// must be start, followed by stdin data, close_stdin, signals and
+// output acks
+type ExecRequest struct {
+	// Types that are valid to be assigned to Request:
+	//	*ExecRequest_Start
+	//	*ExecRequest_Stdin
+	//	*ExecRequest_CloseStdin
+	//	*ExecRequest_Signal
+	//	*ExecRequest_Ack
+	Request              isExecRequest_Request `protobuf_oneof:"request"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *ExecRequest) Reset()         { *m = ExecRequest{} }
+func (m *ExecRequest) String() string { return proto.CompactTextString(m) }
+func (*ExecRequest) ProtoMessage()    {}
+func (*ExecRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{18}
+}
+
+func (m *ExecRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExecRequest.Unmarshal(m, b)
+}
+func (m *ExecRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExecRequest.Marshal(b, m, deterministic)
+}
+func (m *ExecRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExecRequest.Merge(m, src)
+}
+func (m *ExecRequest) XXX_Size() int {
+	return xxx_messageInfo_ExecRequest.Size(m)
+}
+func (m *ExecRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExecRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExecRequest proto.InternalMessageInfo
+
+type isExecRequest_Request interface {
+	isExecRequest_Request()
+}
+
+type ExecRequest_Start struct {
+	Start *ExecStart `protobuf:"bytes,1,opt,name=start,proto3,oneof"`
+}
+
+type ExecRequest_Stdin struct {
+	Stdin []byte `protobuf:"bytes,2,opt,name=stdin,proto3,oneof"`
+}
+
+type ExecRequest_CloseStdin struct {
+	CloseStdin bool `protobuf:"varint,3,opt,name=close_stdin,json=closeStdin,proto3,oneof"`
+}
+
+type ExecRequest_Signal struct {
+	Signal int32 `protobuf:"varint,4,opt,name=signal,proto3,oneof"`
+}
+
+type ExecRequest_Ack struct {
+	Ack *OutputAck `protobuf:"bytes,5,opt,name=ack,proto3,oneof"`
+}
+
+func (*ExecRequest_Start) isExecRequest_Request() {}
+
+func (*ExecRequest_Stdin) isExecRequest_Request() {}
+
+func (*ExecRequest_CloseStdin) isExecRequest_Request() {}
+
+func (*ExecRequest_Signal) isExecRequest_Request() {}
+
+func (*ExecRequest_Ack) isExecRequest_Request() {}
+
+func (m *ExecRequest) GetRequest() isExecRequest_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetStart() *ExecStart {
+	if x, ok := m.GetRequest().(*ExecRequest_Start); ok {
+		return x.Start
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetStdin() []byte {
+	if x, ok := m.GetRequest().(*ExecRequest_Stdin); ok {
+		return x.Stdin
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetCloseStdin() bool {
+	if x, ok := m.GetRequest().(*ExecRequest_CloseStdin); ok {
+		return x.CloseStdin
+	}
+	return false
+}
+
+func (m *ExecRequest) GetSignal() int32 {
+	if x, ok := m.GetRequest().(*ExecRequest_Signal); ok {
+		return x.Signal
+	}
+	return 0
+}
+
+func (m *ExecRequest) GetAck() *OutputAck {
+	if x, ok := m.GetRequest().(*ExecRequest_Ack); ok {
+		return x.Ack
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*ExecRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*ExecRequest_Start)(nil),
+		(*ExecRequest_Stdin)(nil),
+		(*ExecRequest_CloseStdin)(nil),
+		(*ExecRequest_Signal)(nil),
+		(*ExecRequest_Ack)(nil),
+	}
+}
+
+// ExecResponse is sent by server on Exec stream, started comes first,
+// exit is the last one after all stdout and stderr data
+type ExecResponse struct {
+	// Types that are valid to be assigned to Response:
+	//	*ExecResponse_Started
+	//	*ExecResponse_Stdout
+	//	*ExecResponse_Stderr
+	//	*ExecResponse_Exit
+	//	*ExecResponse_Handoff
+	//	*ExecResponse_StdinAck
+	Response             isExecResponse_Response `protobuf_oneof:"response"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *ExecResponse) Reset()         { *m = ExecResponse{} }
+func (m *ExecResponse) String() string { return proto.CompactTextString(m) }
+func (*ExecResponse) ProtoMessage()    {}
+func (*ExecResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{19}
+}
+
+func (m *ExecResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExecResponse.Unmarshal(m, b)
+}
+func (m *ExecResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExecResponse.Marshal(b, m, deterministic)
+}
+func (m *ExecResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExecResponse.Merge(m, src)
+}
+func (m *ExecResponse) XXX_Size() int {
+	return xxx_messageInfo_ExecResponse.Size(m)
+}
+func (m *ExecResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExecResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExecResponse proto.InternalMessageInfo
+
+type isExecResponse_Response interface {
+	isExecResponse_Response()
+}
+
+type ExecResponse_Started struct {
+	Started *StartResponse `protobuf:"bytes,1,opt,name=started,proto3,oneof"`
+}
+
+type ExecResponse_Stdout struct {
+	Stdout []byte `protobuf:"bytes,2,opt,name=stdout,proto3,oneof"`
+}
+
+type ExecResponse_Stderr struct {
+	Stderr []byte `protobuf:"bytes,3,opt,name=stderr,proto3,oneof"`
+}
+
+type ExecResponse_Exit struct {
+	Exit *WaitResponse `protobuf:"bytes,4,opt,name=exit,proto3,oneof"`
+}
+
+type ExecResponse_Handoff struct {
+	Handoff bool `protobuf:"varint,5,opt,name=handoff,proto3,oneof"`
+}
+
+type ExecResponse_StdinAck struct {
+	StdinAck uint64 `protobuf:"varint,6,opt,name=stdin_ack,json=stdinAck,proto3,oneof"`
+}
+
+func (*ExecResponse_Started) isExecResponse_Response() {}
+
+func (*ExecResponse_Stdout) isExecResponse_Response() {}
+
+func (*ExecResponse_Stderr) isExecResponse_Response() {}
+
+func (*ExecResponse_Exit) isExecResponse_Response() {}
+
+func (*ExecResponse_Handoff) isExecResponse_Response() {}
+
+func (*ExecResponse_StdinAck) isExecResponse_Response() {}
+
+func (m *ExecResponse) GetResponse() isExecResponse_Response {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+func (m *ExecResponse) GetStarted() *StartResponse {
+	if x, ok := m.GetResponse().(*ExecResponse_Started); ok {
+		return x.Started
+	}
+	return nil
+}
+
+func (m *ExecResponse) GetStdout() []byte {
+	if x, ok := m.GetResponse().(*ExecResponse_Stdout); ok {
+		return x.Stdout
+	}
+	return nil
+}
+
+func (m *ExecResponse) GetStderr() []byte {
+	if x, ok := m.GetResponse().(*ExecResponse_Stderr); ok {
+		return x.Stderr
+	}
+	return nil
+}
+
+func (m *ExecResponse) GetExit() *WaitResponse {
+	if x, ok := m.GetResponse().(*ExecResponse_Exit); ok {
+		return x.Exit
+	}
+	return nil
+}
+
+func (m *ExecResponse) GetHandoff() bool {
+	if x, ok := m.GetResponse().(*ExecResponse_Handoff); ok {
+		return x.Handoff
+	}
+	return false
+}
+
+func (m *ExecResponse) GetStdinAck() uint64 {
+	if x, ok := m.GetResponse().(*ExecResponse_StdinAck); ok {
+		return x.StdinAck
+	}
+	return 0
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*ExecResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*ExecResponse_Started)(nil),
+		(*ExecResponse_Stdout)(nil),
+		(*ExecResponse_Stderr)(nil),
+		(*ExecResponse_Exit)(nil),
+		(*ExecResponse_Handoff)(nil),
+		(*ExecResponse_StdinAck)(nil),
+	}
+}
+
+type LookPathResponse struct {
+	Path                 []byte   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	NotFound             bool     `protobuf:"varint,2,opt,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	Error                []byte   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LookPathResponse) Reset()         { *m = LookPathResponse{} }
+func (m *LookPathResponse) String() string { return proto.CompactTextString(m) }
+func (*LookPathResponse) ProtoMessage()    {}
+func (*LookPathResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{20}
+}
+
+func (m *LookPathResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LookPathResponse.Unmarshal(m, b)
+}
+func (m *LookPathResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LookPathResponse.Marshal(b, m, deterministic)
+}
+func (m *LookPathResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LookPathResponse.Merge(m, src)
+}
+func (m *LookPathResponse) XXX_Size() int {
+	return xxx_messageInfo_LookPathResponse.Size(m)
+}
+func (m *LookPathResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LookPathResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LookPathResponse proto.InternalMessageInfo
+
+func (m *LookPathResponse) GetPath() []byte {
+	if m != nil {
+		return m.Path
+	}
+	return nil
+}
+
+func (m *LookPathResponse) GetNotFound() bool {
+	if m != nil {
+		return m.NotFound
+	}
+	return false
+}
+
+func (m *LookPathResponse) GetError() []byte {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type Empty struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+func (*Empty) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{21}
+}
+
+func (m *Empty) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Empty.Unmarshal(m, b)
+}
+func (m *Empty) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Empty.Marshal(b, m, deterministic)
+}
+func (m *Empty) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Empty.Merge(m, src)
+}
+func (m *Empty) XXX_Size() int {
+	return xxx_messageInfo_Empty.Size(m)
+}
+func (m *Empty) XXX_DiscardUnknown() {
+	xxx_messageInfo_Empty.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Empty proto.InternalMessageInfo
+
+type ProcessInfo struct {
+	Sn   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+	Pid  int32    `protobuf:"varint,2,opt,name=pid,proto3" json:"pid,omitempty"`
+	Path []byte   `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Args [][]byte `protobuf:"bytes,4,rep,name=args,proto3" json:"args,omitempty"`
+	// unix seconds, 0 if not started yet
+	StartedAt            int64    `protobuf:"varint,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	RequestId            string   `protobuf:"bytes,6,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProcessInfo) Reset()         { *m = ProcessInfo{} }
+func (m *ProcessInfo) String() string { return proto.CompactTextString(m) }
+func (*ProcessInfo) ProtoMessage()    {}
+func (*ProcessInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{22}
+}
+
+func (m *ProcessInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProcessInfo.Unmarshal(m, b)
+}
+func (m *ProcessInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProcessInfo.Marshal(b, m, deterministic)
+}
+func (m *ProcessInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProcessInfo.Merge(m, src)
+}
+func (m *ProcessInfo) XXX_Size() int {
+	return xxx_messageInfo_ProcessInfo.Size(m)
+}
+func (m *ProcessInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProcessInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProcessInfo proto.InternalMessageInfo
+
+func (m *ProcessInfo) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetPid() int32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetPath() []byte {
+	if m != nil {
+		return m.Path
+	}
+	return nil
+}
+
+func (m *ProcessInfo) GetArgs() [][]byte {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *ProcessInfo) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+// InspectResponse describes the running process of a command,
+// taken from /proc
+type InspectResponse struct {
+	Process        *ProcessInfo `protobuf:"bytes,1,opt,name=process,proto3" json:"process,omitempty"`
+	Uid            uint32       `protobuf:"varint,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Gid            uint32       `protobuf:"varint,3,opt,name=gid,proto3" json:"gid,omitempty"`
+	CapEffective   []string     `protobuf:"bytes,4,rep,name=cap_effective,json=capEffective,proto3" json:"cap_effective,omitempty"`
+	CapPermitted   []string     `protobuf:"bytes,5,rep,name=cap_permitted,json=capPermitted,proto3" json:"cap_permitted,omitempty"`
+	CapInheritable []string     `protobuf:"bytes,6,rep,name=cap_inheritable,json=capInheritable,proto3" json:"cap_inheritable,omitempty"`
+	CapBounding    []string     `protobuf:"bytes,7,rep,name=cap_bounding,json=capBounding,proto3" json:"cap_bounding,omitempty"`
+	CapAmbient     []string     `protobuf:"bytes,8,rep,name=cap_ambient,json=capAmbient,proto3" json:"cap_ambient,omitempty"`
+	NoNewPrivs     bool         `protobuf:"varint,9,opt,name=no_new_privs,json=noNewPrivs,proto3" json:"no_new_privs,omitempty"`
+	// 0 disabled, 1 strict, 2 filter
+	SeccompMode    int32    `protobuf:"varint,10,opt,name=seccomp_mode,json=seccompMode,proto3" json:"seccomp_mode,omitempty"`
+	SeccompProfile string   `protobuf:"bytes,11,opt,name=seccomp_profile,json=seccompProfile,proto3" json:"seccomp_profile,omitempty"`
+	Nice           int32    `protobuf:"varint,12,opt,name=nice,proto3" json:"nice,omitempty"`
+	IoClass        IoClass  `protobuf:"varint,13,opt,name=io_class,json=ioClass,proto3,enum=apis.IoClass" json:"io_class,omitempty"`
+	IoPriority     int32    `protobuf:"varint,14,opt,name=io_priority,json=ioPriority,proto3" json:"io_priority,omitempty"`
+	Cpus           []uint32 `protobuf:"varint,15,rep,packed,name=cpus,proto3" json:"cpus,omitempty"`
+	OomScoreAdj    int32    `protobuf:"varint,16,opt,name=oom_score_adj,json=oomScoreAdj,proto3" json:"oom_score_adj,omitempty"`
+	Umask          uint32   `protobuf:"varint,17,opt,name=umask,proto3" json:"umask,omitempty"`
+	// namespaces other than those of the server, e.g. net, mnt, pid
+	Namespaces []string        `protobuf:"bytes,18,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	Landlock   *LandlockStatus `protobuf:"bytes,19,opt,name=landlock,proto3" json:"landlock,omitempty"`
+	// set for commands on Exec streams with flow control
+	Flow                 *FlowStatus `protobuf:"bytes,20,opt,name=flow,proto3" json:"flow,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *InspectResponse) Reset()         { *m = InspectResponse{} }
+func (m *InspectResponse) String() string { return proto.CompactTextString(m) }
+func (*InspectResponse) ProtoMessage()    {}
+func (*InspectResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{23}
+}
+
+func (m *InspectResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InspectResponse.Unmarshal(m, b)
+}
+func (m *InspectResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InspectResponse.Marshal(b, m, deterministic)
+}
+func (m *InspectResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InspectResponse.Merge(m, src)
+}
+func (m *InspectResponse) XXX_Size() int {
+	return xxx_messageInfo_InspectResponse.Size(m)
+}
+func (m *InspectResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InspectResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InspectResponse proto.InternalMessageInfo
+
+func (m *InspectResponse) GetProcess() *ProcessInfo {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetUid() uint32 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetGid() uint32 {
+	if m != nil {
+		return m.Gid
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetCapEffective() []string {
+	if m != nil {
+		return m.CapEffective
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetCapPermitted() []string {
+	if m != nil {
+		return m.CapPermitted
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetCapInheritable() []string {
+	if m != nil {
+		return m.CapInheritable
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetCapBounding() []string {
+	if m != nil {
+		return m.CapBounding
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetCapAmbient() []string {
+	if m != nil {
+		return m.CapAmbient
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetNoNewPrivs() bool {
+	if m != nil {
+		return m.NoNewPrivs
+	}
+	return false
+}
+
+func (m *InspectResponse) GetSeccompMode() int32 {
+	if m != nil {
+		return m.SeccompMode
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetSeccompProfile() string {
+	if m != nil {
+		return m.SeccompProfile
+	}
+	return ""
+}
+
+func (m *InspectResponse) GetNice() int32 {
+	if m != nil {
+		return m.Nice
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetIoClass() IoClass {
+	if m != nil {
+		return m.IoClass
+	}
+	return IoClass_IO_CLASS_NONE
+}
+
+func (m *InspectResponse) GetIoPriority() int32 {
+	if m != nil {
+		return m.IoPriority
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetCpus() []uint32 {
+	if m != nil {
+		return m.Cpus
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetOomScoreAdj() int32 {
+	if m != nil {
+		return m.OomScoreAdj
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetUmask() uint32 {
+	if m != nil {
+		return m.Umask
+	}
+	return 0
+}
+
+func (m *InspectResponse) GetNamespaces() []string {
+	if m != nil {
+		return m.Namespaces
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetLandlock() *LandlockStatus {
+	if m != nil {
+		return m.Landlock
+	}
+	return nil
+}
+
+func (m *InspectResponse) GetFlow() *FlowStatus {
+	if m != nil {
+		return m.Flow
+	}
+	return nil
+}
+
+// FlowStatus tells the bytes of a command buffered unacknowledged
+// and how long output waits on a full window
+type FlowStatus struct {
+	Window               uint32   `protobuf:"varint,1,opt,name=window,proto3" json:"window,omitempty"`
+	StdinBuffered        uint64   `protobuf:"varint,2,opt,name=stdin_buffered,json=stdinBuffered,proto3" json:"stdin_buffered,omitempty"`
+	StdoutBuffered       uint64   `protobuf:"varint,3,opt,name=stdout_buffered,json=stdoutBuffered,proto3" json:"stdout_buffered,omitempty"`
+	StderrBuffered       uint64   `protobuf:"varint,4,opt,name=stderr_buffered,json=stderrBuffered,proto3" json:"stderr_buffered,omitempty"`
+	StdoutStalledMs      int64    `protobuf:"varint,5,opt,name=stdout_stalled_ms,json=stdoutStalledMs,proto3" json:"stdout_stalled_ms,omitempty"`
+	StderrStalledMs      int64    `protobuf:"varint,6,opt,name=stderr_stalled_ms,json=stderrStalledMs,proto3" json:"stderr_stalled_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FlowStatus) Reset()         { *m = FlowStatus{} }
+func (m *FlowStatus) String() string { return proto.CompactTextString(m) }
+func (*FlowStatus) ProtoMessage()    {}
+func (*FlowStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{24}
+}
+
+func (m *FlowStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FlowStatus.Unmarshal(m, b)
+}
+func (m *FlowStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FlowStatus.Marshal(b, m, deterministic)
+}
+func (m *FlowStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FlowStatus.Merge(m, src)
+}
+func (m *FlowStatus) XXX_Size() int {
+	return xxx_messageInfo_FlowStatus.Size(m)
+}
+func (m *FlowStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_FlowStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FlowStatus proto.InternalMessageInfo
+
+func (m *FlowStatus) GetWindow() uint32 {
+	if m != nil {
+		return m.Window
+	}
+	return 0
+}
+
+func (m *FlowStatus) GetStdinBuffered() uint64 {
+	if m != nil {
+		return m.StdinBuffered
+	}
+	return 0
+}
+
+func (m *FlowStatus) GetStdoutBuffered() uint64 {
+	if m != nil {
+		return m.StdoutBuffered
+	}
+	return 0
+}
+
+func (m *FlowStatus) GetStderrBuffered() uint64 {
+	if m != nil {
+		return m.StderrBuffered
+	}
+	return 0
+}
+
+func (m *FlowStatus) GetStdoutStalledMs() int64 {
+	if m != nil {
+		return m.StdoutStalledMs
+	}
+	return 0
+}
+
+func (m *FlowStatus) GetStderrStalledMs() int64 {
+	if m != nil {
+		return m.StderrStalledMs
+	}
+	return 0
+}
+
+type ListResponse struct {
+	Processes            []*ProcessInfo `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ListResponse) Reset()         { *m = ListResponse{} }
+func (m *ListResponse) String() string { return proto.CompactTextString(m) }
+func (*ListResponse) ProtoMessage()    {}
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{25}
+}
+
+func (m *ListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+}
+func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+}
+func (m *ListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListResponse.Merge(m, src)
+}
+func (m *ListResponse) XXX_Size() int {
+	return xxx_messageInfo_ListResponse.Size(m)
+}
+func (m *ListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+
+func (m *ListResponse) GetProcesses() []*ProcessInfo {
+	if m != nil {
+		return m.Processes
+	}
+	return nil
+}
+
+type InfoResponse struct {
+	Version              string   `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Pid                  int32    `protobuf:"varint,2,opt,name=pid,proto3" json:"pid,omitempty"`
+	StartedAt            int64    `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Running              uint32   `protobuf:"varint,4,opt,name=running,proto3" json:"running,omitempty"`
+	GoVersion            string   `protobuf:"bytes,5,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
+func (m *InfoResponse) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()    {}
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{26}
+}
+
+func (m *InfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoResponse.Unmarshal(m, b)
+}
+func (m *InfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoResponse.Marshal(b, m, deterministic)
+}
+func (m *InfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoResponse.Merge(m, src)
+}
+func (m *InfoResponse) XXX_Size() int {
+	return xxx_messageInfo_InfoResponse.Size(m)
+}
+func (m *InfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
+
+func (m *InfoResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetPid() int32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetRunning() uint32 {
+	if m != nil {
+		return m.Running
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetGoVersion() string {
+	if m != nil {
+		return m.GoVersion
+	}
+	return ""
+}
+
+type SignalRequest struct {
+	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
+	Signal               int32    `protobuf:"varint,2,opt,name=signal,proto3" json:"signal,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignalRequest) Reset()         { *m = SignalRequest{} }
+func (m *SignalRequest) String() string { return proto.CompactTextString(m) }
+func (*SignalRequest) ProtoMessage()    {}
+func (*SignalRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{27}
+}
+
+func (m *SignalRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignalRequest.Unmarshal(m, b)
+}
+func (m *SignalRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignalRequest.Marshal(b, m, deterministic)
+}
+func (m *SignalRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignalRequest.Merge(m, src)
+}
+func (m *SignalRequest) XXX_Size() int {
+	return xxx_messageInfo_SignalRequest.Size(m)
+}
+func (m *SignalRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignalRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignalRequest proto.InternalMessageInfo
+
+func (m *SignalRequest) GetSn() uint32 {
+	if m != nil {
+		return m.Sn
+	}
+	return 0
+}
+
+func (m *SignalRequest) GetSignal() int32 {
+	if m != nil {
+		return m.Signal
+	}
+	return 0
+}
+
+// ServiceSpec describes a command supervised by the server
+type ServiceSpec struct {
+	Name    string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Command *Command      `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Restart RestartPolicy `protobuf:"varint,3,opt,name=restart,proto3,enum=apis.RestartPolicy" json:"restart,omitempty"`
+	// delay before the first restart, doubled on every restart up to
+	// max_backoff_ms, reset once the process ran longer than max_backoff_ms
+	BackoffMs    int64 `protobuf:"varint,4,opt,name=backoff_ms,json=backoffMs,proto3" json:"backoff_ms,omitempty"`
+	MaxBackoffMs int64 `protobuf:"varint,5,opt,name=max_backoff_ms,json=maxBackoffMs,proto3" json:"max_backoff_ms,omitempty"`
+	// 0 is unlimited
+	MaxRestarts uint32 `protobuf:"varint,6,opt,name=max_restarts,json=maxRestarts,proto3" json:"max_restarts,omitempty"`
+	// output is written to <name>.log in the service log dir,
+	// rotated to <name>.log.1 ... <name>.log.<log_max_files>
+	LogMaxBytes int64  `protobuf:"varint,7,opt,name=log_max_bytes,json=logMaxBytes,proto3" json:"log_max_bytes,omitempty"`
+	LogMaxFiles uint32 `protobuf:"varint,8,opt,name=log_max_files,json=logMaxFiles,proto3" json:"log_max_files,omitempty"`
+	// time between SIGTERM and SIGKILL on stop
+	StopTimeoutMs        int64    `protobuf:"varint,9,opt,name=stop_timeout_ms,json=stopTimeoutMs,proto3" json:"stop_timeout_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServiceSpec) Reset()         { *m = ServiceSpec{} }
+func (m *ServiceSpec) String() string { return proto.CompactTextString(m) }
+func (*ServiceSpec) ProtoMessage()    {}
+func (*ServiceSpec) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{28}
+}
+
+func (m *ServiceSpec) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceSpec.Unmarshal(m, b)
+}
+func (m *ServiceSpec) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceSpec.Marshal(b, m, deterministic)
+}
+func (m *ServiceSpec) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceSpec.Merge(m, src)
+}
+func (m *ServiceSpec) XXX_Size() int {
+	return xxx_messageInfo_ServiceSpec.Size(m)
+}
+func (m *ServiceSpec) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceSpec.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceSpec proto.InternalMessageInfo
+
+func (m *ServiceSpec) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ServiceSpec) GetCommand() *Command {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *ServiceSpec) GetRestart() RestartPolicy {
+	if m != nil {
+		return m.Restart
+	}
+	return RestartPolicy_RESTART_NEVER
+}
+
+func (m *ServiceSpec) GetBackoffMs() int64 {
+	if m != nil {
+		return m.BackoffMs
+	}
+	return 0
+}
+
+func (m *ServiceSpec) GetMaxBackoffMs() int64 {
+	if m != nil {
+		return m.MaxBackoffMs
+	}
+	return 0
+}
+
+func (m *ServiceSpec) GetMaxRestarts() uint32 {
+	if m != nil {
+		return m.MaxRestarts
+	}
+	return 0
+}
+
+func (m *ServiceSpec) GetLogMaxBytes() int64 {
+	if m != nil {
+		return m.LogMaxBytes
+	}
+	return 0
+}
+
+func (m *ServiceSpec) GetLogMaxFiles() uint32 {
+	if m != nil {
+		return m.LogMaxFiles
+	}
+	return 0
+}
+
+func (m *ServiceSpec) GetStopTimeoutMs() int64 {
+	if m != nil {
+		return m.StopTimeoutMs
+	}
+	return 0
+}
+
+type ServiceName struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServiceName) Reset()         { *m = ServiceName{} }
+func (m *ServiceName) String() string { return proto.CompactTextString(m) }
+func (*ServiceName) ProtoMessage()    {}
+func (*ServiceName) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{29}
 }
 
-func (m *Sn) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Sn.Unmarshal(m, b)
+func (m *ServiceName) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceName.Unmarshal(m, b)
 }
-func (m *Sn) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Sn.Marshal(b, m, deterministic)
+func (m *ServiceName) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceName.Marshal(b, m, deterministic)
 }
-func (m *Sn) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Sn.Merge(m, src)
+func (m *ServiceName) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceName.Merge(m, src)
 }
-func (m *Sn) XXX_Size() int {
-	return xxx_messageInfo_Sn.Size(m)
+func (m *ServiceName) XXX_Size() int {
+	return xxx_messageInfo_ServiceName.Size(m)
 }
-func (m *Sn) XXX_DiscardUnknown() {
-	xxx_messageInfo_Sn.DiscardUnknown(m)
+func (m *ServiceName) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceName.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Sn proto.InternalMessageInfo
+var xxx_messageInfo_ServiceName proto.InternalMessageInfo
 
-func (m *Sn) GetSn() uint32 {
+func (m *ServiceName) GetName() string {
 	if m != nil {
-		return m.Sn
+		return m.Name
 	}
-	return 0
+	return ""
 }
 
-type StartInput struct {
-	Sn                   uint32   `protobuf:"varint,1,opt,name=sn,proto3" json:"sn,omitempty"`
-	HasStdin             bool     `protobuf:"varint,2,opt,name=has_stdin,json=hasStdin,proto3" json:"has_stdin,omitempty"`
-	HasStdout            bool     `protobuf:"varint,3,opt,name=has_stdout,json=hasStdout,proto3" json:"has_stdout,omitempty"`
-	HasStderr            bool     `protobuf:"varint,4,opt,name=has_stderr,json=hasStderr,proto3" json:"has_stderr,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type ServiceStatus struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// starting, running, backoff, stopping, stopped, exited or failed
+	State    string `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Pid      int32  `protobuf:"varint,3,opt,name=pid,proto3" json:"pid,omitempty"`
+	Restarts uint32 `protobuf:"varint,4,opt,name=restarts,proto3" json:"restarts,omitempty"`
+	// unix seconds of the current or last process
+	StartedAt            int64        `protobuf:"varint,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	LastExitStatus       uint32       `protobuf:"varint,6,opt,name=last_exit_status,json=lastExitStatus,proto3" json:"last_exit_status,omitempty"`
+	LastError            []byte       `protobuf:"bytes,7,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LogPath              string       `protobuf:"bytes,8,opt,name=log_path,json=logPath,proto3" json:"log_path,omitempty"`
+	Spec                 *ServiceSpec `protobuf:"bytes,9,opt,name=spec,proto3" json:"spec,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
-func (m *StartInput) Reset()         { *m = StartInput{} }
-func (m *StartInput) String() string { return proto.CompactTextString(m) }
-func (*StartInput) ProtoMessage()    {}
-func (*StartInput) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{8}
+func (m *ServiceStatus) Reset()         { *m = ServiceStatus{} }
+func (m *ServiceStatus) String() string { return proto.CompactTextString(m) }
+func (*ServiceStatus) ProtoMessage()    {}
+func (*ServiceStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{30}
 }
 
-func (m *StartInput) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StartInput.Unmarshal(m, b)
+func (m *ServiceStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceStatus.Unmarshal(m, b)
 }
-func (m *StartInput) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StartInput.Marshal(b, m, deterministic)
+func (m *ServiceStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceStatus.Marshal(b, m, deterministic)
 }
-func (m *StartInput) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StartInput.Merge(m, src)
+func (m *ServiceStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceStatus.Merge(m, src)
 }
-func (m *StartInput) XXX_Size() int {
-	return xxx_messageInfo_StartInput.Size(m)
+func (m *ServiceStatus) XXX_Size() int {
+	return xxx_messageInfo_ServiceStatus.Size(m)
 }
-func (m *StartInput) XXX_DiscardUnknown() {
-	xxx_messageInfo_StartInput.DiscardUnknown(m)
+func (m *ServiceStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceStatus.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StartInput proto.InternalMessageInfo
+var xxx_messageInfo_ServiceStatus proto.InternalMessageInfo
 
-func (m *StartInput) GetSn() uint32 {
+func (m *ServiceStatus) GetName() string {
 	if m != nil {
-		return m.Sn
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ServiceStatus) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ServiceStatus) GetPid() int32 {
+	if m != nil {
+		return m.Pid
 	}
 	return 0
 }
 
-func (m *StartInput) GetHasStdin() bool {
+func (m *ServiceStatus) GetRestarts() uint32 {
 	if m != nil {
-		return m.HasStdin
+		return m.Restarts
 	}
-	return false
+	return 0
 }
 
-func (m *StartInput) GetHasStdout() bool {
+func (m *ServiceStatus) GetStartedAt() int64 {
 	if m != nil {
-		return m.HasStdout
+		return m.StartedAt
 	}
-	return false
+	return 0
 }
 
-func (m *StartInput) GetHasStderr() bool {
+func (m *ServiceStatus) GetLastExitStatus() uint32 {
 	if m != nil {
-		return m.HasStderr
+		return m.LastExitStatus
 	}
-	return false
+	return 0
 }
 
-type Error struct {
-	Error                []byte   `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *ServiceStatus) GetLastError() []byte {
+	if m != nil {
+		return m.LastError
+	}
+	return nil
 }
 
-func (m *Error) Reset()         { *m = Error{} }
-func (m *Error) String() string { return proto.CompactTextString(m) }
-func (*Error) ProtoMessage()    {}
-func (*Error) Descriptor() ([]byte, []int) {
-	return fileDescriptor_12d1cdcda51e000f, []int{9}
+func (m *ServiceStatus) GetLogPath() string {
+	if m != nil {
+		return m.LogPath
+	}
+	return ""
 }
 
-func (m *Error) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Error.Unmarshal(m, b)
+func (m *ServiceStatus) GetSpec() *ServiceSpec {
+	if m != nil {
+		return m.Spec
+	}
+	return nil
 }
-func (m *Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Error.Marshal(b, m, deterministic)
+
+type ServiceListResponse struct {
+	Services             []*ServiceStatus `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
-func (m *Error) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Error.Merge(m, src)
+
+func (m *ServiceListResponse) Reset()         { *m = ServiceListResponse{} }
+func (m *ServiceListResponse) String() string { return proto.CompactTextString(m) }
+func (*ServiceListResponse) ProtoMessage()    {}
+func (*ServiceListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_12d1cdcda51e000f, []int{31}
 }
-func (m *Error) XXX_Size() int {
-	return xxx_messageInfo_Error.Size(m)
+
+func (m *ServiceListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceListResponse.Unmarshal(m, b)
 }
-func (m *Error) XXX_DiscardUnknown() {
-	xxx_messageInfo_Error.DiscardUnknown(m)
+func (m *ServiceListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceListResponse.Marshal(b, m, deterministic)
+}
+func (m *ServiceListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceListResponse.Merge(m, src)
+}
+func (m *ServiceListResponse) XXX_Size() int {
+	return xxx_messageInfo_ServiceListResponse.Size(m)
+}
+func (m *ServiceListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceListResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Error proto.InternalMessageInfo
+var xxx_messageInfo_ServiceListResponse proto.InternalMessageInfo
 
-func (m *Error) GetError() []byte {
+func (m *ServiceListResponse) GetServices() []*ServiceStatus {
 	if m != nil {
-		return m.Error
+		return m.Services
 	}
 	return nil
 }
 
 func init() {
+	proto.RegisterEnum("apis.EnvMode", EnvMode_name, EnvMode_value)
+	proto.RegisterEnum("apis.IoClass", IoClass_name, IoClass_value)
+	proto.RegisterEnum("apis.RestartPolicy", RestartPolicy_name, RestartPolicy_value)
 	proto.RegisterType((*Command)(nil), "apis.Command")
+	proto.RegisterType((*Landlock)(nil), "apis.Landlock")
+	proto.RegisterType((*LandlockStatus)(nil), "apis.LandlockStatus")
+	proto.RegisterType((*Isolation)(nil), "apis.Isolation")
+	proto.RegisterType((*Sched)(nil), "apis.Sched")
+	proto.RegisterType((*Capabilities)(nil), "apis.Capabilities")
 	proto.RegisterType((*Input)(nil), "apis.Input")
 	proto.RegisterType((*Stdout)(nil), "apis.Stdout")
 	proto.RegisterType((*Stderr)(nil), "apis.Stderr")
@@ -545,43 +2545,187 @@ func init() {
 	proto.RegisterType((*Sn)(nil), "apis.Sn")
 	proto.RegisterType((*StartInput)(nil), "apis.StartInput")
 	proto.RegisterType((*Error)(nil), "apis.Error")
+	proto.RegisterType((*ExecStart)(nil), "apis.ExecStart")
+	proto.RegisterType((*PassedFiles)(nil), "apis.PassedFiles")
+	proto.RegisterType((*OutputAck)(nil), "apis.OutputAck")
+	proto.RegisterType((*ExecRequest)(nil), "apis.ExecRequest")
+	proto.RegisterType((*ExecResponse)(nil), "apis.ExecResponse")
+	proto.RegisterType((*LookPathResponse)(nil), "apis.LookPathResponse")
+	proto.RegisterType((*Empty)(nil), "apis.Empty")
+	proto.RegisterType((*ProcessInfo)(nil), "apis.ProcessInfo")
+	proto.RegisterType((*InspectResponse)(nil), "apis.InspectResponse")
+	proto.RegisterType((*FlowStatus)(nil), "apis.FlowStatus")
+	proto.RegisterType((*ListResponse)(nil), "apis.ListResponse")
+	proto.RegisterType((*InfoResponse)(nil), "apis.InfoResponse")
+	proto.RegisterType((*SignalRequest)(nil), "apis.SignalRequest")
+	proto.RegisterType((*ServiceSpec)(nil), "apis.ServiceSpec")
+	proto.RegisterType((*ServiceName)(nil), "apis.ServiceName")
+	proto.RegisterType((*ServiceStatus)(nil), "apis.ServiceStatus")
+	proto.RegisterType((*ServiceListResponse)(nil), "apis.ServiceListResponse")
 }
 
 func init() { proto.RegisterFile("executor.proto", fileDescriptor_12d1cdcda51e000f) }
 
 var fileDescriptor_12d1cdcda51e000f = []byte{
-	// 485 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0x4f, 0x6b, 0xdb, 0x4e,
-	0x10, 0x45, 0x7f, 0xec, 0xc8, 0x23, 0x3b, 0x84, 0xfd, 0x85, 0x1f, 0xc2, 0xc5, 0x34, 0xa8, 0xa5,
-	0xc9, 0xa5, 0x26, 0xb4, 0x1f, 0xa0, 0x87, 0x90, 0x42, 0xe9, 0xa5, 0x48, 0x94, 0x1e, 0x8d, 0x2a,
-	0x2d, 0xb5, 0xc0, 0x5e, 0x89, 0x99, 0x55, 0x9b, 0xcf, 0xd3, 0x4f, 0x5a, 0x66, 0x76, 0xe5, 0x2a,
-	0x6d, 0x8e, 0xbd, 0xcd, 0x7b, 0x6f, 0x76, 0x66, 0xf7, 0x3d, 0x21, 0x38, 0xd7, 0x0f, 0xba, 0x1e,
-	0x6c, 0x87, 0xdb, 0x1e, 0x3b, 0xdb, 0xa9, 0xb8, 0xea, 0x5b, 0xca, 0x3f, 0xc3, 0xd9, 0x5d, 0x77,
-	0x3c, 0x56, 0xa6, 0x51, 0x0a, 0xe2, 0xbe, 0xb2, 0xfb, 0x2c, 0xb8, 0x0a, 0x6e, 0x96, 0x85, 0xd4,
-	0xcc, 0x55, 0xf8, 0x8d, 0xb2, 0xf0, 0x2a, 0x62, 0x8e, 0x6b, 0x75, 0x01, 0x91, 0x36, 0xdf, 0xb3,
-	0x48, 0x28, 0x2e, 0x99, 0x69, 0x5a, 0xcc, 0x62, 0x39, 0xc8, 0x65, 0xfe, 0x1a, 0x66, 0x1f, 0x4c,
-	0x3f, 0x58, 0x75, 0x0e, 0x21, 0x19, 0x19, 0xb9, 0x2a, 0x42, 0x32, 0xea, 0x12, 0x66, 0x2d, 0x0b,
-	0x59, 0x28, 0xcd, 0x0e, 0xe4, 0x04, 0xf3, 0xd2, 0x36, 0xdd, 0x60, 0xd5, 0xff, 0x30, 0x27, 0xa9,
-	0xfc, 0x35, 0x3c, 0x62, 0xbe, 0x3e, 0x74, 0xa4, 0x1b, 0x39, 0x98, 0x14, 0x1e, 0xa9, 0x17, 0xb0,
-	0xc2, 0xc1, 0xd8, 0xf6, 0xa8, 0x77, 0x1a, 0xb1, 0xc3, 0x2c, 0x92, 0x63, 0x4b, 0x4f, 0xde, 0x33,
-	0xc7, 0x4b, 0xc9, 0x56, 0x68, 0xe5, 0x86, 0x49, 0xe1, 0x80, 0x5f, 0xaa, 0x11, 0xfd, 0x52, 0x8d,
-	0x38, 0x59, 0xea, 0xf9, 0x7f, 0xbd, 0xf4, 0x1d, 0xac, 0x4a, 0x2e, 0x0a, 0x4d, 0x7d, 0x67, 0x48,
-	0xab, 0x0c, 0xce, 0x68, 0xa8, 0x6b, 0x4d, 0x24, 0xcb, 0x93, 0x62, 0x84, 0x3c, 0xc0, 0x4d, 0xf7,
-	0x56, 0x09, 0xc8, 0x37, 0x90, 0x7e, 0xa9, 0x5a, 0x3b, 0x86, 0xf6, 0x87, 0xbf, 0xf9, 0x27, 0x58,
-	0xb2, 0x7c, 0x1a, 0xff, 0x1c, 0x52, 0xfd, 0xd0, 0xda, 0x1d, 0xd9, 0xca, 0x0e, 0xe4, 0x1b, 0x81,
-	0xa9, 0x52, 0x18, 0x69, 0x40, 0xdc, 0xd5, 0x9d, 0xb1, 0xda, 0x8c, 0xb1, 0x80, 0x46, 0xbc, 0x73,
-	0x4c, 0x7e, 0x09, 0x61, 0x69, 0xfe, 0xda, 0xf3, 0x03, 0x40, 0xde, 0xf1, 0x74, 0xca, 0xcf, 0x60,
-	0xb1, 0xaf, 0x68, 0x47, 0xb6, 0x69, 0x8d, 0xf7, 0x2e, 0xd9, 0x57, 0x54, 0x32, 0x56, 0x1b, 0x00,
-	0x2f, 0x72, 0xcc, 0x91, 0xa8, 0x0b, 0xa7, 0x72, 0xd2, 0xbf, 0x65, 0x0e, 0x24, 0x9e, 0xca, 0x1a,
-	0xf9, 0xfd, 0xb3, 0x93, 0xbf, 0xce, 0x9e, 0x60, 0x62, 0xcf, 0x9b, 0x9f, 0x21, 0x24, 0xf7, 0xfe,
-	0x43, 0x57, 0xd7, 0xb0, 0x28, 0xb5, 0x69, 0xdc, 0x1d, 0xd3, 0x2d, 0x7f, 0xf0, 0x5b, 0x01, 0x6b,
-	0x0f, 0x64, 0xd2, 0x4d, 0xa0, 0xae, 0x21, 0x7d, 0xaf, 0x6d, 0xbd, 0xf7, 0x57, 0x48, 0x9c, 0x5a,
-	0x9a, 0xf5, 0xd2, 0x57, 0xc2, 0xdf, 0x3e, 0x6a, 0xe4, 0x0f, 0xe4, 0xa9, 0x46, 0x8d, 0x78, 0x1b,
-	0xa8, 0x2d, 0xcc, 0xc4, 0x1f, 0x75, 0x31, 0x0a, 0xa3, 0x59, 0xeb, 0xff, 0x26, 0xcc, 0x29, 0xa7,
-	0x97, 0x10, 0x73, 0x6e, 0x93, 0x89, 0xca, 0x55, 0x8f, 0xd2, 0x7c, 0x05, 0x29, 0x3f, 0x6e, 0x0c,
-	0x7f, 0xe5, 0x5a, 0x3c, 0x5c, 0x9f, 0xce, 0xaa, 0x0d, 0xc4, 0x1f, 0xdb, 0xc3, 0x61, 0x32, 0x6d,
-	0xfa, 0xe0, 0xaf, 0x73, 0xf9, 0x03, 0xbc, 0xfd, 0x15, 0x00, 0x00, 0xff, 0xff, 0x60, 0x42, 0x00,
-	0x66, 0x13, 0x04, 0x00, 0x00,
+	// 2519 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x59, 0xcb, 0x6e, 0xe3, 0xc8,
+	0xd5, 0x36, 0x75, 0xb1, 0xc8, 0xa3, 0x8b, 0xe5, 0xea, 0x9e, 0x06, 0xc7, 0x03, 0xff, 0xe3, 0xe6,
+	0x5c, 0xac, 0xdf, 0xc1, 0x74, 0x7a, 0x26, 0xc9, 0x64, 0x91, 0x45, 0x20, 0xf7, 0xc8, 0x63, 0x21,
+	0x6e, 0xd9, 0x28, 0xb9, 0x7b, 0x30, 0x2b, 0x86, 0x26, 0x4b, 0x36, 0xc7, 0x22, 0x8b, 0x53, 0x45,
+	0xf9, 0xb2, 0xca, 0x3a, 0x9b, 0x00, 0x09, 0x10, 0x64, 0x9b, 0x07, 0xc8, 0x0b, 0x04, 0xc9, 0x4b,
+	0xe4, 0x0d, 0xb2, 0xc8, 0x13, 0x04, 0xc8, 0x3e, 0x38, 0x55, 0x45, 0x8a, 0x72, 0xab, 0x93, 0x0c,
+	0x90, 0x45, 0x76, 0x55, 0x5f, 0x7d, 0xac, 0xcb, 0xa9, 0x73, 0xf9, 0x4a, 0x82, 0x1e, 0xbb, 0x63,
+	0xe1, 0x22, 0xe7, 0xe2, 0x59, 0x26, 0x78, 0xce, 0x49, 0x23, 0xc8, 0x62, 0xe9, 0xfd, 0xbd, 0x06,
+	0xad, 0x17, 0x3c, 0x49, 0x82, 0x34, 0x22, 0x04, 0x1a, 0x59, 0x90, 0x5f, 0xb9, 0xd6, 0x9e, 0x35,
+	0xe8, 0x50, 0xd5, 0x46, 0x2c, 0x10, 0x97, 0xd2, 0xad, 0xed, 0xd5, 0x11, 0xc3, 0x36, 0xe9, 0x43,
+	0x9d, 0xa5, 0x37, 0x6e, 0x5d, 0x41, 0xd8, 0x44, 0x24, 0x8a, 0x85, 0xdb, 0x50, 0x1f, 0x62, 0x93,
+	0x0c, 0xc0, 0x66, 0xe9, 0x8d, 0x9f, 0xf0, 0x88, 0xb9, 0xcd, 0x3d, 0x6b, 0xd0, 0xfb, 0xac, 0xfb,
+	0x0c, 0x17, 0x7c, 0x36, 0x4a, 0x6f, 0x5e, 0xf2, 0x88, 0xd1, 0x16, 0xd3, 0x0d, 0xb2, 0x0f, 0x5b,
+	0x92, 0x85, 0x21, 0x4f, 0x32, 0x3f, 0x13, 0x7c, 0x16, 0xcf, 0x99, 0xbb, 0xb9, 0x67, 0x0d, 0x1c,
+	0xda, 0x33, 0xf0, 0x99, 0x46, 0x71, 0x2b, 0x0b, 0xc9, 0x84, 0xdb, 0x52, 0xa3, 0xaa, 0x4d, 0x3e,
+	0x87, 0x4e, 0x18, 0x64, 0xc1, 0x45, 0x3c, 0x8f, 0xf3, 0x98, 0x49, 0xd7, 0xde, 0xb3, 0x06, 0xed,
+	0xcf, 0x88, 0x5e, 0xea, 0x45, 0x65, 0x84, 0xae, 0xf0, 0xc8, 0x53, 0x68, 0xca, 0xf0, 0x8a, 0x45,
+	0xae, 0xa3, 0x3e, 0x68, 0xeb, 0x0f, 0xa6, 0x08, 0x51, 0x3d, 0x42, 0x3e, 0x01, 0x27, 0x96, 0x7c,
+	0x1e, 0xe4, 0x31, 0x4f, 0x5d, 0x50, 0xb4, 0x2d, 0x4d, 0x1b, 0x17, 0x30, 0x5d, 0x32, 0xc8, 0x01,
+	0xd8, 0xf3, 0x20, 0x8d, 0xe6, 0x3c, 0xbc, 0x76, 0xdb, 0x8a, 0xdd, 0xd3, 0xec, 0x13, 0x83, 0xd2,
+	0x72, 0xdc, 0xfb, 0xa5, 0x05, 0x76, 0x01, 0x93, 0x5d, 0x00, 0xc1, 0x82, 0xc8, 0x47, 0x73, 0x4b,
+	0xd7, 0xda, 0xab, 0x0f, 0x1c, 0xea, 0x20, 0x72, 0x86, 0x00, 0x79, 0x1f, 0xda, 0xb7, 0x22, 0xce,
+	0x99, 0x19, 0xaf, 0xa9, 0x71, 0x50, 0x90, 0x26, 0x3c, 0x85, 0x8e, 0xbc, 0x97, 0x39, 0x4b, 0x0c,
+	0xa3, 0xbe, 0x67, 0x0d, 0x6c, 0xda, 0xd6, 0x98, 0xa6, 0xec, 0x80, 0x2d, 0xd8, 0xb7, 0x8b, 0x58,
+	0xb0, 0x48, 0xdd, 0x91, 0x4d, 0xcb, 0xbe, 0xf7, 0x1a, 0x7a, 0xc5, 0x56, 0xa6, 0x79, 0x90, 0x2f,
+	0x14, 0x9b, 0xa5, 0x33, 0x2e, 0x42, 0x16, 0x29, 0x57, 0xb0, 0x69, 0xd9, 0xc7, 0x8b, 0x0e, 0x2e,
+	0x62, 0xb7, 0xb6, 0x67, 0x0d, 0x9a, 0x14, 0x9b, 0xe4, 0x09, 0x6c, 0x0a, 0x16, 0x48, 0x9e, 0xaa,
+	0x85, 0x1d, 0x6a, 0x7a, 0xde, 0xaf, 0x2c, 0x70, 0x4a, 0x43, 0xe1, 0x77, 0x29, 0xcb, 0xcd, 0x74,
+	0xd8, 0x24, 0x8f, 0xa1, 0x99, 0xf0, 0x45, 0x9a, 0xab, 0xb9, 0x6c, 0xaa, 0x3b, 0xc8, 0xcb, 0xe2,
+	0xc8, 0x9c, 0x01, 0x9b, 0xe4, 0x63, 0xd8, 0x52, 0xe6, 0xe1, 0xe9, 0xfc, 0xde, 0x9c, 0xb0, 0xa1,
+	0x6c, 0xd0, 0x45, 0xf8, 0x34, 0x9d, 0xdf, 0x97, 0x76, 0xca, 0x44, 0x7c, 0x13, 0xe4, 0xcc, 0xcf,
+	0x93, 0x4c, 0xf9, 0x9c, 0x4d, 0xc1, 0x40, 0xe7, 0x49, 0xe6, 0xfd, 0xae, 0x06, 0x4d, 0x75, 0xc1,
+	0xe4, 0x5d, 0xb0, 0xaf, 0x02, 0xe9, 0xa7, 0x71, 0xc8, 0xcc, 0x8e, 0x5a, 0x57, 0x81, 0x9c, 0xc4,
+	0xa1, 0xf2, 0x31, 0x05, 0xeb, 0x03, 0xaa, 0x36, 0xba, 0x72, 0xcc, 0xfd, 0x70, 0x1e, 0x48, 0x6d,
+	0xdc, 0xd2, 0x95, 0xc7, 0xfc, 0x05, 0x82, 0xb4, 0x15, 0xeb, 0x06, 0xee, 0x21, 0xe6, 0x7e, 0x26,
+	0x62, 0x2e, 0xe2, 0xfc, 0x5e, 0x99, 0xba, 0x49, 0x21, 0xe6, 0x67, 0x06, 0xc1, 0xe9, 0xc3, 0x6c,
+	0x21, 0xdd, 0xe6, 0x5e, 0x7d, 0xd0, 0xa5, 0xaa, 0x4d, 0xfe, 0x1f, 0xb6, 0x71, 0x37, 0x9c, 0x27,
+	0xbe, 0x0c, 0xb9, 0x60, 0x7e, 0x10, 0x7d, 0xa3, 0x22, 0xc0, 0xa6, 0xbd, 0xab, 0x40, 0x9e, 0xf2,
+	0x64, 0x8a, 0xf0, 0x30, 0xfa, 0x86, 0x78, 0xd0, 0x5d, 0xa5, 0xb5, 0xd4, 0x0a, 0x6d, 0x5e, 0xe1,
+	0xbc, 0x07, 0x0e, 0x4e, 0xb7, 0x48, 0x02, 0x79, 0xad, 0xc2, 0xc1, 0xa6, 0x78, 0xda, 0x57, 0xd8,
+	0x47, 0xa3, 0xeb, 0x01, 0x74, 0xfb, 0x2e, 0xd5, 0x1d, 0xef, 0x0c, 0x3a, 0xd5, 0x50, 0xc1, 0x5d,
+	0x5e, 0x33, 0x96, 0x19, 0x5f, 0x54, 0x6d, 0xc4, 0x22, 0xc1, 0x33, 0xe3, 0x7f, 0xaa, 0x4d, 0x5c,
+	0x68, 0x05, 0xc9, 0x45, 0xcc, 0xd2, 0x5c, 0xe5, 0x02, 0x87, 0x16, 0x5d, 0xef, 0x13, 0x68, 0x8e,
+	0xd3, 0x6c, 0x91, 0x93, 0x1e, 0xd4, 0x64, 0xaa, 0x8c, 0xdc, 0xa5, 0x35, 0x99, 0xe2, 0x06, 0x62,
+	0x1c, 0x50, 0x06, 0xee, 0x50, 0xdd, 0xf1, 0x24, 0x6c, 0x4e, 0xf3, 0x88, 0x2f, 0x72, 0xf4, 0x26,
+	0xa9, 0x5a, 0x26, 0x09, 0x6d, 0xca, 0x12, 0x0f, 0xe7, 0x5c, 0xb2, 0xc8, 0xb8, 0x8b, 0xe9, 0x91,
+	0x0f, 0xa0, 0x2b, 0x16, 0x69, 0x1e, 0x27, 0xcc, 0x67, 0x42, 0x70, 0xa1, 0x2e, 0xa8, 0x43, 0x3b,
+	0x06, 0x1c, 0x21, 0x86, 0x8b, 0xca, 0x3c, 0x10, 0xb9, 0xf1, 0x7d, 0xdd, 0x31, 0x8b, 0x32, 0x21,
+	0xcc, 0xa2, 0x4c, 0x88, 0xca, 0xa2, 0x06, 0xff, 0x6f, 0x2f, 0xfa, 0x67, 0x0b, 0xba, 0x53, 0x6c,
+	0x51, 0x26, 0x33, 0x9e, 0x4a, 0x86, 0x46, 0x94, 0x8b, 0x30, 0x64, 0x52, 0x16, 0xbe, 0x68, 0xba,
+	0x38, 0x83, 0x9e, 0xde, 0xd8, 0x4a, 0x75, 0x8c, 0x45, 0xeb, 0xa5, 0x45, 0x9f, 0x57, 0xf2, 0x4e,
+	0x43, 0xe5, 0x9d, 0xc7, 0xab, 0x79, 0x47, 0x47, 0xf5, 0x32, 0xfb, 0xe0, 0xb1, 0x6e, 0xe3, 0x34,
+	0xe2, 0xb7, 0x2a, 0x48, 0xba, 0xd4, 0xf4, 0x54, 0x22, 0xc9, 0xa3, 0x38, 0xf5, 0xf9, 0x6c, 0x26,
+	0x59, 0xae, 0x7c, 0xb0, 0x41, 0xdb, 0x0a, 0x3b, 0x55, 0x90, 0xb7, 0x0b, 0xed, 0xaf, 0x82, 0x38,
+	0x2f, 0x0a, 0xc6, 0x83, 0xdb, 0x45, 0x47, 0xc2, 0xe1, 0xf2, 0x6c, 0xef, 0x43, 0x9b, 0xdd, 0xc5,
+	0xb9, 0x2f, 0xd5, 0x16, 0x0c, 0x11, 0x10, 0x32, 0xa9, 0x06, 0x09, 0x42, 0xf8, 0x21, 0x4f, 0x73,
+	0x96, 0x16, 0x4e, 0x01, 0x4c, 0x88, 0x17, 0x1a, 0xf1, 0x1e, 0x43, 0x6d, 0x9a, 0xbe, 0xb1, 0xce,
+	0xef, 0x2d, 0x00, 0x65, 0xc5, 0xf5, 0x4e, 0x66, 0x42, 0x40, 0x6d, 0xdc, 0xad, 0x95, 0x21, 0x30,
+	0xc5, 0x3e, 0xa6, 0x5b, 0x33, 0x88, 0x5e, 0xa6, 0x13, 0x8d, 0xa3, 0x47, 0xd1, 0xd1, 0x96, 0xc3,
+	0xe8, 0x0f, 0x8d, 0xea, 0x30, 0xba, 0xc4, 0x3e, 0x6c, 0x85, 0x3c, 0xb9, 0x88, 0x53, 0x16, 0xf9,
+	0x7c, 0x91, 0xa3, 0x27, 0xeb, 0x4c, 0xd3, 0x2b, 0xe0, 0x53, 0x85, 0x7a, 0xbb, 0xd0, 0x2c, 0xfd,
+	0x40, 0xdf, 0xa2, 0x55, 0xb9, 0x45, 0xef, 0xaf, 0x35, 0x70, 0x46, 0x77, 0x2c, 0x54, 0xa7, 0x20,
+	0xfb, 0xd0, 0x0a, 0xb5, 0x49, 0x15, 0xab, 0x5d, 0x24, 0x18, 0x63, 0x67, 0x5a, 0x8c, 0xfe, 0x2f,
+	0x9c, 0x0c, 0xf7, 0x20, 0x98, 0x5c, 0x24, 0xcc, 0x97, 0xa9, 0xf2, 0x91, 0x2e, 0xb5, 0x35, 0x30,
+	0x4d, 0x31, 0x34, 0xf4, 0xfa, 0x85, 0x13, 0xb5, 0x94, 0x13, 0x75, 0x34, 0xa8, 0xbd, 0xc8, 0x90,
+	0xf0, 0xe2, 0x0d, 0xc9, 0x2e, 0x49, 0x4c, 0x08, 0x43, 0x5a, 0x7a, 0xa9, 0xb3, 0xe2, 0xa5, 0xfb,
+	0xd0, 0x44, 0x35, 0x20, 0x4d, 0x49, 0xde, 0xd6, 0x96, 0x3a, 0x0b, 0xa4, 0x64, 0xd1, 0x11, 0x0e,
+	0x50, 0x3d, 0xee, 0xfd, 0x02, 0xda, 0x15, 0x14, 0xef, 0x21, 0xe7, 0xd7, 0x4c, 0xfb, 0x89, 0x43,
+	0x75, 0x47, 0x47, 0xe9, 0xd2, 0x98, 0xba, 0x53, 0xc9, 0x42, 0xda, 0x8a, 0x95, 0x2c, 0xb4, 0x62,
+	0x3e, 0xd3, 0x53, 0x77, 0x7c, 0x97, 0x8b, 0xc0, 0x04, 0x94, 0xee, 0x78, 0x3f, 0x01, 0x47, 0x9b,
+	0x6c, 0xa8, 0x83, 0xae, 0x92, 0xd8, 0x1a, 0x6b, 0xa6, 0xac, 0x95, 0x38, 0x13, 0xc2, 0xfb, 0x93,
+	0x05, 0x6d, 0x74, 0x10, 0xca, 0xbe, 0x5d, 0x30, 0x89, 0x2e, 0x62, 0xd2, 0x89, 0x55, 0x55, 0x22,
+	0xa5, 0x0b, 0x1d, 0x6f, 0x98, 0x0c, 0x43, 0x9e, 0x54, 0x4f, 0xd4, 0x39, 0xde, 0x28, 0xce, 0xf4,
+	0x14, 0xda, 0x2a, 0x7d, 0x19, 0xe7, 0x51, 0x07, 0x3b, 0xde, 0xa0, 0xa0, 0x40, 0xed, 0x40, 0x2e,
+	0x6c, 0xca, 0xf8, 0x32, 0x0d, 0xe6, 0xba, 0x72, 0x1d, 0x6f, 0x50, 0xd3, 0x27, 0x1f, 0x40, 0x3d,
+	0x08, 0xaf, 0xdd, 0x66, 0x75, 0xed, 0xf2, 0x6c, 0xc7, 0x1b, 0x14, 0x47, 0x0f, 0x1d, 0x68, 0x09,
+	0xbd, 0x5b, 0xef, 0x6f, 0x16, 0x74, 0xf4, 0xee, 0x4d, 0x26, 0xf8, 0x3e, 0xb4, 0xd4, 0xf6, 0x58,
+	0xe1, 0xe1, 0x8f, 0x8c, 0xe2, 0xaa, 0xe6, 0xc2, 0xe3, 0x0d, 0x5a, 0xb0, 0xd4, 0x5e, 0xb4, 0xbd,
+	0x8a, 0x73, 0x14, 0x16, 0x73, 0x4b, 0x8b, 0xd5, 0x2b, 0x23, 0x78, 0x0d, 0x03, 0x68, 0x60, 0x6e,
+	0x71, 0x1b, 0x55, 0x11, 0x58, 0x4d, 0x48, 0xc7, 0x1b, 0x54, 0x31, 0xc8, 0x0e, 0xb4, 0xae, 0x82,
+	0x34, 0xe2, 0xb3, 0x99, 0x76, 0x72, 0x5c, 0xd9, 0x00, 0x64, 0x17, 0x1c, 0x9d, 0x06, 0xf1, 0xc4,
+	0x2a, 0x07, 0x1e, 0x6f, 0x50, 0x5b, 0x41, 0xc3, 0xf0, 0xfa, 0x10, 0x50, 0x4b, 0xe9, 0xe9, 0xbc,
+	0xaf, 0xa1, 0x7f, 0xc2, 0xf9, 0x35, 0x0a, 0x90, 0xf2, 0xa4, 0xeb, 0x44, 0xf4, 0x7b, 0xe0, 0xa4,
+	0x3c, 0xf7, 0x67, 0x7c, 0x91, 0x16, 0xb5, 0xc4, 0x4e, 0x79, 0x7e, 0x84, 0xfd, 0x65, 0x82, 0xa8,
+	0x57, 0x13, 0x44, 0x0b, 0x9a, 0xa3, 0x24, 0xcb, 0xef, 0xbd, 0xdf, 0x5a, 0xd0, 0x3e, 0x13, 0x1c,
+	0x2b, 0xc2, 0x38, 0x9d, 0xf1, 0x37, 0x92, 0x9d, 0x51, 0x4c, 0x46, 0x91, 0xa1, 0x62, 0x2a, 0x76,
+	0x50, 0x5f, 0x23, 0xe3, 0x1b, 0x15, 0x19, 0xbf, 0x0b, 0x60, 0xac, 0xed, 0x07, 0x3a, 0xd8, 0xeb,
+	0xd4, 0x31, 0xc8, 0x30, 0xd7, 0xba, 0x54, 0x5d, 0xa7, 0x1f, 0x47, 0x46, 0x92, 0x3b, 0x06, 0x19,
+	0x47, 0xde, 0x1f, 0x9a, 0xb0, 0x35, 0x4e, 0x65, 0xc6, 0xc2, 0x65, 0xbe, 0xff, 0x1e, 0xb4, 0x32,
+	0xbd, 0x55, 0xd7, 0x5a, 0x89, 0xce, 0xe5, 0xfe, 0x69, 0xc1, 0xc0, 0x8d, 0x2f, 0xcc, 0xc6, 0xbb,
+	0x14, 0x9b, 0x88, 0x5c, 0x1a, 0xf1, 0xd7, 0xa5, 0xd8, 0xc4, 0x4c, 0x11, 0x06, 0x99, 0xcf, 0x66,
+	0x33, 0x16, 0xe6, 0xf1, 0x0d, 0x33, 0xd2, 0x0f, 0xb5, 0xfc, 0xa8, 0xc0, 0x0a, 0x52, 0xc6, 0x44,
+	0x12, 0xe7, 0xe8, 0x61, 0xcd, 0x92, 0x74, 0x56, 0x60, 0x2a, 0xbd, 0x05, 0x99, 0x1f, 0xa7, 0x57,
+	0x4c, 0xc4, 0x79, 0x70, 0xa1, 0x5e, 0x19, 0x48, 0xeb, 0x85, 0x41, 0x36, 0x5e, 0xa2, 0x58, 0x05,
+	0x91, 0x78, 0x81, 0x77, 0x13, 0xa7, 0x97, 0x6e, 0x4b, 0xb1, 0xda, 0x61, 0x90, 0x1d, 0x1a, 0x08,
+	0xab, 0x16, 0x52, 0x0a, 0xed, 0x63, 0x2b, 0x06, 0x84, 0x41, 0x36, 0xd4, 0x08, 0xd9, 0x83, 0x4e,
+	0xca, 0xfd, 0x94, 0xdd, 0xa2, 0x16, 0xbc, 0x91, 0x2a, 0x83, 0xd9, 0x14, 0x52, 0x3e, 0x61, 0xb7,
+	0x67, 0x88, 0xe0, 0x2a, 0xc5, 0xa3, 0x47, 0x3d, 0x91, 0x40, 0x0b, 0x39, 0x83, 0xbd, 0xed, 0x5d,
+	0xd4, 0x7e, 0xdb, 0xbb, 0x48, 0x69, 0xd6, 0xce, 0x5b, 0x34, 0x6b, 0xf7, 0xbb, 0x68, 0xd6, 0xde,
+	0x5b, 0x35, 0xeb, 0x56, 0x45, 0xb3, 0xbe, 0x21, 0x44, 0xfb, 0x6f, 0x0a, 0xd1, 0x52, 0x6b, 0x6e,
+	0x57, 0xb4, 0x26, 0xf9, 0x3f, 0x80, 0x34, 0x48, 0x98, 0xcc, 0x82, 0x90, 0x49, 0x97, 0x68, 0xd3,
+	0x2d, 0x91, 0x15, 0x39, 0xf3, 0xe8, 0x3f, 0x92, 0x33, 0x1f, 0x42, 0x63, 0x36, 0xe7, 0xb7, 0xee,
+	0x63, 0xc5, 0xee, 0x6b, 0xf6, 0xd1, 0x9c, 0xdf, 0x1a, 0xa6, 0x1a, 0xf5, 0xfe, 0x61, 0x01, 0x2c,
+	0xc1, 0x4a, 0x75, 0xb1, 0x56, 0xaa, 0xcb, 0x47, 0xd0, 0xd3, 0xc1, 0x7f, 0xb1, 0x98, 0xcd, 0x98,
+	0x30, 0xd2, 0xaf, 0x41, 0xbb, 0x0a, 0x3d, 0x34, 0xa0, 0xba, 0x1b, 0x5d, 0xe6, 0x4a, 0x5e, 0x5d,
+	0xf1, 0x7a, 0x1a, 0x7e, 0x40, 0xc4, 0x52, 0x57, 0x12, 0x1b, 0x25, 0x91, 0x09, 0x51, 0x12, 0x0f,
+	0x60, 0xdb, 0xcc, 0x28, 0xf3, 0x60, 0x3e, 0x67, 0x91, 0x9f, 0x48, 0x13, 0x93, 0x66, 0xa9, 0xa9,
+	0xc6, 0x5f, 0x4a, 0xc3, 0xc5, 0x49, 0x2b, 0xdc, 0xcd, 0x92, 0xcb, 0x84, 0x28, 0xb9, 0xde, 0x4f,
+	0xa1, 0x73, 0x12, 0xcb, 0xbc, 0x92, 0x88, 0x1d, 0x13, 0x80, 0x4c, 0x3f, 0x36, 0xd7, 0x06, 0xe9,
+	0x92, 0xe3, 0xfd, 0xc6, 0x82, 0x8e, 0xc2, 0x2a, 0x82, 0xf5, 0x86, 0x09, 0x89, 0xaf, 0x62, 0x5d,
+	0x4a, 0x8b, 0xee, 0x9a, 0x54, 0xb4, 0x9a, 0x62, 0xea, 0x0f, 0x53, 0x8c, 0x0b, 0x2d, 0xb1, 0x48,
+	0x53, 0x0c, 0xb3, 0x86, 0xba, 0x86, 0xa2, 0x8b, 0x1f, 0x5e, 0x72, 0xbf, 0x58, 0xa7, 0xa9, 0x93,
+	0xcf, 0x25, 0x7f, 0xad, 0x01, 0xef, 0xc7, 0xd0, 0x9d, 0xaa, 0xca, 0x54, 0x94, 0xc7, 0x87, 0x59,
+	0xf1, 0x49, 0x59, 0xca, 0xf4, 0x6e, 0x4c, 0xcf, 0xfb, 0x4b, 0x0d, 0xda, 0x53, 0x26, 0x6e, 0xe2,
+	0x90, 0x4d, 0x33, 0x16, 0xaa, 0xd8, 0x09, 0x12, 0x66, 0x4e, 0xa2, 0xda, 0x55, 0x35, 0x56, 0xfb,
+	0x97, 0x6a, 0xec, 0x13, 0x2c, 0x78, 0xba, 0x2a, 0xeb, 0x77, 0xa1, 0x29, 0x6a, 0x54, 0x83, 0x67,
+	0x7c, 0x1e, 0x87, 0xf7, 0xb4, 0xe0, 0xe0, 0x99, 0x2e, 0x82, 0xf0, 0x9a, 0xcf, 0x66, 0x78, 0x5f,
+	0x0d, 0x6d, 0x0c, 0x83, 0xbc, 0x94, 0xe4, 0x43, 0xe8, 0x25, 0xc1, 0x9d, 0x5f, 0xa1, 0xe8, 0xeb,
+	0xef, 0x24, 0xc1, 0xdd, 0x61, 0xc9, 0x7a, 0x0a, 0xd8, 0xf7, 0xcd, 0x9c, 0xd2, 0x08, 0xb0, 0x76,
+	0x12, 0xdc, 0x99, 0x55, 0x55, 0x70, 0xce, 0xf9, 0xa5, 0xaf, 0x26, 0xbb, 0xcf, 0x99, 0x54, 0x1a,
+	0xac, 0x4e, 0xdb, 0x73, 0x7e, 0xf9, 0x32, 0xb8, 0x3b, 0x44, 0xa8, 0xca, 0xd1, 0x6a, 0xca, 0xd6,
+	0xf3, 0x68, 0x8e, 0x56, 0x4c, 0x1f, 0xa3, 0xef, 0xf2, 0xcc, 0xc7, 0x37, 0x0d, 0x3a, 0x66, 0xa2,
+	0x13, 0x59, 0x1d, 0x83, 0x81, 0x67, 0xe7, 0x1a, 0x7d, 0x29, 0xbd, 0xa7, 0xa5, 0x49, 0x27, 0x68,
+	0xbe, 0x35, 0x26, 0xf5, 0x7e, 0x5d, 0x83, 0x6e, 0x61, 0x76, 0x1d, 0x80, 0xeb, 0x0c, 0xaf, 0x9f,
+	0x4c, 0xb9, 0x7e, 0x7d, 0x3b, 0x54, 0x77, 0xaa, 0x3f, 0x09, 0x18, 0xaf, 0x52, 0x3f, 0x67, 0x98,
+	0xf3, 0x37, 0x4a, 0x01, 0xaa, 0x0f, 0xff, 0x6f, 0x8a, 0xda, 0x00, 0xfa, 0xf3, 0x40, 0xe6, 0x7e,
+	0xf5, 0x59, 0xa2, 0x4d, 0xd8, 0x43, 0x7c, 0xb4, 0x7c, 0x9a, 0xec, 0x02, 0x68, 0xa6, 0xaa, 0xcd,
+	0x2d, 0x55, 0x4b, 0x1d, 0xc5, 0x41, 0x00, 0x7f, 0x43, 0x40, 0x03, 0xaa, 0x42, 0x6b, 0xeb, 0x30,
+	0x98, 0xf3, 0x4b, 0x54, 0x02, 0xe4, 0x23, 0x68, 0x60, 0x59, 0x34, 0x3f, 0x2d, 0x99, 0xe8, 0xaa,
+	0x38, 0x1d, 0x55, 0xc3, 0xde, 0x11, 0x3c, 0x32, 0xe0, 0x83, 0x00, 0xb5, 0xa5, 0x86, 0x8b, 0xf8,
+	0x7c, 0xb4, 0x3a, 0x83, 0xc9, 0x7f, 0x05, 0xe9, 0x20, 0x84, 0x96, 0xf9, 0x4d, 0x8d, 0x6c, 0x41,
+	0x7b, 0x34, 0x79, 0xed, 0x7f, 0x31, 0x3a, 0x1a, 0xbe, 0x3a, 0x39, 0xef, 0x6f, 0x14, 0xc0, 0x78,
+	0x72, 0x3c, 0xa2, 0xe3, 0xf3, 0xbe, 0x45, 0x5c, 0x78, 0x5c, 0x01, 0xfc, 0xd3, 0xd7, 0x23, 0x4a,
+	0xc7, 0x5f, 0x8c, 0xfa, 0x35, 0xd2, 0x05, 0x07, 0x47, 0x5e, 0x9c, 0x8c, 0x86, 0x93, 0x7e, 0xbd,
+	0xe8, 0x9e, 0x9c, 0x7e, 0x39, 0x9e, 0xf4, 0x1b, 0x07, 0x3f, 0x87, 0x96, 0xa9, 0x1c, 0x64, 0x1b,
+	0xba, 0xe3, 0x53, 0xff, 0xc5, 0xc9, 0x70, 0x3a, 0xf5, 0x27, 0xa7, 0x93, 0x51, 0x7f, 0x83, 0xbc,
+	0x03, 0xdb, 0x25, 0x44, 0x47, 0xc3, 0x93, 0xf3, 0xf1, 0xcb, 0x91, 0x5e, 0xac, 0x84, 0x0f, 0x47,
+	0xd3, 0x73, 0x7f, 0x74, 0x74, 0x74, 0x4a, 0xcf, 0xfb, 0xb5, 0x95, 0x39, 0xc6, 0x5f, 0x9c, 0x8c,
+	0xfa, 0xf5, 0x83, 0x09, 0x74, 0x57, 0xe2, 0x06, 0x39, 0x74, 0x34, 0x3d, 0x1f, 0xd2, 0x73, 0x7f,
+	0x32, 0x7a, 0x3d, 0xa2, 0xfd, 0x0d, 0x42, 0xa0, 0x57, 0x40, 0xc3, 0x93, 0xaf, 0x86, 0x5f, 0x4f,
+	0xfb, 0x16, 0x79, 0x02, 0xa4, 0xc0, 0x4e, 0x27, 0xfe, 0xd1, 0x70, 0x7c, 0xf2, 0x8a, 0x8e, 0xfa,
+	0xb5, 0xcf, 0xfe, 0xb8, 0x09, 0xf6, 0xc8, 0xfc, 0xe2, 0x49, 0xf6, 0xc1, 0x99, 0xb2, 0x34, 0xd2,
+	0xcf, 0x45, 0xf3, 0x63, 0x9f, 0xea, 0xec, 0x98, 0x8e, 0xba, 0xd4, 0x81, 0x45, 0xf6, 0xa1, 0x7d,
+	0xc4, 0xf2, 0xf0, 0xca, 0xbc, 0x99, 0x6c, 0x63, 0xfa, 0x74, 0xa7, 0x63, 0x5a, 0x0a, 0x7f, 0xbe,
+	0x42, 0x44, 0xe9, 0xb9, 0x8e, 0xc8, 0x84, 0x78, 0x6e, 0x91, 0x67, 0xd0, 0xd4, 0x8f, 0xbc, 0x7e,
+	0x45, 0xf1, 0xea, 0xb5, 0xd7, 0x69, 0x60, 0x2c, 0x67, 0x28, 0x59, 0x2b, 0x33, 0xae, 0x11, 0xb2,
+	0xe4, 0x63, 0xfd, 0x3a, 0x28, 0x1e, 0xe2, 0xab, 0x19, 0x6a, 0xa7, 0xfc, 0x96, 0xec, 0x42, 0xe3,
+	0x67, 0xf1, 0x7c, 0x5e, 0x99, 0xad, 0x7a, 0x60, 0xf2, 0x29, 0xd8, 0x85, 0x80, 0x7d, 0x38, 0xc7,
+	0x13, 0x53, 0x76, 0x1f, 0xea, 0xdb, 0x4f, 0xa1, 0x81, 0x2b, 0x93, 0xed, 0xe5, 0x0b, 0xc4, 0x24,
+	0xe1, 0x1d, 0x52, 0x85, 0x34, 0x7d, 0x60, 0x29, 0x5b, 0x35, 0xd0, 0xc5, 0x0b, 0xc3, 0x2b, 0x5d,
+	0x5b, 0x90, 0x57, 0x7c, 0x7f, 0x1f, 0x1a, 0x4a, 0xe3, 0xae, 0x23, 0xae, 0xd4, 0xa0, 0x03, 0xd8,
+	0xd4, 0xf9, 0x9f, 0x14, 0x36, 0xac, 0x56, 0x83, 0xd5, 0x33, 0x0e, 0x60, 0x73, 0x98, 0xe7, 0x41,
+	0x78, 0xf5, 0xa6, 0x49, 0xab, 0x3b, 0x7d, 0x6e, 0x91, 0x03, 0x68, 0x19, 0x45, 0x5b, 0xa1, 0xbe,
+	0x53, 0x2c, 0xbf, 0x2a, 0x75, 0x3f, 0x87, 0x8e, 0xba, 0x37, 0x13, 0x95, 0xe4, 0xcd, 0x30, 0xdf,
+	0x59, 0x17, 0xb7, 0xe4, 0x47, 0xd0, 0x9e, 0xe6, 0x3c, 0x5b, 0xff, 0x19, 0xe6, 0xcf, 0xf5, 0x9f,
+	0xfd, 0x10, 0xe0, 0x4b, 0x96, 0x7f, 0xd7, 0xaf, 0x3e, 0xd7, 0xc5, 0xdf, 0x80, 0x72, 0xd5, 0xae,
+	0xef, 0xae, 0x7c, 0x51, 0xbd, 0x87, 0x8b, 0x4d, 0xf5, 0x0f, 0xc1, 0x0f, 0xfe, 0x39, 0x00, 0xed,
+	0xbf, 0xb2, 0xdd, 0x33, 0x18, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -603,6 +2747,27 @@ type ExecutorClient interface {
 	Wait(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*WaitResponse, error)
 	ExecCommand(ctx context.Context, in *Command, opts ...grpc.CallOption) (*Sn, error)
 	Kill(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*Error, error)
+	// LookPath resolves command path with the env and dir it would run with
+	LookPath(ctx context.Context, in *Command, opts ...grpc.CallOption) (*LookPathResponse, error)
+	// Exec runs a command on a single stream, replacing
+	// ExecCommand, Start, SendInput, FetchStdout, FetchStderr and Wait
+	Exec(ctx context.Context, opts ...grpc.CallOption) (Executor_ExecClient, error)
+	// List returns commands known to the server
+	List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListResponse, error)
+	Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*InfoResponse, error)
+	Signal(ctx context.Context, in *SignalRequest, opts ...grpc.CallOption) (*Error, error)
+	// Attach streams output of a running command from now on,
+	// ended by its exit
+	Attach(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_AttachClient, error)
+	// Inspect reports credentials and restrictions of a running command
+	Inspect(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*InspectResponse, error)
+	// StartService starts a supervised command, a finished service
+	// of the same name is replaced
+	StartService(ctx context.Context, in *ServiceSpec, opts ...grpc.CallOption) (*ServiceStatus, error)
+	// StopService stops the service and forgets it, logs are kept
+	StopService(ctx context.Context, in *ServiceName, opts ...grpc.CallOption) (*ServiceStatus, error)
+	GetService(ctx context.Context, in *ServiceName, opts ...grpc.CallOption) (*ServiceStatus, error)
+	ListServices(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServiceListResponse, error)
 }
 
 type executorClient struct {
@@ -613,78 +2778,213 @@ func NewExecutorClient(cc *grpc.ClientConn) ExecutorClient {
 	return &executorClient{cc}
 }
 
-func (c *executorClient) SendInput(ctx context.Context, opts ...grpc.CallOption) (Executor_SendInputClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[0], "/apis.Executor/SendInput", opts...)
+func (c *executorClient) SendInput(ctx context.Context, opts ...grpc.CallOption) (Executor_SendInputClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[0], "/apis.Executor/SendInput", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &executorSendInputClient{stream}
+	return x, nil
+}
+
+type Executor_SendInputClient interface {
+	Send(*Input) error
+	CloseAndRecv() (*Error, error)
+	grpc.ClientStream
+}
+
+type executorSendInputClient struct {
+	grpc.ClientStream
+}
+
+func (x *executorSendInputClient) Send(m *Input) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *executorSendInputClient) CloseAndRecv() (*Error, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Error)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *executorClient) FetchStdout(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_FetchStdoutClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[1], "/apis.Executor/FetchStdout", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &executorFetchStdoutClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Executor_FetchStdoutClient interface {
+	Recv() (*Stdout, error)
+	grpc.ClientStream
+}
+
+type executorFetchStdoutClient struct {
+	grpc.ClientStream
+}
+
+func (x *executorFetchStdoutClient) Recv() (*Stdout, error) {
+	m := new(Stdout)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *executorClient) FetchStderr(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_FetchStderrClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[2], "/apis.Executor/FetchStderr", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &executorFetchStderrClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Executor_FetchStderrClient interface {
+	Recv() (*Stderr, error)
+	grpc.ClientStream
+}
+
+type executorFetchStderrClient struct {
+	grpc.ClientStream
+}
+
+func (x *executorFetchStderrClient) Recv() (*Stderr, error) {
+	m := new(Stderr)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *executorClient) Start(ctx context.Context, in *StartInput, opts ...grpc.CallOption) (*StartResponse, error) {
+	out := new(StartResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Start", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) Wait(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*WaitResponse, error) {
+	out := new(WaitResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Wait", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) ExecCommand(ctx context.Context, in *Command, opts ...grpc.CallOption) (*Sn, error) {
+	out := new(Sn)
+	err := c.cc.Invoke(ctx, "/apis.Executor/ExecCommand", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) Kill(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*Error, error) {
+	out := new(Error)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Kill", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) LookPath(ctx context.Context, in *Command, opts ...grpc.CallOption) (*LookPathResponse, error) {
+	out := new(LookPathResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/LookPath", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) Exec(ctx context.Context, opts ...grpc.CallOption) (Executor_ExecClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[3], "/apis.Executor/Exec", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &executorSendInputClient{stream}
+	x := &executorExecClient{stream}
 	return x, nil
 }
 
-type Executor_SendInputClient interface {
-	Send(*Input) error
-	CloseAndRecv() (*Error, error)
+type Executor_ExecClient interface {
+	Send(*ExecRequest) error
+	Recv() (*ExecResponse, error)
 	grpc.ClientStream
 }
 
-type executorSendInputClient struct {
+type executorExecClient struct {
 	grpc.ClientStream
 }
 
-func (x *executorSendInputClient) Send(m *Input) error {
+func (x *executorExecClient) Send(m *ExecRequest) error {
 	return x.ClientStream.SendMsg(m)
 }
 
-func (x *executorSendInputClient) CloseAndRecv() (*Error, error) {
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	m := new(Error)
+func (x *executorExecClient) Recv() (*ExecResponse, error) {
+	m := new(ExecResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (c *executorClient) FetchStdout(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_FetchStdoutClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[1], "/apis.Executor/FetchStdout", opts...)
+func (c *executorClient) List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/List", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &executorFetchStdoutClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
-}
-
-type Executor_FetchStdoutClient interface {
-	Recv() (*Stdout, error)
-	grpc.ClientStream
+	return out, nil
 }
 
-type executorFetchStdoutClient struct {
-	grpc.ClientStream
+func (c *executorClient) Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (x *executorFetchStdoutClient) Recv() (*Stdout, error) {
-	m := new(Stdout)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
+func (c *executorClient) Signal(ctx context.Context, in *SignalRequest, opts ...grpc.CallOption) (*Error, error) {
+	out := new(Error)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Signal", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	return m, nil
+	return out, nil
 }
 
-func (c *executorClient) FetchStderr(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_FetchStderrClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[2], "/apis.Executor/FetchStderr", opts...)
+func (c *executorClient) Attach(ctx context.Context, in *Sn, opts ...grpc.CallOption) (Executor_AttachClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Executor_serviceDesc.Streams[4], "/apis.Executor/Attach", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &executorFetchStderrClient{stream}
+	x := &executorAttachClient{stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -694,53 +2994,62 @@ func (c *executorClient) FetchStderr(ctx context.Context, in *Sn, opts ...grpc.C
 	return x, nil
 }
 
-type Executor_FetchStderrClient interface {
-	Recv() (*Stderr, error)
+type Executor_AttachClient interface {
+	Recv() (*ExecResponse, error)
 	grpc.ClientStream
 }
 
-type executorFetchStderrClient struct {
+type executorAttachClient struct {
 	grpc.ClientStream
 }
 
-func (x *executorFetchStderrClient) Recv() (*Stderr, error) {
-	m := new(Stderr)
+func (x *executorAttachClient) Recv() (*ExecResponse, error) {
+	m := new(ExecResponse)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (c *executorClient) Start(ctx context.Context, in *StartInput, opts ...grpc.CallOption) (*StartResponse, error) {
-	out := new(StartResponse)
-	err := c.cc.Invoke(ctx, "/apis.Executor/Start", in, out, opts...)
+func (c *executorClient) Inspect(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*InspectResponse, error) {
+	out := new(InspectResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/Inspect", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *executorClient) Wait(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*WaitResponse, error) {
-	out := new(WaitResponse)
-	err := c.cc.Invoke(ctx, "/apis.Executor/Wait", in, out, opts...)
+func (c *executorClient) StartService(ctx context.Context, in *ServiceSpec, opts ...grpc.CallOption) (*ServiceStatus, error) {
+	out := new(ServiceStatus)
+	err := c.cc.Invoke(ctx, "/apis.Executor/StartService", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *executorClient) ExecCommand(ctx context.Context, in *Command, opts ...grpc.CallOption) (*Sn, error) {
-	out := new(Sn)
-	err := c.cc.Invoke(ctx, "/apis.Executor/ExecCommand", in, out, opts...)
+func (c *executorClient) StopService(ctx context.Context, in *ServiceName, opts ...grpc.CallOption) (*ServiceStatus, error) {
+	out := new(ServiceStatus)
+	err := c.cc.Invoke(ctx, "/apis.Executor/StopService", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *executorClient) Kill(ctx context.Context, in *Sn, opts ...grpc.CallOption) (*Error, error) {
-	out := new(Error)
-	err := c.cc.Invoke(ctx, "/apis.Executor/Kill", in, out, opts...)
+func (c *executorClient) GetService(ctx context.Context, in *ServiceName, opts ...grpc.CallOption) (*ServiceStatus, error) {
+	out := new(ServiceStatus)
+	err := c.cc.Invoke(ctx, "/apis.Executor/GetService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) ListServices(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServiceListResponse, error) {
+	out := new(ServiceListResponse)
+	err := c.cc.Invoke(ctx, "/apis.Executor/ListServices", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -756,6 +3065,27 @@ type ExecutorServer interface {
 	Wait(context.Context, *Sn) (*WaitResponse, error)
 	ExecCommand(context.Context, *Command) (*Sn, error)
 	Kill(context.Context, *Sn) (*Error, error)
+	// LookPath resolves command path with the env and dir it would run with
+	LookPath(context.Context, *Command) (*LookPathResponse, error)
+	// Exec runs a command on a single stream, replacing
+	// ExecCommand, Start, SendInput, FetchStdout, FetchStderr and Wait
+	Exec(Executor_ExecServer) error
+	// List returns commands known to the server
+	List(context.Context, *Empty) (*ListResponse, error)
+	Info(context.Context, *Empty) (*InfoResponse, error)
+	Signal(context.Context, *SignalRequest) (*Error, error)
+	// Attach streams output of a running command from now on,
+	// ended by its exit
+	Attach(*Sn, Executor_AttachServer) error
+	// Inspect reports credentials and restrictions of a running command
+	Inspect(context.Context, *Sn) (*InspectResponse, error)
+	// StartService starts a supervised command, a finished service
+	// of the same name is replaced
+	StartService(context.Context, *ServiceSpec) (*ServiceStatus, error)
+	// StopService stops the service and forgets it, logs are kept
+	StopService(context.Context, *ServiceName) (*ServiceStatus, error)
+	GetService(context.Context, *ServiceName) (*ServiceStatus, error)
+	ListServices(context.Context, *Empty) (*ServiceListResponse, error)
 }
 
 // UnimplementedExecutorServer can be embedded to have forward compatible implementations.
@@ -783,6 +3113,39 @@ func (*UnimplementedExecutorServer) ExecCommand(ctx context.Context, req *Comman
 func (*UnimplementedExecutorServer) Kill(ctx context.Context, req *Sn) (*Error, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Kill not implemented")
 }
+func (*UnimplementedExecutorServer) LookPath(ctx context.Context, req *Command) (*LookPathResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookPath not implemented")
+}
+func (*UnimplementedExecutorServer) Exec(srv Executor_ExecServer) error {
+	return status.Errorf(codes.Unimplemented, "method Exec not implemented")
+}
+func (*UnimplementedExecutorServer) List(ctx context.Context, req *Empty) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedExecutorServer) Info(ctx context.Context, req *Empty) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (*UnimplementedExecutorServer) Signal(ctx context.Context, req *SignalRequest) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Signal not implemented")
+}
+func (*UnimplementedExecutorServer) Attach(req *Sn, srv Executor_AttachServer) error {
+	return status.Errorf(codes.Unimplemented, "method Attach not implemented")
+}
+func (*UnimplementedExecutorServer) Inspect(ctx context.Context, req *Sn) (*InspectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Inspect not implemented")
+}
+func (*UnimplementedExecutorServer) StartService(ctx context.Context, req *ServiceSpec) (*ServiceStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartService not implemented")
+}
+func (*UnimplementedExecutorServer) StopService(ctx context.Context, req *ServiceName) (*ServiceStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopService not implemented")
+}
+func (*UnimplementedExecutorServer) GetService(ctx context.Context, req *ServiceName) (*ServiceStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetService not implemented")
+}
+func (*UnimplementedExecutorServer) ListServices(ctx context.Context, req *Empty) (*ServiceListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
 
 func RegisterExecutorServer(s *grpc.Server, srv ExecutorServer) {
 	s.RegisterService(&_Executor_serviceDesc, srv)
@@ -928,6 +3291,215 @@ func _Executor_Kill_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Executor_LookPath_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Command)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).LookPath(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/LookPath",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).LookPath(ctx, req.(*Command))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Exec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExecutorServer).Exec(&executorExecServer{stream})
+}
+
+type Executor_ExecServer interface {
+	Send(*ExecResponse) error
+	Recv() (*ExecRequest, error)
+	grpc.ServerStream
+}
+
+type executorExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *executorExecServer) Send(m *ExecResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *executorExecServer) Recv() (*ExecRequest, error) {
+	m := new(ExecRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Executor_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).List(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).Info(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Signal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).Signal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/Signal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).Signal(ctx, req.(*SignalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Attach_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Sn)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExecutorServer).Attach(m, &executorAttachServer{stream})
+}
+
+type Executor_AttachServer interface {
+	Send(*ExecResponse) error
+	grpc.ServerStream
+}
+
+type executorAttachServer struct {
+	grpc.ServerStream
+}
+
+func (x *executorAttachServer) Send(m *ExecResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Executor_Inspect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sn)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).Inspect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/Inspect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).Inspect(ctx, req.(*Sn))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_StartService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).StartService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/StartService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).StartService(ctx, req.(*ServiceSpec))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_StopService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).StopService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/StopService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).StopService(ctx, req.(*ServiceName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_GetService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).GetService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/GetService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).GetService(ctx, req.(*ServiceName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_ListServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).ListServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.Executor/ListServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).ListServices(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Executor_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "apis.Executor",
 	HandlerType: (*ExecutorServer)(nil),
@@ -948,6 +3520,42 @@ var _Executor_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Kill",
 			Handler:    _Executor_Kill_Handler,
 		},
+		{
+			MethodName: "LookPath",
+			Handler:    _Executor_LookPath_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Executor_List_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Executor_Info_Handler,
+		},
+		{
+			MethodName: "Signal",
+			Handler:    _Executor_Signal_Handler,
+		},
+		{
+			MethodName: "Inspect",
+			Handler:    _Executor_Inspect_Handler,
+		},
+		{
+			MethodName: "StartService",
+			Handler:    _Executor_StartService_Handler,
+		},
+		{
+			MethodName: "StopService",
+			Handler:    _Executor_StopService_Handler,
+		},
+		{
+			MethodName: "GetService",
+			Handler:    _Executor_GetService_Handler,
+		},
+		{
+			MethodName: "ListServices",
+			Handler:    _Executor_ListServices_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -965,6 +3573,17 @@ var _Executor_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Executor_FetchStderr_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Exec",
+			Handler:       _Executor_Exec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Attach",
+			Handler:       _Executor_Attach_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "executor.proto",
 }
@@ -0,0 +1,51 @@
+package apis
+
+// gRPC metadata keys carrying the caller's trace context to the executor
+const (
+	MetadataTraceparent = "traceparent"
+	MetadataRequestId   = "x-request-id"
+	// MetadataDedup carries an id the server executes only once, each
+	// command retried by a client without being idempotent has its own
+	// random id shared by its attempts. The server answers a retry of
+	// the same command with AlreadyExists, another command reusing the
+	// id with InvalidArgument
+	MetadataDedup = "x-executor-dedup"
+)
+
+// Environment variables the executor may inject into child processes
+const (
+	EnvTraceparent = "TRACEPARENT"
+	EnvRequestId   = "EXECUTOR_REQUEST_ID"
+)
+
+// Sizes of stdin and output messages
+const (
+	DefaultChunkSize = 32 << 10
+	// MaxChunkSize keeps messages below the 4MB grpc default limit of
+	// older clients and servers
+	MaxChunkSize = 1 << 20
+	// MaxMessageSize is the limit of messages set by server and client,
+	// a chunk with the rest of its message
+	MaxMessageSize = MaxChunkSize + 64<<10
+)
+
+// Flow control of Exec streams
+const (
+	// DefaultWindow is the window a client asks for each stream
+	DefaultWindow = 1 << 20
+	// DefaultMaxBuffered caps bytes of a command buffered by the server,
+	// split between its three streams
+	DefaultMaxBuffered = 3 * DefaultWindow
+)
+
+// Passing files on the unix socket
+const (
+	// FilesPreface starts a connection of the unix socket sending files
+	// with SCM_RIGHTS instead of speaking grpc, the files are attached
+	// to the preface, the server answers "ok <token>\n" or
+	// "error <message>\n" and closes the connection. It is longer than
+	// the http2 preface, so servers not taking files close at once
+	FilesPreface = "EXECUTOR-FILES/1 SCM_RIGHTS\n"
+	// MaxPassedFiles is the most files passed at once
+	MaxPassedFiles = 64
+)
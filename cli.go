@@ -0,0 +1,652 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+// exit codes of run, following timeout(1) and shells
+const (
+	exitTimeout     = 124
+	exitCannotStart = 127
+	exitSignalBase  = 128
+)
+
+const (
+	usageRun     = "run [-env K=V]... [-dir D] [-timeout T] [-seccomp P] [-user U] [-cap-keep CAPS] [-cap-drop CAPS] [-cap-ambient CAPS] [-nice N] [-ionice CLASS[:PRIO]] [-cpus LIST] [-oom-score-adj N] [-umask MASK] [-unshare net,mount,pid] [-read-only PATH]... [-private-tmp] [-safe] [-landlock-read PATH]... [-landlock-write PATH]... [-landlock-system] [-landlock-required] [-pass-stdio] [-extra-fd FD]... -- cmd args..."
+	usagePs      = "ps"
+	usageKill    = "kill [-s SIGNAL] SN..."
+	usageInfo    = "info"
+	usageAttach  = "attach SN"
+	usageInspect = "inspect SN"
+	usageService = "service start|stop|status|ls ..."
+	usageFanout  = "fanout -target T... [-targets-file F] [-concurrency N] [-timeout T] [-fail-fast] [-json] -- cmd args..."
+)
+
+var subcommands = map[string]func(e *client.Executor, args []string) int{
+	"run":     cliRun,
+	"ps":      cliPs,
+	"kill":    cliKill,
+	"info":    cliInfo,
+	"attach":  cliAttach,
+	"inspect": cliInspect,
+	"fanout":  cliFanout,
+	"service": cliService,
+}
+
+func cliUsage() {
+	fmt.Fprintf(os.Stderr, "usage: executor [-socket-path PATH] <subcommand>, without subcommand starts a shell\n")
+	for _, usage := range []string{usageRun, usagePs, usageKill, usageInfo, usageAttach, usageInspect, usageService, usageFanout} {
+		fmt.Fprintf(os.Stderr, "  %s\n", usage)
+	}
+}
+
+func runSubcommand(args []string) int {
+	sub, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown subcommand %q\n", args[0])
+		cliUsage()
+		return 2
+	}
+	e, err := client.New(socketPath, client.WithStallTimeout(stallTimeout))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer e.Close()
+	return sub(e, args[1:])
+}
+
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: executor %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// exitCode maps error of a remote command to the exit code of executor
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if e, ok := errors.Cause(err).(*client.ExitError); ok {
+		if e.ExitStatus.Signaled() {
+			return exitSignalBase + int(e.ExitStatus.Signal())
+		}
+		return e.ExitStatus.ExitStatus()
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+func cliRun(e *client.Executor, args []string) int {
+	var (
+		env     envFlag
+		dir     string
+		timeout time.Duration
+		seccomp string
+		user    string
+		caps    capsFlags
+		sched   schedFlags
+		iso     isolationFlags
+		ll      landlockFlags
+		files   filesFlags
+	)
+	fs := newFlagSet("run", usageRun)
+	fs.Var(&env, "env", "KEY=VALUE added to the server environment, can be repeated")
+	fs.StringVar(&dir, "dir", "", "working directory")
+	fs.DurationVar(&timeout, "timeout", 0, "kill command after timeout, exit code is 124")
+	fs.StringVar(&seccomp, "seccomp", "", "seccomp profile of the server, e.g. no-network or read-only-fs-syscalls")
+	fs.StringVar(&user, "user", "", "run as user, name or uid")
+	caps.register(fs)
+	sched.register(fs)
+	iso.register(fs)
+	ll.register(fs)
+	files.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	extraFiles, err := files.extraFiles()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	attrs, err := sched.sched()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	isolation, err := iso.isolation()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := e.CommandContext(ctx, fs.Arg(0), fs.Args()[1:]...)
+	if len(env) > 0 {
+		cmd.Env = env
+		cmd.EnvMode = apis.EnvMode_ENV_INHERIT_OVERRIDE
+	}
+	cmd.Dir = dir
+	cmd.SeccompProfile = seccomp
+	cmd.User = user
+	cmd.Capabilities = caps.capabilities()
+	cmd.Sched = attrs
+	cmd.Isolation = isolation
+	cmd.Landlock = ll.landlock()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.PassStdio = files.passStdio
+	cmd.ExtraFiles = extraFiles
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCannotStart
+	}
+	if st := cmd.LandlockStatus; st != nil && !st.Enforced {
+		fmt.Fprintf(os.Stderr, "executor: landlock best effort: %s\n", st.Reason)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	go func() {
+		for sig := range sigs {
+			cmd.Signal(sig.(syscall.Signal))
+		}
+	}()
+
+	err = cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		fmt.Fprintf(os.Stderr, "timeout after %s\n", timeout)
+		return exitTimeout
+	}
+	return exitCode(err)
+}
+
+func cliPs(e *client.Executor, args []string) int {
+	fs := newFlagSet("ps", usagePs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	procs, err := e.List(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SN\tPID\tSTARTED\tREQUEST_ID\tCOMMAND")
+	for _, p := range procs {
+		started := "-"
+		if p.StartedAt > 0 {
+			started = time.Unix(p.StartedAt, 0).Format("15:04:05")
+		}
+		pid := "-"
+		if p.Pid > 0 {
+			pid = strconv.Itoa(int(p.Pid))
+		}
+		requestId := p.RequestId
+		if requestId == "" {
+			requestId = "-"
+		}
+		command := string(p.Path)
+		for _, arg := range p.Args {
+			command += " " + string(arg)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", p.Sn, pid, started, requestId, command)
+	}
+	w.Flush()
+	return 0
+}
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+}
+
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return syscall.Signal(n), nil
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if sig, ok := signalNames[name]; ok {
+		return sig, nil
+	}
+	return 0, errors.Errorf("unknown signal %q", s)
+}
+
+func parseSn(s string) (uint32, error) {
+	sn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid sn %q", s)
+	}
+	return uint32(sn), nil
+}
+
+func cliKill(e *client.Executor, args []string) int {
+	fs := newFlagSet("kill", usageKill)
+	sigName := fs.String("s", "KILL", "signal name or number")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	sig, err := parseSignal(*sigName)
+	if err != nil || fs.NArg() == 0 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fs.Usage()
+		return 2
+	}
+	ret := 0
+	for _, arg := range fs.Args() {
+		sn, err := parseSn(arg)
+		if err == nil {
+			err = e.Signal(context.Background(), sn, sig)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ret = 1
+		}
+	}
+	return ret
+}
+
+func cliInfo(e *client.Executor, args []string) int {
+	fs := newFlagSet("info", usageInfo)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	info, err := e.Info(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("version:    %s\n", info.Version)
+	fmt.Printf("go version: %s\n", info.GoVersion)
+	fmt.Printf("pid:        %d\n", info.Pid)
+	fmt.Printf("started:    %s\n", time.Unix(info.StartedAt, 0).Format(time.RFC3339))
+	fmt.Printf("running:    %d\n", info.Running)
+	fmt.Printf("socket:     %s\n", socketPath)
+	return 0
+}
+
+func cliAttach(e *client.Executor, args []string) int {
+	fs := newFlagSet("attach", usageAttach)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	sn, err := parseSn(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// interrupt detaches, the command keeps running
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+	err = e.Attach(ctx, sn, os.Stdout, os.Stderr)
+	if ctx.Err() != nil {
+		return exitSignalBase + int(syscall.SIGINT)
+	}
+	return exitCode(err)
+}
+
+// capsFlags are comma separated capability names
+type capsFlags struct {
+	keep, drop, ambient string
+}
+
+func (f *capsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.keep, "cap-keep", "", "keep only these capabilities, e.g. CAP_NET_ADMIN,CAP_NET_RAW")
+	fs.StringVar(&f.drop, "cap-drop", "", "drop these capabilities")
+	fs.StringVar(&f.ambient, "cap-ambient", "", "raise these capabilities in the ambient set, for non-root users")
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func (f *capsFlags) capabilities() *apis.Capabilities {
+	caps := &apis.Capabilities{
+		Keep:    splitList(f.keep),
+		Drop:    splitList(f.drop),
+		Ambient: splitList(f.ambient),
+	}
+	if len(caps.Keep)+len(caps.Drop)+len(caps.Ambient) == 0 {
+		return nil
+	}
+	return caps
+}
+
+func cliInspect(e *client.Executor, args []string) int {
+	fs := newFlagSet("inspect", usageInspect)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	sn, err := parseSn(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	res, err := e.Inspect(context.Background(), sn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	list := func(caps []string) string {
+		if len(caps) == 0 {
+			return "-"
+		}
+		return strings.Join(caps, ",")
+	}
+	seccomp := []string{"disabled", "strict", "filter"}[res.SeccompMode%3]
+	if len(res.SeccompProfile) > 0 {
+		seccomp += " " + res.SeccompProfile
+	}
+	fmt.Printf("pid:             %d\n", res.Process.Pid)
+	fmt.Printf("uid:             %d\n", res.Uid)
+	fmt.Printf("gid:             %d\n", res.Gid)
+	fmt.Printf("cap effective:   %s\n", list(res.CapEffective))
+	fmt.Printf("cap permitted:   %s\n", list(res.CapPermitted))
+	fmt.Printf("cap inheritable: %s\n", list(res.CapInheritable))
+	fmt.Printf("cap bounding:    %s\n", list(res.CapBounding))
+	fmt.Printf("cap ambient:     %s\n", list(res.CapAmbient))
+	fmt.Printf("no new privs:    %t\n", res.NoNewPrivs)
+	fmt.Printf("seccomp:         %s\n", seccomp)
+	ioClass := "none"
+	for name, class := range ioClasses {
+		if class == res.IoClass {
+			ioClass = name
+		}
+	}
+	if res.IoClass == apis.IoClass_IO_CLASS_REALTIME || res.IoClass == apis.IoClass_IO_CLASS_BEST_EFFORT {
+		ioClass += fmt.Sprintf(":%d", res.IoPriority)
+	}
+	fmt.Printf("nice:            %d\n", res.Nice)
+	fmt.Printf("ionice:          %s\n", ioClass)
+	fmt.Printf("cpus:            %s\n", formatCpuList(res.Cpus))
+	fmt.Printf("oom_score_adj:   %d\n", res.OomScoreAdj)
+	fmt.Printf("umask:           %04o\n", res.Umask)
+	fmt.Printf("namespaces:      %s\n", list(res.Namespaces))
+	if st := res.Landlock; st != nil {
+		landlock := "enforced"
+		if !st.Enforced {
+			landlock = "best effort, " + st.Reason
+		}
+		fmt.Printf("landlock:        %s\n", landlock)
+	}
+	if f := res.Flow; f != nil {
+		fmt.Printf("window:          %d\n", f.Window)
+		fmt.Printf("buffered:        stdin %d, stdout %d, stderr %d\n", f.StdinBuffered, f.StdoutBuffered, f.StderrBuffered)
+		if f.StdoutStalledMs > 0 || f.StderrStalledMs > 0 {
+			fmt.Printf("stalled:         stdout %s, stderr %s\n",
+				time.Duration(f.StdoutStalledMs)*time.Millisecond, time.Duration(f.StderrStalledMs)*time.Millisecond)
+		}
+	}
+	return 0
+}
+
+// schedFlags are scheduling attributes, empty ones are inherited
+type schedFlags struct {
+	nice, ionice, cpus, oomScoreAdj, umask string
+}
+
+func (f *schedFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.nice, "nice", "", "nice value, -20 to 19")
+	fs.StringVar(&f.ionice, "ionice", "", "io scheduling class realtime, best-effort or idle, with priority 0-7 after colon")
+	fs.StringVar(&f.cpus, "cpus", "", "cpu affinity list, e.g. 0-3,6")
+	fs.StringVar(&f.oomScoreAdj, "oom-score-adj", "", "oom_score_adj, -1000 to 1000")
+	fs.StringVar(&f.umask, "umask", "", "octal umask, e.g. 027")
+}
+
+var ioClasses = map[string]apis.IoClass{
+	"none":        apis.IoClass_IO_CLASS_NONE,
+	"realtime":    apis.IoClass_IO_CLASS_REALTIME,
+	"best-effort": apis.IoClass_IO_CLASS_BEST_EFFORT,
+	"idle":        apis.IoClass_IO_CLASS_IDLE,
+}
+
+// maxCpus is the size of the affinity mask of the server
+const maxCpus = 1024
+
+func parseCpuList(s string) ([]uint32, error) {
+	var cpus []uint32
+	for _, part := range splitList(s) {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid cpu list %q", s)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.ParseUint(bounds[1], 10, 32); err != nil || last < first {
+				return nil, errors.Errorf("invalid cpu list %q", s)
+			}
+		}
+		if last >= maxCpus {
+			return nil, errors.Errorf("cpu %d out of range", last)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, uint32(cpu))
+		}
+	}
+	return cpus, nil
+}
+
+func (f *schedFlags) sched() (*apis.Sched, error) {
+	sched := &apis.Sched{}
+	set := false
+	if len(f.nice) > 0 {
+		nice, err := strconv.ParseInt(f.nice, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid nice %q", f.nice)
+		}
+		sched.HasNice, sched.Nice, set = true, int32(nice), true
+	}
+	if len(f.ionice) > 0 {
+		parts := strings.SplitN(f.ionice, ":", 2)
+		class, ok := ioClasses[parts[0]]
+		if !ok {
+			return nil, errors.Errorf("unknown io class %q", parts[0])
+		}
+		sched.IoClass, set = class, true
+		if len(parts) == 2 {
+			prio, err := strconv.ParseInt(parts[1], 10, 32)
+			if err != nil {
+				return nil, errors.Errorf("invalid io priority %q", parts[1])
+			}
+			sched.IoPriority = int32(prio)
+		} else if class != apis.IoClass_IO_CLASS_IDLE {
+			// default of ionice
+			sched.IoPriority = 4
+		}
+	}
+	if len(f.cpus) > 0 {
+		cpus, err := parseCpuList(f.cpus)
+		if err != nil {
+			return nil, err
+		}
+		sched.Cpus, set = cpus, true
+	}
+	if len(f.oomScoreAdj) > 0 {
+		adj, err := strconv.ParseInt(f.oomScoreAdj, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid oom_score_adj %q", f.oomScoreAdj)
+		}
+		sched.HasOomScoreAdj, sched.OomScoreAdj, set = true, int32(adj), true
+	}
+	if len(f.umask) > 0 {
+		umask, err := strconv.ParseUint(f.umask, 8, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid umask %q", f.umask)
+		}
+		sched.HasUmask, sched.Umask, set = true, uint32(umask), true
+	}
+	if !set {
+		return nil, nil
+	}
+	return sched, nil
+}
+
+func formatCpuList(cpus []uint32) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		} else {
+			parts = append(parts, fmt.Sprint(cpus[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// isolationFlags select new namespaces of the command
+type isolationFlags struct {
+	unshare    string
+	readOnly   envFlag
+	privateTmp bool
+	safe       bool
+}
+
+func (f *isolationFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.unshare, "unshare", "", "new namespaces, net with loopback only, mount and pid")
+	fs.Var(&f.readOnly, "read-only", "path made read-only in a new mount namespace, can be repeated")
+	fs.BoolVar(&f.privateTmp, "private-tmp", false, "empty tmpfs on /tmp in a new mount namespace")
+	fs.BoolVar(&f.safe, "safe", false, "same as -unshare net,mount,pid -read-only / -private-tmp")
+}
+
+func (f *isolationFlags) isolation() (*apis.Isolation, error) {
+	iso := &apis.Isolation{
+		ReadOnlyPaths: f.readOnly,
+		PrivateTmp:    f.privateTmp,
+	}
+	for _, ns := range splitList(f.unshare) {
+		switch ns {
+		case "net":
+			iso.Net = true
+		case "mount", "mnt":
+			iso.Mount = true
+		case "pid":
+			iso.Pid = true
+		default:
+			return nil, errors.Errorf("unknown namespace %q", ns)
+		}
+	}
+	if f.safe {
+		iso.Net, iso.Mount, iso.Pid, iso.PrivateTmp = true, true, true, true
+		iso.ReadOnlyPaths = append(iso.ReadOnlyPaths, "/")
+	}
+	if !iso.Net && !iso.Mount && !iso.Pid && !iso.PrivateTmp && len(iso.ReadOnlyPaths) == 0 {
+		return nil, nil
+	}
+	return iso, nil
+}
+
+// landlockFlags are paths the command may access
+type landlockFlags struct {
+	read, write      envFlag
+	system, required bool
+}
+
+func (f *landlockFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.read, "landlock-read", "path the command may read and execute below, can be repeated")
+	fs.Var(&f.write, "landlock-write", "path the command may write below, can be repeated")
+	fs.BoolVar(&f.system, "landlock-system", false, "allow reading system directories like /usr and writing /dev/null")
+	fs.BoolVar(&f.required, "landlock-required", false, "fail unless landlock is fully enforced")
+}
+
+func (f *landlockFlags) landlock() *apis.Landlock {
+	if len(f.read)+len(f.write) == 0 && !f.system {
+		return nil
+	}
+	return &apis.Landlock{
+		ReadPaths:   f.read,
+		WritePaths:  f.write,
+		SystemPaths: f.system,
+		Required:    f.required,
+	}
+}
+
+// filesFlags pass files of the client to the command
+type filesFlags struct {
+	passStdio bool
+	extraFds  envFlag
+}
+
+func (f *filesFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.passStdio, "pass-stdio", false, "pass stdin, stdout and stderr to the command instead of streaming them")
+	fs.Var(&f.extraFds, "extra-fd", "fd passed to the command as fd 3 and up in order, can be repeated")
+}
+
+func (f *filesFlags) extraFiles() ([]*os.File, error) {
+	var files []*os.File
+	for _, s := range f.extraFds {
+		fd, err := strconv.Atoi(s)
+		if err != nil || fd < 0 {
+			return nil, errors.Errorf("invalid fd %q", s)
+		}
+		file := os.NewFile(uintptr(fd), "fd "+s)
+		if _, err := file.Stat(); err != nil {
+			return nil, errors.Errorf("fd %d not open", fd)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
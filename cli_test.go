@@ -0,0 +1,164 @@
+package main
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want syscall.Signal
+		fail bool
+	}{
+		{in: "9", want: syscall.SIGKILL},
+		{in: "TERM", want: syscall.SIGTERM},
+		{in: "SIGTERM", want: syscall.SIGTERM},
+		{in: "sigusr2", want: syscall.SIGUSR2},
+		{in: "hup", want: syscall.SIGHUP},
+		{in: "SIGFOO", fail: true},
+		{in: "", fail: true},
+	} {
+		got, err := parseSignal(c.in)
+		if (err != nil) != c.fail || got != c.want {
+			t.Errorf("%q: got %d %v, want %d", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestParseSn(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want uint32
+		fail bool
+	}{
+		{in: "1", want: 1},
+		{in: "4294967295", want: 4294967295},
+		{in: "4294967296", fail: true},
+		{in: "-1", fail: true},
+		{in: "abc", fail: true},
+	} {
+		got, err := parseSn(c.in)
+		if (err != nil) != c.fail || got != c.want {
+			t.Errorf("%q: got %d %v, want %d", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{&client.ExitError{ExitStatus: syscall.WaitStatus(3 << 8)}, 3},
+		{errors.Wrap(&client.ExitError{ExitStatus: syscall.WaitStatus(1 << 8)}, "wrapped"), 1},
+		{&client.ExitError{ExitStatus: syscall.WaitStatus(syscall.SIGKILL)}, exitSignalBase + 9},
+		{errors.New("grpc broken"), 1},
+	} {
+		if got := exitCode(c.err); got != c.want {
+			t.Errorf("%v: got %d, want %d", c.err, got, c.want)
+		}
+	}
+}
+
+func TestCliRun(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	for _, c := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"/bin/true"}, 0},
+		{[]string{"/bin/sh", "-c", "exit 3"}, 3},
+		{[]string{"-env", "CODE=5", "/bin/sh", "-c", "exit $CODE"}, 5},
+		{[]string{"-dir", "/", "/bin/sh", "-c", `test "$(pwd)" = /`}, 0},
+		{[]string{"/bin/sh", "-c", "kill -KILL $$"}, exitSignalBase + 9},
+		{[]string{"-timeout", "100ms", "/bin/sleep", "60"}, exitTimeout},
+		{[]string{"/nonexistent/command"}, exitCannotStart},
+		{[]string{}, 2},
+		{[]string{"-no-such-flag", "/bin/true"}, 2},
+		{[]string{"-cpus", "3-1", "/bin/true"}, 2},
+	} {
+		if got := cliRun(h.Executor, c.args); got != c.want {
+			t.Errorf("run %q: got %d, want %d", c.args, got, c.want)
+		}
+	}
+}
+
+func TestParseCpuList(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want []uint32
+		fail bool
+	}{
+		{in: "0", want: []uint32{0}},
+		{in: "0-3,6", want: []uint32{0, 1, 2, 3, 6}},
+		{in: "2,4-5", want: []uint32{2, 4, 5}},
+		{in: "1023", want: []uint32{1023}},
+		{in: "3-1", fail: true},
+		{in: "a-b", fail: true},
+		{in: "1024", fail: true},
+		{in: "0-4294967295", fail: true},
+	} {
+		got, err := parseCpuList(c.in)
+		if (err != nil) != c.fail || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %v %v, want %v", c.in, got, err, c.want)
+		}
+		if !c.fail && formatCpuList(got) != c.in {
+			t.Errorf("format %v: got %q, want %q", got, formatCpuList(got), c.in)
+		}
+	}
+}
+
+func TestSchedFlags(t *testing.T) {
+	f := &schedFlags{}
+	if s, err := f.sched(); s != nil || err != nil {
+		t.Errorf("without flags: got %v %v", s, err)
+	}
+
+	f = &schedFlags{nice: "10", ionice: "best-effort", cpus: "0-1", oomScoreAdj: "-500", umask: "027"}
+	s, err := f.sched()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &apis.Sched{
+		HasNice: true, Nice: 10,
+		IoClass: apis.IoClass_IO_CLASS_BEST_EFFORT, IoPriority: 4,
+		Cpus:           []uint32{0, 1},
+		HasOomScoreAdj: true, OomScoreAdj: -500,
+		HasUmask: true, Umask: 027,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("got %v, want %v", s, want)
+	}
+
+	f = &schedFlags{ionice: "realtime:2"}
+	if s, err := f.sched(); err != nil || s.IoPriority != 2 || s.IoClass != apis.IoClass_IO_CLASS_REALTIME {
+		t.Errorf("ionice realtime:2: got %v %v", s, err)
+	}
+	f = &schedFlags{ionice: "idle"}
+	if s, err := f.sched(); err != nil || s.IoPriority != 0 {
+		t.Errorf("ionice idle: got %v %v", s, err)
+	}
+	for _, f := range []*schedFlags{
+		{nice: "x"},
+		{ionice: "fast"},
+		{ionice: "realtime:x"},
+		{cpus: "2-1"},
+		{oomScoreAdj: "x"},
+		{umask: "9"},
+	} {
+		if _, err := f.sched(); err == nil {
+			t.Errorf("%+v parsed", f)
+		}
+	}
+}
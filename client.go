@@ -1,13 +1,22 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
+	"syscall"
 
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"yunion.io/x/executor/apis"
 	"yunion.io/x/executor/client"
 	"yunion.io/x/pkg/utils"
 )
@@ -16,66 +25,543 @@ func Client() {
 	if len(socketPath) == 0 {
 		panic("socket path not provide")
 	}
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runSubcommand(args))
+	}
 	client.Init(socketPath)
 
 	start()
 }
 
 func start() {
-	fmt.Print("# ")
-	reader := bufio.NewReader(os.Stdin)
-	var cmdRunning bool
-	var Sinput io.Writer
+	newShell(client.Default()).run()
+}
+
+// inputRouter owns stdin, lines are read from it at the prompt and the
+// rest is forwarded to the foreground job
+type inputRouter struct {
+	// nil data is EOF, ch is closed when stdin is not a tty and ends
+	ch      chan []byte
+	pending []byte
+}
+
+func newInputRouter(f *os.File, tty bool) *inputRouter {
+	r := &inputRouter{ch: make(chan []byte)}
+	go func() {
+		var buf = make([]byte, 4096)
+		for {
+			n, err := f.Read(buf)
+			if n > 0 {
+				r.ch <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				if err == io.EOF && tty {
+					// ctrl-d on a tty, more input may follow
+					r.ch <- nil
+					continue
+				}
+				close(r.ch)
+				return
+			}
+		}
+	}()
+	return r
+}
+
+func (r *inputRouter) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		data := <-r.ch
+		if data == nil {
+			return 0, io.EOF
+		}
+		r.pending = data
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// readLine reads a line without consuming input after it
+func (r *inputRouter) readLine() (string, error) {
+	var line []byte
 	for {
-		content, _ := reader.ReadString('\n')
-		if cmdRunning {
-			io.WriteString(Sinput, content)
-			continue
+		if len(r.pending) == 0 {
+			data := <-r.ch
+			if data == nil {
+				if len(line) > 0 {
+					return string(line), nil
+				}
+				return "", io.EOF
+			}
+			r.pending = data
+		}
+		if i := bytes.IndexByte(r.pending, '\n'); i >= 0 {
+			line = append(line, r.pending[:i]...)
+			r.pending = r.pending[i+1:]
+			return string(line), nil
+		}
+		line = append(line, r.pending...)
+		r.pending = nil
+	}
+}
+
+// promptInput feeds the line editor, ctrl-c discards the current line
+type promptInput struct {
+	r *inputRouter
+}
+
+func (p promptInput) Read(buf []byte) (int, error) {
+	// room for the replacement of ctrl-c
+	n, err := p.r.Read(buf[:len(buf)/4])
+	if i := bytes.IndexByte(buf[:n], 3); i >= 0 {
+		// end and ctrl-u clear the line
+		p.r.pending = append(append([]byte(nil), buf[i+1:n]...), p.r.pending...)
+		n = i + copy(buf[i:], "\x1b[F\x15")
+	}
+	return n, err
+}
+
+type job struct {
+	id    int
+	line  string
+	cmd   *client.Cmd
+	stdin io.WriteCloser
+	done  chan struct{}
+	err   error
+
+	stopped     bool
+	stdinClosed bool
+}
+
+func (j *job) state() string {
+	select {
+	case <-j.done:
+		if ws, ok := exitStatus(j.err); ok {
+			if ws.Signaled() {
+				return fmt.Sprintf("Killed(%s)", ws.Signal())
+			}
+			return fmt.Sprintf("Exit %d", ws.ExitStatus())
+		} else if j.err != nil {
+			return "Error"
+		}
+		return "Done"
+	default:
+	}
+	if j.stopped {
+		return "Stopped"
+	}
+	return "Running"
+}
+
+func exitStatus(err error) (syscall.WaitStatus, bool) {
+	if e, ok := errors.Cause(err).(*client.ExitError); ok {
+		return e.ExitStatus, true
+	}
+	return 0, false
+}
+
+type shell struct {
+	e *client.Executor
+
+	dir     string
+	oldDir  string
+	env     []string
+	envMode apis.EnvMode
+
+	input *inputRouter
+	tty   bool
+	term  *terminal.Terminal
+	sigs  chan os.Signal
+
+	// prompting is true while the line editor owns a raw mode tty,
+	// output of background jobs goes through it then
+	outLock   sync.Mutex
+	prompting bool
+
+	jobsLock sync.Mutex
+	jobs     []*job
+}
+
+func newShell(e *client.Executor) *shell {
+	s := &shell{
+		e:    e,
+		tty:  terminal.IsTerminal(int(os.Stdin.Fd())),
+		sigs: make(chan os.Signal, 1),
+	}
+	s.input = newInputRouter(os.Stdin, s.tty)
+	if s.tty {
+		s.term = terminal.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{promptInput{s.input}, os.Stdout}, "# ")
+	}
+	// never stopped or killed by keys, they go to the remote job
+	signal.Notify(s.sigs, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTSTP)
+	s.initSession()
+	return s
+}
+
+// initSession takes working dir and environment of the server, so cd,
+// export and unset can edit them
+func (s *shell) initSession() {
+	if out, err := s.e.Command("pwd").Output(); err == nil {
+		s.dir = strings.TrimSpace(string(out))
+	}
+	out, err := s.e.Command("env", "-0").Output()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed fetch remote env, unset disabled: %s\n", err)
+		s.envMode = apis.EnvMode_ENV_INHERIT_OVERRIDE
+		return
+	}
+	for _, kv := range bytes.Split(out, []byte{0}) {
+		if len(kv) > 0 {
+			s.env = append(s.env, string(kv))
+		}
+	}
+	s.envMode = apis.EnvMode_ENV_CLEAN
+}
+
+func (s *shell) Write(p []byte) (int, error) {
+	s.outLock.Lock()
+	defer s.outLock.Unlock()
+	if s.prompting {
+		return s.term.Write(p)
+	}
+	return os.Stdout.Write(p)
+}
+
+func (s *shell) printf(format string, args ...interface{}) {
+	fmt.Fprintf(s, format, args...)
+}
+
+func (s *shell) readLine() (string, error) {
+	if !s.tty {
+		return s.input.readLine()
+	}
+	fd := int(os.Stdin.Fd())
+	if w, h, err := terminal.GetSize(fd); err == nil && w > 0 {
+		s.term.SetSize(w, h)
+	}
+	s.term.SetPrompt(s.dir + " # ")
+
+	s.outLock.Lock()
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		s.outLock.Unlock()
+		return "", errors.Wrap(err, "make raw terminal")
+	}
+	s.prompting = true
+	s.outLock.Unlock()
+
+	line, err := s.term.ReadLine()
+
+	s.outLock.Lock()
+	s.prompting = false
+	terminal.Restore(fd, state)
+	s.outLock.Unlock()
+	return line, err
+}
+
+func (s *shell) run() {
+	for {
+		s.reportJobs()
+		line, err := s.readLine()
+		if err == io.EOF {
+			if s.tty {
+				fmt.Println("exit")
+			}
+			return
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
 		}
-		input := strings.TrimSpace(strings.Trim(content, "\n"))
-		if utils.IsInStringArray(input, []string{"exit", "quit"}) {
+		line = strings.TrimSpace(line)
+		if utils.IsInStringArray(line, []string{"exit", "quit"}) {
 			return
-		} else if input == "" {
-			fmt.Println(time.Now().UTC().String())
-			fmt.Print("# ")
+		} else if line == "" {
 			continue
 		}
-		is := utils.ArgsStringToArray(input)
-		c := client.Command(is[0], is[1:]...)
-		si, err := c.StdinPipe()
-		if err != nil {
-			panic(err)
+		s.execLine(line)
+	}
+}
+
+func (s *shell) execLine(line string) {
+	background := strings.HasSuffix(line, "&")
+	if background {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "&"))
+	}
+	args := utils.ArgsStringToArray(line)
+	if len(args) == 0 {
+		return
+	}
+	switch args[0] {
+	case "cd":
+		s.cd(args[1:])
+	case "pwd":
+		s.printf("%s\n", s.dir)
+	case "export":
+		s.export(args[1:])
+	case "unset":
+		s.unset(args[1:])
+	case "jobs":
+		s.listJobs()
+	case "fg":
+		if j := s.findJob(args[1:]); j != nil {
+			s.resume(j)
+			s.foreground(j)
 		}
-		Sinput = si
-		stdout, err := c.StdoutPipe()
-		if err != nil {
-			panic(err)
+	case "bg":
+		if j := s.findJob(args[1:]); j != nil {
+			s.resume(j)
+			s.printf("[%d] %s &\n", j.id, j.line)
+		}
+	default:
+		s.startJob(args, line, background)
+	}
+}
+
+func (s *shell) command(path string, args ...string) *client.Cmd {
+	cmd := s.e.Command(path, args...)
+	cmd.Dir = s.dir
+	cmd.Env = s.env
+	cmd.EnvMode = s.envMode
+	return cmd
+}
+
+func (s *shell) getEnv(key string) string {
+	for _, kv := range s.env {
+		if strings.HasPrefix(kv, key+"=") {
+			return kv[len(key)+1:]
 		}
-		stderr, err := c.StderrPipe()
-		if err != nil {
-			panic(err)
+	}
+	return ""
+}
+
+func (s *shell) cd(args []string) {
+	target := s.getEnv("HOME")
+	if len(args) > 0 {
+		target = args[0]
+	}
+	if target == "-" {
+		target = s.oldDir
+	}
+	if target == "" {
+		target = "/"
+	}
+	// resolve and check on the server
+	out, err := s.command("sh", "-c", `cd "$1" && pwd`, "sh", target).Output()
+	if err != nil {
+		if e, ok := err.(*client.ExitError); ok && len(e.Stderr) > 0 {
+			s.printf("cd: %s", e.Stderr)
+		} else {
+			s.printf("cd: %s: %s\n", target, err)
 		}
+		return
+	}
+	s.oldDir, s.dir = s.dir, strings.TrimSpace(string(out))
+}
 
-		if err := c.Start(); err != nil {
-			fmt.Printf("cmd %s start failed: %s\n", input, err)
-			fmt.Print("# ")
+func (s *shell) export(args []string) {
+	if len(args) == 0 {
+		env := append([]string(nil), s.env...)
+		sort.Strings(env)
+		for _, kv := range env {
+			s.printf("export %s\n", kv)
+		}
+		return
+	}
+	for _, kv := range args {
+		if !strings.Contains(kv, "=") {
+			// exported already when set
 			continue
 		}
+		s.unset([]string{strings.SplitN(kv, "=", 2)[0]})
+		s.env = append(s.env, kv)
+	}
+}
+
+func (s *shell) unset(keys []string) {
+	for _, key := range keys {
+		env := s.env[:0]
+		for _, kv := range s.env {
+			if !strings.HasPrefix(kv, key+"=") {
+				env = append(env, kv)
+			}
+		}
+		s.env = env
+	}
+}
 
-		go func() {
-			io.Copy(os.Stdout, stdout)
-		}()
-		go func() {
-			io.Copy(os.Stderr, stderr)
-		}()
+func (s *shell) startJob(args []string, line string, background bool) {
+	cmd := s.command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		s.printf("%s\n", err)
+		return
+	}
+	// one writer keeps order of stdout and stderr
+	cmd.Stdout = s
+	cmd.Stderr = s
+	if err := cmd.Start(); err != nil {
+		s.printf("%s: %s\n", args[0], err)
+		return
+	}
 
-		cmdRunning = true
-		go func() {
-			if err := c.Wait(); err != nil {
-				fmt.Printf("cmd %s wait failed: %s\n", input, err)
+	s.jobsLock.Lock()
+	id := 1
+	for _, j := range s.jobs {
+		if j.id >= id {
+			id = j.id + 1
+		}
+	}
+	j := &job{
+		id:    id,
+		line:  line,
+		cmd:   cmd,
+		stdin: stdin,
+		done:  make(chan struct{}),
+	}
+	s.jobs = append(s.jobs, j)
+	s.jobsLock.Unlock()
+	go func() {
+		j.err = cmd.Wait()
+		close(j.done)
+	}()
+
+	if background {
+		s.printf("[%d] %s &\n", j.id, j.line)
+		return
+	}
+	s.foreground(j)
+}
+
+// foreground forwards input and keyboard signals to j until it exits
+// or is stopped by ctrl-z
+func (s *shell) foreground(j *job) {
+	select {
+	case <-s.sigs:
+		// stale signal from the prompt
+	default:
+	}
+	input := s.input.ch
+	if !s.tty {
+		// stdin is a script of commands, not input of them
+		input = nil
+		j.stdin.Close()
+		j.stdinClosed = true
+	} else if len(s.input.pending) > 0 {
+		// typed ahead at the prompt
+		s.writeStdin(j, s.input.pending)
+		s.input.pending = nil
+	}
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				input = nil
+			}
+			if data == nil {
+				if !j.stdinClosed {
+					j.stdin.Close()
+					j.stdinClosed = true
+				}
+				continue
 			}
-			cmdRunning = false
-			fmt.Print("# ")
-		}()
+			s.writeStdin(j, data)
+		case sig := <-s.sigs:
+			j.cmd.Signal(sig.(syscall.Signal))
+			if sig == syscall.SIGTSTP {
+				j.stopped = true
+				s.printf("\n[%d] Stopped %s\n", j.id, j.line)
+				return
+			}
+		case <-j.done:
+			s.removeJob(j)
+			if ws, ok := exitStatus(j.err); ok && ws.Signaled() {
+				// after the echoed ^C
+				s.printf("\n")
+			} else if !ok && j.err != nil {
+				s.printf("%s: %s\n", j.line, j.err)
+			}
+			return
+		}
+	}
+}
+
+func (s *shell) writeStdin(j *job, data []byte) {
+	if j.stdinClosed {
+		return
+	}
+	if _, err := j.stdin.Write(data); err != nil {
+		j.stdinClosed = true
+	}
+}
+
+func (s *shell) resume(j *job) {
+	if j.stopped {
+		j.cmd.Signal(syscall.SIGCONT)
+		j.stopped = false
+	}
+}
+
+func (s *shell) removeJob(j *job) {
+	s.jobsLock.Lock()
+	defer s.jobsLock.Unlock()
+	for i := range s.jobs {
+		if s.jobs[i] == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			break
+		}
+	}
+	j.stdin.Close()
+}
+
+// findJob finds job by "%n" or "n", the latest one without args
+func (s *shell) findJob(args []string) *job {
+	s.jobsLock.Lock()
+	defer s.jobsLock.Unlock()
+	if len(args) == 0 {
+		if len(s.jobs) == 0 {
+			s.printf("no current job\n")
+			return nil
+		}
+		return s.jobs[len(s.jobs)-1]
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "%"))
+	if err == nil {
+		for _, j := range s.jobs {
+			if j.id == id {
+				return j
+			}
+		}
+	}
+	s.printf("%s: no such job\n", args[0])
+	return nil
+}
+
+func (s *shell) listJobs() {
+	s.jobsLock.Lock()
+	jobs := append([]*job(nil), s.jobs...)
+	s.jobsLock.Unlock()
+	for _, j := range jobs {
+		s.printf("[%d] %-12s %s\n", j.id, j.state(), j.line)
+	}
+}
+
+// reportJobs tells finished background jobs before the prompt
+func (s *shell) reportJobs() {
+	s.jobsLock.Lock()
+	var finished []*job
+	for _, j := range s.jobs {
+		select {
+		case <-j.done:
+			finished = append(finished, j)
+		default:
+		}
+	}
+	s.jobsLock.Unlock()
+	for _, j := range finished {
+		s.printf("[%d] %-12s %s\n", j.id, j.state(), j.line)
+		s.removeJob(j)
 	}
 }
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"io"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+func (e *Executor) newClient(ctx context.Context) (apis.ExecutorClient, error) {
+	conn, err := e.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc dial error")
+	}
+	return apis.NewExecutorClient(conn), nil
+}
+
+// List returns commands known to the executor server
+func (e *Executor) List(ctx context.Context) ([]*apis.ProcessInfo, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := cli.List(ctx, &apis.Empty{})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc list")
+	}
+	return res.Processes, nil
+}
+
+func (e *Executor) Info(ctx context.Context) (*apis.InfoResponse, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := cli.Info(ctx, &apis.Empty{})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc info")
+	}
+	return res, nil
+}
+
+// Inspect returns credentials and restrictions of the running command sn
+func (e *Executor) Inspect(ctx context.Context, sn uint32) (*apis.InspectResponse, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := cli.Inspect(ctx, &apis.Sn{Sn: sn})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc inspect")
+	}
+	return res, nil
+}
+
+// Signal sends sig to the command sn running on executor server
+func (e *Executor) Signal(ctx context.Context, sn uint32, sig syscall.Signal) error {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := cli.Signal(ctx, &apis.SignalRequest{Sn: sn, Signal: int32(sig)})
+	if err != nil {
+		return errors.Wrap(err, "grpc signal")
+	}
+	if len(res.Error) > 0 {
+		return errors.Errorf("signal %d: %s", sn, res.Error)
+	}
+	return nil
+}
+
+// Attach copies output of the running command sn to stdout and stderr
+// until it exits, the error is like Wait's
+func (e *Executor) Attach(ctx context.Context, sn uint32, stdout, stderr io.Writer) error {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	stream, err := cli.Attach(ctx, &apis.Sn{Sn: sn})
+	if err != nil {
+		return errors.Wrap(err, "grpc attach")
+	}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("attach stream closed before exit")
+		} else if err != nil {
+			return errors.Wrap(err, "grpc attach recv")
+		}
+		switch r := res.Response.(type) {
+		case *apis.ExecResponse_Stdout:
+			if stdout != nil {
+				if err := writeTo(r.Stdout, stdout); err != nil {
+					return errors.Wrap(err, "write to stdout")
+				}
+			}
+		case *apis.ExecResponse_Stderr:
+			if stderr != nil {
+				if err := writeTo(r.Stderr, stderr); err != nil {
+					return errors.Wrap(err, "write to stderr")
+				}
+			}
+		case *apis.ExecResponse_Exit:
+			if len(r.Exit.ErrContent) > 0 {
+				return errors.New(string(r.Exit.ErrContent))
+			}
+			if r.Exit.ExitStatus != 0 {
+				return &ExitError{ExitStatus: newWaitStatus(r.Exit.ExitStatus)}
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package client_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+const benchOutputSize = 64 << 20
+
+type benchCmd interface {
+	StdoutPipe() (io.ReadCloser, error)
+	Start() error
+	Wait() error
+}
+
+// drainOutput runs cmd and reads its stdout till it exits
+func drainOutput(b *testing.B, cmd benchCmd) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		b.Fatal(err)
+	}
+	n, err := io.Copy(ioutil.Discard, stdout)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		b.Fatal(err)
+	}
+	if n != benchOutputSize {
+		b.Fatalf("read %d bytes of output", n)
+	}
+}
+
+func BenchmarkOutputThroughput(b *testing.B) {
+	headArgs := []string{"-c", strconv.Itoa(benchOutputSize), "/dev/zero"}
+	b.Run("local", func(b *testing.B) {
+		b.SetBytes(benchOutputSize)
+		for i := 0; i < b.N; i++ {
+			drainOutput(b, exec.Command("head", headArgs...))
+		}
+	})
+	for _, c := range []struct {
+		name string
+		opts []client.Option
+	}{
+		{"executor", nil},
+		{"window-64k", []client.Option{client.WithWindow(64 << 10)}},
+		{"chunk-256k", []client.Option{client.WithChunkSize(256 << 10)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			h := executortest.NewRealHarness(c.opts...)
+			defer h.Close()
+			b.SetBytes(benchOutputSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				drainOutput(b, h.Executor.Command("head", headArgs...))
+			}
+		})
+	}
+}
+
+// BenchmarkRoundTrip writes lines to a remote cat one at a time and
+// times until each comes back
+func BenchmarkRoundTrip(b *testing.B) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+	cmd := h.Executor.Command("cat")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		b.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer stdin.Close()
+
+	line := append(bytes.Repeat([]byte("x"), 63), '\n')
+	r := bufio.NewReader(stdout)
+	lat := make([]time.Duration, 0, b.N)
+	b.SetBytes(int64(len(line)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		if _, err := stdin.Write(line); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.ReadBytes('\n'); err != nil {
+			b.Fatal(err)
+		}
+		lat = append(lat, time.Since(start))
+	}
+	b.StopTimer()
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	b.ReportMetric(float64(lat[(len(lat)-1)*99/100].Nanoseconds()), "p99-ns")
+}
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"yunion.io/x/executor/apis"
+)
+
+func (e *Executor) getChunkSize() int {
+	if e.chunkSize > 0 && e.chunkSize <= apis.MaxChunkSize {
+		return e.chunkSize
+	}
+	return apis.DefaultChunkSize
+}
@@ -12,8 +12,12 @@ import (
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 
 	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/internal/chunk"
 )
 
 const (
@@ -35,42 +39,72 @@ func GetTimeoutSeconds() int {
 }
 
 type Executor struct {
-	socketPath string
+	addr    string
+	network string
+
+	timeout      time.Duration
+	dialer       func(ctx context.Context, addr string) (net.Conn, error)
+	creds        credentials.TransportCredentials
+	dialOptions  []grpc.DialOption
+	hooks        []Hooks
+	retryPolicy  *RetryPolicy
+	chunkSize    int
+	window       int
+	stallTimeout time.Duration
+
+	connLock sync.Mutex
+	conn     *grpc.ClientConn
+	// running is the number of commands using conn
+	running int
+	// closeIdle closes conn once no command is running
+	closeIdle bool
+}
+
+// Init initializes the package level executor used by Command and
+// CommandContext, it talks to the executor server on unix socket
+func Init(socketPath string) {
+	if exec != nil {
+		// commands already started on the previous executor go on
+		exec.closeWhenIdle()
+	}
+	exec = &Executor{addr: socketPath, network: "unix"}
 }
 
-func Init(socketPath string) {
-	exec = &Executor{socketPath}
+func Default() *Executor {
+	return exec
 }
 
 func Command(path string, args ...string) *Cmd {
 	if exec == nil {
 		panic("executor not init ???")
 	}
-	return &Cmd{
-		Executor: exec,
-		Path:     path,
-		Args:     args,
-		wg:       new(sync.WaitGroup),
-		stdoutCh: make(chan struct{}),
-		stderrCh: make(chan struct{}),
-	}
+	return exec.Command(path, args...)
 }
 
 func CommandContext(ctx context.Context, path string, args ...string) *Cmd {
 	if exec == nil {
 		panic("executor not init ???")
 	}
+	return exec.CommandContext(ctx, path, args...)
+}
+
+func (e *Executor) Command(path string, args ...string) *Cmd {
 	return &Cmd{
-		Executor: exec,
+		Executor: e,
 		Path:     path,
 		Args:     args,
 		wg:       new(sync.WaitGroup),
 		stdoutCh: make(chan struct{}),
 		stderrCh: make(chan struct{}),
-		ctx:      ctx,
 	}
 }
 
+func (e *Executor) CommandContext(ctx context.Context, path string, args ...string) *Cmd {
+	c := e.Command(path, args...)
+	c.ctx = ctx
+	return c
+}
+
 type Cmd struct {
 	*Executor
 
@@ -81,14 +115,54 @@ type Cmd struct {
 	Env  []string
 	Dir  string
 
+	// EnvMode controls how Env is combined with the server environment
+	EnvMode apis.EnvMode
+	// SeccompProfile names a seccomp profile of the server, the command
+	// fails to start if the server doesn't know it
+	SeccompProfile string
+	// User runs the command as another user, name or uid
+	User string
+	// Capabilities limit capabilities of the command
+	Capabilities *apis.Capabilities
+	// Sched sets nice, io priority, cpu affinity, oom_score_adj and umask
+	Sched *apis.Sched
+	// Isolation runs the command in new namespaces
+	Isolation *apis.Isolation
+	// Landlock limits file system access of the command
+	Landlock *apis.Landlock
+	// LandlockStatus is set by Start with Landlock, it tells whether
+	// the restriction is enforced or only best effort
+	LandlockStatus *apis.LandlockStatus
+
+	// Retry opts in retrying Start on failures before the process has
+	// started, with the executor's RetryPolicy
+	Retry bool
+	// Idempotent commands are retried freely when it's unknown whether
+	// the process has started, others are retried under one dedup id
+	// which the server executes at most once
+	Idempotent bool
+
+	dedupId string
+
 	conn   *grpc.ClientConn
 	client apis.ExecutorClient
+	// holdsConn is set while the command counts as running on the
+	// executor, from Connect to closing descriptors
+	holdsConn bool
 
 	sn     *apis.Sn
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
 
+	// ExtraFiles become fd 3 and up of the command like os/exec, they
+	// are passed on the unix socket of the server
+	ExtraFiles []*os.File
+	// PassStdio passes stdin, stdout and stderr to the command as files
+	// on the unix socket instead of streaming them, local pipes are
+	// made for those which aren't files
+	PassStdio bool
+
 	closeAfterWait  []io.Closer
 	closeAfterAfter []io.Closer
 	goroutine       []func() error
@@ -102,32 +176,68 @@ type Cmd struct {
 	streamStdin  error
 	streamStdout error
 	streamStderr error
+	streamExec   error
+
+	stream     apis.Executor_ExecClient
+	streamLock sync.Mutex
+	// sendLock serializes sends, a send blocked by flow control must
+	// not hold streamLock which receiving needs
+	sendLock     sync.Mutex
+	streamDone   chan struct{}
+	cancelStream context.CancelFunc
+	exitRes      *apis.WaitResponse
+	// streamResumed is closed when the stream is replaced after an
+	// upgrade of the server
+	streamResumed chan struct{}
+	// handoff is set when the server told the stream an upgrade is
+	// coming, only then a broken stream is resumed
+	handoff      bool
+	stdinClosed  bool
+	stdoutOffset uint64
+	stderrOffset uint64
+	stdoutAcked  uint64
+	stderrAcked  uint64
+
+	// window is granted by the server, 0 without flow control
+	window   uint32
+	flowLock sync.Mutex
+	flowCond *sync.Cond
+	flowDone bool
+	// stdinUnacked is stdin sent and not acknowledged, sent again
+	// on resume
+	stdinUnacked []byte
+	stdinSent    uint64
+	stdinAcked   uint64
 
 	wg             *sync.WaitGroup
 	combinedOutput chan struct{}
 }
 
-func grcpDialWithUnixSocket(ctx context.Context, socketPath string) (*grpc.ClientConn, error) {
-	return grpc.DialContext(
-		ctx, socketPath,
-		grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(time.Second*time.Duration(timeoutSeconds)),
-		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
-			return net.DialTimeout("unix", addr, timeout)
-		}),
-	)
-}
-
 func (c *Cmd) Connect(ctx context.Context, opts ...grpc.CallOption,
 ) error {
+	if !c.holdsConn {
+		c.Executor.acquireConn()
+		c.holdsConn = true
+	}
 	var err error
-	c.conn, err = grcpDialWithUnixSocket(ctx, c.socketPath)
+	c.conn, err = c.Executor.Conn(ctx)
 	if err != nil {
+		c.releaseConn()
 		return errors.Wrap(err, "grpc dial error")
 	}
 	c.client = apis.NewExecutorClient(c.conn)
 	return nil
 }
 
+func (c *Cmd) connect(retry *retrier) error {
+	for {
+		err := c.Connect(context.Background())
+		if err == nil || !retry.next(notStarted(err)) {
+			return err
+		}
+	}
+}
+
 func (c *Cmd) Run() error {
 	if err := c.Start(); err != nil {
 		return err
@@ -171,26 +281,40 @@ func (c *Cmd) Output() ([]byte, error) {
 	return stdout.Bytes(), nil
 }
 
+func (c *Cmd) command() *apis.Command {
+	return &apis.Command{
+		Path:           []byte(c.Path),
+		Args:           strArrayToBytesArray(c.Args),
+		Env:            strArrayToBytesArray(c.Env),
+		Dir:            []byte(c.Dir),
+		EnvMode:        c.EnvMode,
+		SeccompProfile: c.SeccompProfile,
+		User:           c.User,
+		Capabilities:   c.Capabilities,
+		Sched:          c.Sched,
+		Isolation:      c.Isolation,
+		Landlock:       c.Landlock,
+	}
+}
+
 func (c *Cmd) Start() error {
+	if err := c.beforeStart(); err != nil {
+		return err
+	}
+	err := c.start()
+	c.afterStart(err)
+	return err
+}
+
+func (c *Cmd) start() error {
 	if c.conn != nil {
 		return errors.New("cmd executing")
 	}
-	if err := c.Connect(context.Background()); err != nil {
+	retry := c.newRetrier()
+	if err := c.connect(retry); err != nil {
 		return err
 	}
 
-	sn, err := c.client.ExecCommand(context.Background(), &apis.Command{
-		Path: []byte(c.Path),
-		Args: strArrayToBytesArray(c.Args),
-		Env:  strArrayToBytesArray(c.Env),
-		Dir:  []byte(c.Dir),
-	})
-	if err != nil {
-		c.closeDescriptors()
-		return errors.Wrap(err, "grcp exec command")
-	}
-	c.sn = sn
-
 	if c.ctx != nil {
 		select {
 		case <-c.ctx.Done():
@@ -216,6 +340,60 @@ func (c *Cmd) Start() error {
 		procIO[i] = fd
 	}
 
+	for {
+		err := c.startStream(procIO)
+		if status.Code(errors.Cause(err)) == codes.Unimplemented {
+			// server predates Exec stream
+			err = c.startLegacy(procIO)
+		}
+		if sn, ok := startedAs(err); ok && retry != nil {
+			// an earlier attempt started it, its response was lost
+			err = c.attachStream(sn, procIO)
+		}
+		if err == nil {
+			break
+		}
+		if !retry.next(err) {
+			c.closeDescriptors()
+			return err
+		}
+		if err := c.connect(retry); err != nil {
+			c.closeDescriptors()
+			return err
+		}
+	}
+
+	c.errch = make(chan error, len(c.goroutine))
+	for _, fn := range c.goroutine {
+		go func(fn func() error) {
+			c.errch <- fn()
+		}(fn)
+	}
+
+	if c.ctx != nil {
+		c.waitDone = make(chan struct{})
+		go func() {
+			select {
+			case <-c.ctx.Done():
+				c.Kill()
+			case <-c.waitDone:
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (c *Cmd) startLegacy(procIO [3]*os.File) error {
+	if c.passesFiles() {
+		return errors.New("server can't take files")
+	}
+	sn, err := c.client.ExecCommand(c.traceContext(), c.command())
+	if err != nil {
+		return errors.Wrap(err, "grcp exec command")
+	}
+	c.sn = sn
+
 	input := &apis.StartInput{
 		Sn:        c.sn.Sn,
 		HasStdin:  procIO[0] != nil,
@@ -225,14 +403,13 @@ func (c *Cmd) Start() error {
 
 	res, err := c.client.Start(context.Background(), input)
 	if err != nil {
-		c.closeDescriptors()
 		return errors.Wrap(err, "grpc start cmd")
 	}
 
 	if !res.Success {
-		c.closeDescriptors()
 		return errors.New(string(res.Error))
 	}
+	c.LandlockStatus = res.Landlock
 
 	if procIO[0] != nil {
 		go c.sendStdin(procIO[0])
@@ -261,25 +438,6 @@ func (c *Cmd) Start() error {
 			}
 		}(procIO[1])
 	}
-
-	c.errch = make(chan error, len(c.goroutine))
-	for _, fn := range c.goroutine {
-		go func(fn func() error) {
-			c.errch <- fn()
-		}(fn)
-	}
-
-	if c.ctx != nil {
-		c.waitDone = make(chan struct{})
-		go func() {
-			select {
-			case <-c.ctx.Done():
-				c.Kill()
-			case <-c.waitDone:
-			}
-		}()
-	}
-
 	return nil
 }
 
@@ -293,10 +451,19 @@ func (c *Cmd) streamError() error {
 	if c.streamStderr != nil {
 		return c.streamStderr
 	}
+	if c.streamExec != nil {
+		return c.streamExec
+	}
 	return nil
 }
 
 func (c *Cmd) Kill() error {
+	if c.sn == nil {
+		return errors.New("cmd not executing")
+	}
+	if c.getStream() != nil {
+		return c.Signal(syscall.SIGKILL)
+	}
 	e, err := c.client.Kill(context.Background(), c.sn)
 	if err != nil {
 		return errors.Wrap(err, "grpc send kill")
@@ -308,9 +475,18 @@ func (c *Cmd) Kill() error {
 }
 
 func (c *Cmd) Wait() error {
+	err := c.wait()
+	c.afterWait(err)
+	return err
+}
+
+func (c *Cmd) wait() error {
 	if c.conn == nil {
 		return errors.New("cmd not executing")
 	}
+	if c.getStream() != nil {
+		return c.waitStream()
+	}
 
 	res, err := c.client.Wait(context.Background(), c.sn)
 	if err != nil {
@@ -328,8 +504,12 @@ func (c *Cmd) Wait() error {
 	}
 
 	c.wg.Wait()
+	return c.finish(res)
+}
 
+func (c *Cmd) finish(res *apis.WaitResponse) error {
 	if len(res.ErrContent) > 0 {
+		c.closeDescriptors()
 		return errors.New(string(res.ErrContent))
 	}
 
@@ -353,14 +533,22 @@ func (c *Cmd) Wait() error {
 }
 
 func (c *Cmd) closeDescriptors() {
+	if c.cancelStream != nil {
+		c.cancelStream()
+	}
 	for _, fd := range c.closeAfterWait {
 		fd.Close()
 	}
 	for _, fd := range c.closeAfterAfter {
 		fd.Close()
 	}
-	if c.conn != nil {
-		c.conn.Close()
+	c.releaseConn()
+}
+
+func (c *Cmd) releaseConn() {
+	if c.holdsConn {
+		c.holdsConn = false
+		c.Executor.releaseConn()
 	}
 }
 
@@ -426,6 +614,12 @@ func interfaceEqual(a, b interface{}) bool {
 	return a == b
 }
 
+// copyCloser is a writer wrapping the output of the command, closed when
+// all output was copied to it
+type copyCloser interface {
+	closeAfterCopy()
+}
+
 func (c *Cmd) writerDescriptor(w io.Writer) (*os.File, error) {
 	if w == nil {
 		return nil, nil
@@ -444,6 +638,9 @@ func (c *Cmd) writerDescriptor(w io.Writer) (*os.File, error) {
 	c.goroutine = append(c.goroutine, func() error {
 		_, err := io.Copy(w, pr)
 		pr.Close() // in case io.Copy stopped due to write error
+		if cw, ok := w.(copyCloser); ok {
+			cw.closeAfterCopy()
+		}
 		return err
 	})
 	return pw, nil
@@ -492,7 +689,8 @@ func (c *Cmd) sendStdin(r io.Reader) {
 		return
 	}
 
-	var data = make([]byte, 4096)
+	data := chunk.Get(c.getChunkSize())
+	defer chunk.Put(data)
 	for {
 		n, err := r.Read(data)
 		if err == io.EOF {
@@ -0,0 +1,42 @@
+package client_test
+
+import (
+	"testing"
+
+	"yunion.io/x/executor/executortest"
+)
+
+func TestCloseWhileRunning(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	cmd := h.Executor.Command("/bin/cat")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Executor.Close(); err == nil {
+		t.Errorf("closed with a command running")
+	}
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("command broken by refused close: %v", err)
+	}
+	if err := h.Executor.Close(); err != nil {
+		t.Errorf("close after wait: %v", err)
+	}
+
+	// failed starts don't count as running
+	if err := h.Executor.Command("/nonexistent").Start(); err == nil {
+		t.Fatal("started a missing command")
+	}
+	if _, err := h.Executor.Command("/bin/true").LookPath(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Executor.Close(); err != nil {
+		t.Errorf("close after failed start: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+
+	"yunion.io/x/executor/apis"
+)
+
+const (
+	// keep below the default grpc server enforcement of 5 minutes,
+	// older executor servers close connections pinging more often
+	defaultKeepaliveTime    = 5 * time.Minute
+	defaultKeepaliveTimeout = 20 * time.Second
+	// reconnect quickly after executor restarts, it is a local socket
+	defaultBackoffMaxDelay = 2 * time.Second
+)
+
+func (e *Executor) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if e.dialer != nil {
+		return e.dialer(ctx, addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, e.network, addr)
+}
+
+func (e *Executor) grpcDialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(e.dial),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    defaultKeepaliveTime,
+			Timeout: defaultKeepaliveTimeout,
+		}),
+		grpc.WithBackoffMaxDelay(defaultBackoffMaxDelay),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(apis.MaxMessageSize),
+			grpc.MaxCallSendMsgSize(apis.MaxMessageSize),
+		),
+		grpc.WithInitialWindowSize(e.http2Window()),
+		grpc.WithInitialConnWindowSize(4 * e.http2Window()),
+	}
+	if e.creds != nil && e.network != "unix" {
+		opts = append(opts, grpc.WithTransportCredentials(e.creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	return append(opts, e.dialOptions...)
+}
+
+// getConn returns the connection shared by all commands of executor,
+// it is dialled on first use and reconnects by itself when broken
+func (e *Executor) getConn() (*grpc.ClientConn, error) {
+	e.connLock.Lock()
+	defer e.connLock.Unlock()
+	if e.conn != nil && e.conn.GetState() != connectivity.Shutdown {
+		return e.conn, nil
+	}
+	conn, err := grpc.DialContext(context.Background(), e.addr, e.grpcDialOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	e.conn = conn
+	return conn, nil
+}
+
+// Conn waits until the shared connection is ready, for at most
+// the connect timeout, like a blocking dial does
+func (e *Executor) Conn(ctx context.Context) (*grpc.ClientConn, error) {
+	conn, err := e.getConn()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.connectTimeout())
+	defer cancel()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return conn, nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return nil, errors.Wrapf(ctx.Err(), "connection %s", state)
+		}
+	}
+}
+
+func (e *Executor) acquireConn() {
+	e.connLock.Lock()
+	e.running++
+	e.connLock.Unlock()
+}
+
+func (e *Executor) releaseConn() {
+	e.connLock.Lock()
+	defer e.connLock.Unlock()
+	e.running--
+	if e.running == 0 && e.closeIdle {
+		e.closeConn()
+	}
+}
+
+// closeWhenIdle closes the shared connection now or once the running
+// commands finished
+func (e *Executor) closeWhenIdle() {
+	e.connLock.Lock()
+	defer e.connLock.Unlock()
+	if e.running > 0 {
+		e.closeIdle = true
+		return
+	}
+	e.closeConn()
+}
+
+func (e *Executor) closeConn() error {
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+// Close closes the shared connection, it fails while commands are
+// running on it
+func (e *Executor) Close() error {
+	e.connLock.Lock()
+	defer e.connLock.Unlock()
+	if e.running > 0 {
+		return errors.Errorf("%d commands running", e.running)
+	}
+	return e.closeConn()
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestInitClosesPreviousExecutor(t *testing.T) {
+	defer func(e *Executor) { exec = e }(exec)
+
+	Init("/nonexistent/idle.sock")
+	idle := Default()
+	idleConn, err := idle.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Init("/nonexistent/busy.sock")
+	if idleConn.GetState() != connectivity.Shutdown {
+		t.Errorf("connection of idle executor not closed by Init")
+	}
+
+	busy := Default()
+	busyConn, err := busy.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy.acquireConn()
+	Init("/nonexistent/next.sock")
+	if busyConn.GetState() == connectivity.Shutdown {
+		t.Errorf("connection closed under a running command")
+	}
+	busy.releaseConn()
+	if busyConn.GetState() != connectivity.Shutdown {
+		t.Errorf("connection not closed after the last command")
+	}
+	Default().Close()
+}
@@ -0,0 +1,161 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestExecStartFailure(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	cmd := h.Executor.Command("/nonexistent/command")
+	if err := cmd.Start(); err == nil {
+		cmd.Wait()
+		t.Fatal("started a missing command")
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("wait succeeded on a command not started")
+	}
+
+	cmd = h.Executor.Command("/bin/true")
+	cmd.Dir = "/nonexistent"
+	if err := cmd.Run(); err == nil {
+		t.Errorf("started in a missing dir")
+	}
+}
+
+func TestExecExitStatus(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	if err := h.Executor.Command("/bin/sh", "-c", "exit 0").Run(); err != nil {
+		t.Errorf("exit 0: %v", err)
+	}
+	err := h.Executor.Command("/bin/sh", "-c", "echo oops >&2; exit 3").Run()
+	ee, ok := err.(*client.ExitError)
+	if !ok {
+		t.Fatalf("exit 3: got %T %v, want *ExitError", err, err)
+	}
+	if ee.ExitStatus.ExitStatus() != 3 || ee.Error() != "exit status 3" {
+		t.Errorf("exit 3: got %d %q", ee.ExitStatus.ExitStatus(), ee.Error())
+	}
+
+	_, err = h.Executor.Command("/bin/sh", "-c", "echo out; echo err >&2; exit 4").Output()
+	ee, ok = err.(*client.ExitError)
+	if !ok || string(ee.Stderr) != "err\n" {
+		t.Errorf("Output: got %v", err)
+	}
+
+	out, err := h.Executor.Command("/bin/sh", "-c", "echo out; echo err >&2").CombinedOutput()
+	if err != nil || (string(out) != "out\nerr\n" && string(out) != "err\nout\n") {
+		t.Errorf("CombinedOutput: got %q %v", out, err)
+	}
+}
+
+func TestExecStdinEOF(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	cmd := h.Executor.Command("/bin/cat")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("cat of %d bytes returned %d bytes", len(data), len(out))
+	}
+
+	// closing stdin pipe ends cat
+	cmd = h.Executor.Command("/bin/cat")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(stdin, "hello\n")
+	stdin.Close()
+	got, err := ioutil.ReadAll(stdout)
+	if err != nil || string(got) != "hello\n" {
+		t.Errorf("cat of pipe: got %q %v", got, err)
+	}
+	waited := make(chan error, 1)
+	go func() { waited <- cmd.Wait() }()
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("cat exited with %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Kill()
+		t.Fatal("cat still running after stdin closed")
+	}
+}
+
+func TestExecSignal(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	cmd := h.Executor.Command("/bin/sh", "-c", `trap 'echo got term; exit 7' TERM; echo ready; while :; do sleep 0.05; done`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len("ready\n"))
+	if _, err := io.ReadFull(stdout, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := ioutil.ReadAll(stdout)
+	err = cmd.Wait()
+	if !strings.Contains(string(rest), "got term") {
+		t.Errorf("trap not run: %q", rest)
+	}
+	if ee, ok := err.(*client.ExitError); !ok || ee.ExitStatus.ExitStatus() != 7 {
+		t.Errorf("got %v, want exit status 7", err)
+	}
+
+	cmd = h.Executor.Command("/bin/sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Signal(syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Wait()
+	if ee, ok := err.(*client.ExitError); !ok || !ee.ExitStatus.Signaled() || ee.ExitStatus.Signal() != syscall.SIGKILL {
+		t.Errorf("got %v, want killed", err)
+	}
+
+	cmd = h.Executor.Command("/bin/sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("killed command exited cleanly")
+	}
+}
@@ -0,0 +1,7 @@
+package client
+
+// SetDedupId makes c an attempt of the command with the dedup id, as
+// retries of it are
+func SetDedupId(c *Cmd, id string) {
+	c.dedupId = id
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+const defaultFanoutConcurrency = 16
+
+// Fanout runs the same command on executor servers of Targets concurrently
+type Fanout struct {
+	Targets []string
+	// Concurrency bounds hosts running at the same time, default 16
+	Concurrency int
+	// Timeout bounds the command on each host, zero is no limit
+	Timeout time.Duration
+	// FailFast stops other hosts on the first failed one, pending hosts
+	// are skipped and running ones are killed
+	FailFast bool
+	// Options are applied to the executor of every target, e.g.
+	// WithTransportCredentials for tcp targets
+	Options []Option
+
+	Env     []string
+	EnvMode apis.EnvMode
+	Dir     string
+	// Stdin is sent to the command on every host
+	Stdin []byte
+
+	// Output receives output of hosts as it arrives, stream is
+	// StreamStdout or StreamStderr, called from many goroutines
+	Output func(target, stream string, data []byte)
+}
+
+// HostResult is the outcome of a command on one target
+type HostResult struct {
+	Target     string `json:"target"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ExitCode   int    `json:"exit_code"`
+	Signal     int    `json:"signal,omitempty"`
+	Error      string `json:"error,omitempty"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Failed reports whether the command didn't run or exited non-zero
+func (r *HostResult) Failed() bool {
+	return r.Skipped || r.TimedOut || r.Error != "" || r.ExitCode != 0 || r.Signal != 0
+}
+
+// Run runs path with args on all targets, results are in the order
+// of Targets
+func (f *Fanout) Run(ctx context.Context, path string, args ...string) []*HostResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultFanoutConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	results := make([]*HostResult, len(f.Targets))
+	var wg sync.WaitGroup
+	for i, target := range f.Targets {
+		results[i] = &HostResult{Target: target}
+		// hosts start in the order of targets
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Skipped = true
+			results[i].Error = "skipped"
+			continue
+		}
+		wg.Add(1)
+		go func(res *HostResult) {
+			defer wg.Done()
+			f.runHost(ctx, res, path, args)
+			// cancel before releasing the slot for the next host
+			if f.FailFast && res.Failed() {
+				cancel()
+			}
+			<-sem
+		}(results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func (f *Fanout) runHost(ctx context.Context, res *HostResult, path string, args []string) {
+	startAt := time.Now()
+	defer func() {
+		res.DurationMs = int64(time.Since(startAt) / time.Millisecond)
+	}()
+
+	e, err := New(res.Target, f.Options...)
+	if err != nil {
+		res.Error = err.Error()
+		return
+	}
+	defer e.Close()
+
+	parent := ctx
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := e.CommandContext(ctx, path, args...)
+	cmd.Env = f.Env
+	cmd.EnvMode = f.EnvMode
+	cmd.Dir = f.Dir
+	if f.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(f.Stdin)
+	}
+	cmd.Stdout = &fanoutWriter{f: f, target: res.Target, stream: StreamStdout, buf: &stdout}
+	cmd.Stderr = &fanoutWriter{f: f, target: res.Target, stream: StreamStderr, buf: &stderr}
+	err = cmd.Run()
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+
+	if err == nil {
+		return
+	}
+	ee, exited := errors.Cause(err).(*ExitError)
+	if exited && !ee.ExitStatus.Signaled() {
+		// exited on its own, even if just as the context ended
+		res.ExitCode = ee.ExitStatus.ExitStatus()
+		return
+	}
+	// killed or broken off as the context ended
+	switch ctx.Err() {
+	case context.Canceled:
+		res.Error = "canceled"
+		return
+	case context.DeadlineExceeded:
+		res.TimedOut = true
+		if parent.Err() == context.DeadlineExceeded {
+			res.Error = "deadline exceeded"
+		} else {
+			res.Error = "timeout after " + f.Timeout.String()
+		}
+		return
+	}
+	if exited {
+		res.Signal = int(ee.ExitStatus.Signal())
+		return
+	}
+	res.Error = err.Error()
+}
+
+type fanoutWriter struct {
+	f      *Fanout
+	target string
+	stream string
+	buf    *bytes.Buffer
+}
+
+func (w *fanoutWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.f.Output != nil {
+		w.f.Output(w.target, w.stream, p)
+	}
+	return len(p), nil
+}
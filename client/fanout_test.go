@@ -0,0 +1,178 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+// fanoutHosts serves a fake server per target, targets are dialled to
+// their own server
+func fanoutHosts(fakes []*executortest.FakeServer) ([]string, client.Option, func()) {
+	targets := make([]string, len(fakes))
+	hosts := make(map[string]*executortest.Harness)
+	for i, fake := range fakes {
+		targets[i] = fmt.Sprintf("/host%d.sock", i)
+		hosts[targets[i]] = executortest.NewHarness(fake)
+	}
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		h, ok := hosts[addr]
+		if !ok {
+			return nil, fmt.Errorf("no host %s", addr)
+		}
+		return h.Listener.Dial()
+	}
+	closeAll := func() {
+		for _, h := range hosts {
+			h.Close()
+		}
+	}
+	return targets, client.WithDialer(dialer), closeAll
+}
+
+func TestFanoutOrderAndConcurrency(t *testing.T) {
+	var running, peak int32
+	fakes := make([]*executortest.FakeServer, 6)
+	for i := range fakes {
+		fakes[i] = executortest.NewFakeServer()
+		// later hosts finish first
+		delay := time.Duration(len(fakes)-i) * 10 * time.Millisecond
+		out := fmt.Sprintf("host%d\n", i)
+		fakes[i].On("hostname").Run(func(inv *executortest.Invocation) int {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(delay)
+			atomic.AddInt32(&running, -1)
+			fmt.Fprint(inv.Stdout, out)
+			return 0
+		})
+	}
+	targets, dial, closeAll := fanoutHosts(fakes)
+	defer closeAll()
+
+	f := &client.Fanout{
+		Targets:     targets,
+		Concurrency: 2,
+		Options:     []client.Option{dial},
+	}
+	results := f.Run(context.Background(), "hostname")
+	if len(results) != len(targets) {
+		t.Fatalf("got %d results, want %d", len(results), len(targets))
+	}
+	for i, res := range results {
+		want := fmt.Sprintf("host%d\n", i)
+		if res.Target != targets[i] || res.Stdout != want || res.Failed() {
+			t.Errorf("result %d: got %+v, want target %s stdout %q", i, res, targets[i], want)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("%d hosts ran at once, concurrency is 2", peak)
+	}
+}
+
+func TestFanoutFailFast(t *testing.T) {
+	fakes := make([]*executortest.FakeServer, 4)
+	for i := range fakes {
+		fakes[i] = executortest.NewFakeServer()
+	}
+	fakes[0].On("check").Stderr("broken\n").Exit(3)
+	fakes[1].On("check").Delay(10 * time.Second)
+	fakes[2].On("check")
+	fakes[3].On("check")
+	targets, dial, closeAll := fanoutHosts(fakes)
+	defer closeAll()
+
+	f := &client.Fanout{
+		Targets:     targets,
+		Concurrency: 2,
+		FailFast:    true,
+		Options:     []client.Option{dial},
+	}
+	startAt := time.Now()
+	results := f.Run(context.Background(), "check")
+	if d := time.Since(startAt); d > 5*time.Second {
+		t.Errorf("running host not killed, fanout took %s", d)
+	}
+	if r := results[0]; r.ExitCode != 3 || r.Stderr != "broken\n" || r.Error != "" {
+		t.Errorf("failed host: got %+v", r)
+	}
+	if r := results[1]; r.Error != "canceled" || r.TimedOut {
+		t.Errorf("running host: got %+v, want canceled", r)
+	}
+	for _, r := range results[2:] {
+		if !r.Skipped || r.Error != "skipped" {
+			t.Errorf("pending host %s: got %+v, want skipped", r.Target, r)
+		}
+	}
+	for i, fake := range fakes[2:] {
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("skipped host %d ran %d commands", i+2, len(calls))
+		}
+	}
+}
+
+func TestFanoutTimeouts(t *testing.T) {
+	fakes := []*executortest.FakeServer{executortest.NewFakeServer(), executortest.NewFakeServer()}
+	fakes[0].On("sleep").Delay(10 * time.Second)
+	fakes[1].On("sleep").Stdout("quick\n")
+	targets, dial, closeAll := fanoutHosts(fakes)
+	defer closeAll()
+
+	f := &client.Fanout{
+		Targets: targets,
+		Timeout: 50 * time.Millisecond,
+		Options: []client.Option{dial},
+	}
+	results := f.Run(context.Background(), "sleep")
+	if r := results[0]; !r.TimedOut || r.Error != "timeout after 50ms" {
+		t.Errorf("slow host: got %+v", r)
+	}
+	if r := results[1]; r.Failed() || r.Stdout != "quick\n" {
+		t.Errorf("quick host: got %+v", r)
+	}
+
+	// deadline of the caller without per host timeout
+	f.Timeout = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results = f.Run(ctx, "sleep")
+	if r := results[0]; !r.TimedOut || r.Error != "deadline exceeded" {
+		t.Errorf("slow host under deadline: got %+v", r)
+	}
+	if r := results[1]; r.Failed() {
+		t.Errorf("quick host under deadline: got %+v", r)
+	}
+}
+
+func TestFanoutExitAsTimeoutEnds(t *testing.T) {
+	fake := executortest.NewFakeServer()
+	// exits with its own status when the kill comes, as a process
+	// finishing just before it does
+	fake.On("sleep").Run(func(inv *executortest.Invocation) int {
+		<-inv.Signals
+		return 3
+	})
+	targets, dial, closeAll := fanoutHosts([]*executortest.FakeServer{fake})
+	defer closeAll()
+
+	f := &client.Fanout{
+		Targets: targets,
+		Timeout: 50 * time.Millisecond,
+		Options: []client.Option{dial},
+	}
+	r := f.Run(context.Background(), "sleep")[0]
+	if r.TimedOut || r.Error != "" || r.ExitCode != 3 {
+		t.Errorf("exited as timeout ended: got %+v", r)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+func (c *Cmd) passesFiles() bool {
+	return c.PassStdio || len(c.ExtraFiles) > 0
+}
+
+// passFiles sends stdio of procIO with PassStdio and ExtraFiles
+func (c *Cmd) passFiles(procIO [3]*os.File) (*apis.PassedFiles, error) {
+	files := &apis.PassedFiles{Extra: uint32(len(c.ExtraFiles))}
+	var send []*os.File
+	if c.PassStdio {
+		for i, passed := range []*bool{&files.Stdin, &files.Stdout, &files.Stderr} {
+			if procIO[i] != nil {
+				*passed = true
+				send = append(send, procIO[i])
+			}
+		}
+	}
+	for i, f := range c.ExtraFiles {
+		if f == nil {
+			return nil, errors.Errorf("ExtraFiles[%d] is nil", i)
+		}
+	}
+	send = append(send, c.ExtraFiles...)
+	token, err := c.sendFiles(c.Context(), send)
+	if err != nil {
+		return nil, err
+	}
+	files.Token = token
+	return files, nil
+}
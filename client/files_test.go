@@ -0,0 +1,82 @@
+// +build !windows
+
+package client_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/server"
+)
+
+// serveUnix serves the real executor on a unix socket taking files,
+// bufconn of the harness can't pass them
+func serveUnix(t *testing.T) (*client.Executor, func()) {
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "exec.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	apis.RegisterExecutorServer(srv, &server.Executor{})
+	go srv.Serve(server.NewFilesListener(lis))
+	e, err := client.New(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, func() {
+		e.Close()
+		srv.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPassExtraFiles(t *testing.T) {
+	e, cleanup := serveUnix(t)
+	defer cleanup()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	cmd := e.Command("/bin/sh", "-c", "echo extra >&3; echo stdio")
+	cmd.ExtraFiles = []*os.File{w}
+	cmd.PassStdio = true
+	out, err := cmd.Output()
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "stdio\n" {
+		t.Errorf("stdout: got %q", out)
+	}
+	extra, err := ioutil.ReadAll(r)
+	if err != nil || string(extra) != "extra\n" {
+		t.Errorf("fd 3: got %q %v", extra, err)
+	}
+
+	// more than the server takes fails before sending
+	files := make([]*os.File, apis.MaxPassedFiles+1)
+	for i := range files {
+		files[i] = r
+	}
+	cmd = e.Command("/bin/true")
+	cmd.ExtraFiles = files
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("run with %d files: got %v", len(files), err)
+	}
+}
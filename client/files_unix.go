@@ -0,0 +1,58 @@
+// +build !windows
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// sendFiles passes files to the server on a connection of the unix
+// socket of their own, it returns the token naming them in ExecStart
+func (e *Executor) sendFiles(ctx context.Context, files []*os.File) (string, error) {
+	if len(files) > apis.MaxPassedFiles {
+		return "", errors.Errorf("at most %d files can be passed", apis.MaxPassedFiles)
+	}
+	if e.dialer == nil && e.network != "unix" {
+		return "", errors.New("files can only be passed on unix socket")
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.connectTimeout())
+	defer cancel()
+	conn, err := e.dial(ctx, e.addr)
+	if err != nil {
+		return "", notStarted(errors.Wrap(err, "dial to pass files"))
+	}
+	defer conn.Close()
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return "", errors.New("files can only be passed on unix socket")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		uc.SetDeadline(deadline)
+	}
+	fds := make([]int, len(files))
+	for i, f := range files {
+		fds[i] = int(f.Fd())
+	}
+	if _, _, err := uc.WriteMsgUnix([]byte(apis.FilesPreface), syscall.UnixRights(fds...), nil); err != nil {
+		return "", errors.Wrap(err, "pass files")
+	}
+	reply, err := bufio.NewReader(uc).ReadString('\n')
+	if err != nil {
+		// servers not taking files close the connection
+		return "", errors.Wrap(err, "server doesn't take files")
+	}
+	reply = strings.TrimSuffix(reply, "\n")
+	if strings.HasPrefix(reply, "ok ") {
+		return strings.TrimPrefix(reply, "ok "), nil
+	}
+	return "", errors.Errorf("pass files: %s", strings.TrimPrefix(reply, "error "))
+}
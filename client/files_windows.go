@@ -0,0 +1,13 @@
+package client
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// sendFiles fails, there is no unix socket to pass files on
+func (e *Executor) sendFiles(ctx context.Context, files []*os.File) (string, error) {
+	return "", errors.New("files can only be passed on unix socket")
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+func (e *Executor) getWindow() uint32 {
+	if e.window > 0 {
+		return uint32(e.window)
+	}
+	return apis.DefaultWindow
+}
+
+// http2Window keeps grpc flow control of a stream above the window, so
+// the server sees output the client doesn't consume as unacknowledged
+// instead of blocking on send
+func (e *Executor) http2Window() int32 {
+	w := int32(e.getWindow())
+	return w + w/4 + 64<<10
+}
+
+// stdinStalled tells stdin failed because the process read nothing
+type stdinStalled struct {
+	error
+}
+
+func (c *Cmd) startFlow(window uint32) {
+	c.window = window
+	c.flowCond = sync.NewCond(&c.flowLock)
+}
+
+// stopFlow wakes up stdin waiting for acks once the stream is done
+func (c *Cmd) stopFlow() {
+	c.flowLock.Lock()
+	c.flowDone = true
+	c.flowCond.Broadcast()
+	c.flowLock.Unlock()
+}
+
+// reserveStdin waits for room in the stdin window, it returns how many
+// of n bytes may be sent
+func (c *Cmd) reserveStdin(n int) (int, error) {
+	c.flowLock.Lock()
+	defer c.flowLock.Unlock()
+	var timedOut bool
+	var timer *time.Timer
+	for !c.flowDone && c.stdinSent-c.stdinAcked >= uint64(c.window) {
+		if timedOut {
+			return 0, stdinStalled{errors.Errorf("stdin stalled, the process read none of %d bytes in %s",
+				c.stdinSent-c.stdinAcked, c.stallTimeout)}
+		}
+		if c.stallTimeout > 0 && timer == nil {
+			timer = time.AfterFunc(c.stallTimeout, func() {
+				c.flowLock.Lock()
+				timedOut = true
+				c.flowCond.Broadcast()
+				c.flowLock.Unlock()
+			})
+			defer timer.Stop()
+		}
+		c.flowCond.Wait()
+	}
+	if c.flowDone {
+		return 0, errors.New("exec stream done")
+	}
+	if room := uint64(c.window) - (c.stdinSent - c.stdinAcked); uint64(n) > room {
+		n = int(room)
+	}
+	return n, nil
+}
+
+// ackStdin drops stdin the server wrote up to offset
+func (c *Cmd) ackStdin(offset uint64) {
+	c.flowLock.Lock()
+	if offset > c.stdinAcked && offset <= c.stdinSent {
+		c.stdinUnacked = c.stdinUnacked[offset-c.stdinAcked:]
+		c.stdinAcked = offset
+		c.flowCond.Broadcast()
+	}
+	c.flowLock.Unlock()
+}
+
+// sendStdinData sends stdin within the window, data is kept until
+// acknowledged, a stream broken by an upgrade sends it again on resume
+func (c *Cmd) sendStdinData(data []byte) error {
+	if c.window == 0 {
+		return c.sendRequest(&apis.ExecRequest{
+			Request: &apis.ExecRequest_Stdin{Stdin: data},
+		})
+	}
+	for len(data) > 0 {
+		n, err := c.reserveStdin(len(data))
+		if err != nil {
+			return err
+		}
+		c.sendLock.Lock()
+		c.streamLock.Lock()
+		stream, resumed := c.stream, c.streamResumed
+		c.streamLock.Unlock()
+		c.flowLock.Lock()
+		c.stdinUnacked = append(c.stdinUnacked, data[:n]...)
+		c.stdinSent += uint64(n)
+		c.flowLock.Unlock()
+		err = stream.Send(&apis.ExecRequest{
+			Request: &apis.ExecRequest_Stdin{Stdin: data[:n]},
+		})
+		c.sendLock.Unlock()
+		if err != nil {
+			select {
+			case <-resumed:
+			case <-c.streamDone:
+				return err
+			}
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// resendStdin sends again stdin the server didn't write before the
+// upgrade, from offset it reports, sendLock is held by caller
+func (c *Cmd) resendStdin(stream apis.Executor_ExecClient, offset uint64) error {
+	c.flowLock.Lock()
+	if offset > c.stdinAcked && offset <= c.stdinSent {
+		c.stdinUnacked = c.stdinUnacked[offset-c.stdinAcked:]
+		c.stdinAcked = offset
+	}
+	data := append([]byte(nil), c.stdinUnacked...)
+	c.flowLock.Unlock()
+	size := c.getChunkSize()
+	for len(data) > 0 {
+		n := len(data)
+		if n > size {
+			n = size
+		}
+		err := stream.Send(&apis.ExecRequest{
+			Request: &apis.ExecRequest_Stdin{Stdin: data[:n]},
+		})
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// ackOutput tells the server output consumed once a quarter of the
+// window is, the ack is lost on a broken stream and resuming sends
+// the offsets instead
+func (c *Cmd) ackOutput() {
+	if c.window == 0 || (c.stdoutOffset-c.stdoutAcked < uint64(c.window/4) &&
+		c.stderrOffset-c.stderrAcked < uint64(c.window/4)) {
+		return
+	}
+	c.sendLock.Lock()
+	c.getStream().Send(&apis.ExecRequest{
+		Request: &apis.ExecRequest_Ack{Ack: &apis.OutputAck{
+			Stdout: c.stdoutOffset,
+			Stderr: c.stderrOffset,
+		}},
+	})
+	c.sendLock.Unlock()
+	c.stdoutAcked, c.stderrAcked = c.stdoutOffset, c.stderrOffset
+}
@@ -0,0 +1,28 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestSmallWindow(t *testing.T) {
+	// far more stdin and output than the window, each needs acks
+	h := executortest.NewRealHarness(client.WithWindow(4<<10), client.WithChunkSize(1<<10))
+	defer h.Close()
+
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	cmd := h.Executor.Command("cat")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Errorf("output of %d bytes differs from %d bytes of stdin", len(out), len(data))
+	}
+}
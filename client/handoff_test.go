@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/executortest"
+)
+
+// upgradingServer breaks the stream of the first command as an upgrade
+// does, it is told of the handoff only with notice
+type upgradingServer struct {
+	apis.UnimplementedExecutorServer
+	notice  bool
+	resumed int32
+}
+
+func (u *upgradingServer) Exec(s apis.Executor_ExecServer) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	start := req.GetStart()
+	if start.ResumeSn != 0 {
+		atomic.AddInt32(&u.resumed, 1)
+		for _, res := range []*apis.ExecResponse{
+			{Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{Success: true, Sn: start.ResumeSn}}},
+			{Response: &apis.ExecResponse_Stdout{Stdout: []byte("resumed")}},
+			{Response: &apis.ExecResponse_Exit{Exit: &apis.WaitResponse{}}},
+		} {
+			if err := s.Send(res); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = s.Send(&apis.ExecResponse{
+		Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{Success: true, Sn: 1}},
+	})
+	if err == nil && u.notice {
+		err = s.Send(&apis.ExecResponse{
+			Response: &apis.ExecResponse_Handoff{Handoff: true},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return status.Error(codes.Unavailable, "transport is closing")
+}
+
+func TestResumeOnHandoff(t *testing.T) {
+	srv := &upgradingServer{notice: true}
+	h := executortest.NewHarness(srv)
+	defer h.Close()
+
+	out, err := h.Executor.Command("/bin/true").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "resumed" {
+		t.Errorf("output: got %q", out)
+	}
+	if n := atomic.LoadInt32(&srv.resumed); n != 1 {
+		t.Errorf("resumed %d times", n)
+	}
+}
+
+func TestBrokenStreamFailsFast(t *testing.T) {
+	srv := &upgradingServer{}
+	h := executortest.NewHarness(srv)
+	defer h.Close()
+
+	// no handoff told, the stream broke for good
+	begin := time.Now()
+	err := h.Executor.Command("/bin/true").Run()
+	if status.Code(errors.Cause(err)) != codes.Unavailable {
+		t.Errorf("run: got %v", err)
+	}
+	if d := time.Since(begin); d > time.Second {
+		t.Errorf("failed after %s", d)
+	}
+	if n := atomic.LoadInt32(&srv.resumed); n != 0 {
+		t.Errorf("resumed %d times without handoff", n)
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	osexec "os/exec"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// LookPath resolves name to an absolute path on the executor host,
+// a missing executable is reported as *exec.Error wrapping exec.ErrNotFound
+func LookPath(name string) (string, error) {
+	return Command(name).LookPath()
+}
+
+// LookPath resolves c.Path with the Env, EnvMode and Dir the command
+// would run with on the executor host
+func (c *Cmd) LookPath() (string, error) {
+	if c.conn != nil {
+		return "", errors.New("cmd executing")
+	}
+	if err := c.Connect(context.Background()); err != nil {
+		return "", err
+	}
+	defer c.closeDescriptors()
+
+	res, err := c.client.LookPath(c.traceContext(), &apis.Command{
+		Path:    []byte(c.Path),
+		Env:     strArrayToBytesArray(c.Env),
+		Dir:     []byte(c.Dir),
+		EnvMode: c.EnvMode,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "grpc look path")
+	}
+	if res.NotFound {
+		return "", &osexec.Error{Name: c.Path, Err: osexec.ErrNotFound}
+	}
+	if len(res.Error) > 0 {
+		return "", &osexec.Error{Name: c.Path, Err: errors.New(string(res.Error))}
+	}
+	return string(res.Path), nil
+}
@@ -0,0 +1,36 @@
+package client_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestLookPath(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	want, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh in PATH")
+	}
+	if got, err := h.Executor.Command("sh").LookPath(); err != nil || got != want {
+		t.Errorf("sh: got %q %v, want %q", got, err, want)
+	}
+
+	cmd := h.Executor.Command("sh")
+	cmd.EnvMode = apis.EnvMode_ENV_CLEAN
+	cmd.Env = []string{"PATH=/nonexistent"}
+	_, err = cmd.LookPath()
+	if ee, ok := err.(*exec.Error); !ok || ee.Err != exec.ErrNotFound {
+		t.Errorf("sh with empty PATH: got %v, want not found", err)
+	}
+
+	cmd = h.Executor.Command("./sh")
+	cmd.Dir = "/bin"
+	if got, err := cmd.LookPath(); err != nil || got != "/bin/sh" {
+		t.Errorf("./sh in /bin: got %q %v", got, err)
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Hooks are called around execution of every command of an executor,
+// nil members are skipped
+type Hooks struct {
+	// BeforeStart may modify the command, an error aborts Start
+	BeforeStart func(c *Cmd) error
+	AfterStart  func(c *Cmd, err error)
+	AfterWait   func(c *Cmd, err error)
+}
+
+type Option func(e *Executor)
+
+// WithTimeout sets how long commands wait for the connection to the
+// executor server becoming ready, default is GetTimeoutSeconds()
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Executor) {
+		e.timeout = timeout
+	}
+}
+
+// WithTransportCredentials secures tcp connections to executor server
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(e *Executor) {
+		e.creds = creds
+	}
+}
+
+// WithDialOptions appends grpc dial options, they take precedence
+// over the options set by executor
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(e *Executor) {
+		e.dialOptions = append(e.dialOptions, opts...)
+	}
+}
+
+// WithDialer replaces dialing unix socket or tcp address of target,
+// e.g. for in-process connections in tests
+func WithDialer(dialer func(ctx context.Context, addr string) (net.Conn, error)) Option {
+	return func(e *Executor) {
+		e.dialer = dialer
+	}
+}
+
+// WithChunkSize sets max bytes of a stdin message, default is
+// apis.DefaultChunkSize, output chunk size is set by the server
+func WithChunkSize(size int) Option {
+	return func(e *Executor) {
+		e.chunkSize = size
+	}
+}
+
+// WithWindow sets bytes of each of stdin, stdout and stderr in flight
+// unacknowledged on Exec streams, default is apis.DefaultWindow, the
+// server may grant less
+func WithWindow(size int) Option {
+	return func(e *Executor) {
+		e.window = size
+	}
+}
+
+// WithStallTimeout fails stdin of commands whose process read nothing
+// for so long while the window is full, the command is killed, default
+// 0 waits forever
+func WithStallTimeout(timeout time.Duration) Option {
+	return func(e *Executor) {
+		e.stallTimeout = timeout
+	}
+}
+
+func WithHooks(hooks Hooks) Option {
+	return func(e *Executor) {
+		e.hooks = append(e.hooks, hooks)
+	}
+}
+
+// ParseTarget splits target into network and address, accepted forms are
+// unix:///path/to/sock, unix:/path/to/sock, /path/to/sock,
+// tcp://host:port and host:port
+func ParseTarget(target string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(target, "unix://"):
+		return "unix", strings.TrimPrefix(target, "unix://"), nil
+	case strings.HasPrefix(target, "unix:"):
+		return "unix", strings.TrimPrefix(target, "unix:"), nil
+	case strings.HasPrefix(target, "tcp://"):
+		return "tcp", strings.TrimPrefix(target, "tcp://"), nil
+	case strings.Contains(target, "://"):
+		return "", "", errors.Errorf("unsupported target %q", target)
+	case strings.Contains(target, "/"):
+		return "unix", target, nil
+	case len(target) > 0:
+		return "tcp", target, nil
+	default:
+		return "", "", errors.New("empty target")
+	}
+}
+
+// New returns an executor talking to the executor server at target,
+// the connection is dialled when the first command starts
+func New(target string, opts ...Option) (*Executor, error) {
+	network, addr, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	e := &Executor{addr: addr, network: network}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+func (e *Executor) connectTimeout() time.Duration {
+	if e.timeout > 0 {
+		return e.timeout
+	}
+	return time.Second * time.Duration(timeoutSeconds)
+}
+
+func (e *Executor) LookPath(name string) (string, error) {
+	return e.Command(name).LookPath()
+}
+
+func (c *Cmd) beforeStart() error {
+	for _, h := range c.hooks {
+		if h.BeforeStart != nil {
+			if err := h.BeforeStart(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cmd) afterStart(err error) {
+	for _, h := range c.hooks {
+		if h.AfterStart != nil {
+			h.AfterStart(c, err)
+		}
+	}
+}
+
+func (c *Cmd) afterWait(err error) {
+	for _, h := range c.hooks {
+		if h.AfterWait != nil {
+			h.AfterWait(c, err)
+		}
+	}
+}
+
+// Context returns the context of command, background if not set
+func (c *Cmd) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestParseTarget(t *testing.T) {
+	for _, c := range []struct {
+		target  string
+		network string
+		addr    string
+		fail    bool
+	}{
+		{target: "unix:///var/run/executor.sock", network: "unix", addr: "/var/run/executor.sock"},
+		{target: "unix:/var/run/executor.sock", network: "unix", addr: "/var/run/executor.sock"},
+		{target: "/var/run/executor.sock", network: "unix", addr: "/var/run/executor.sock"},
+		{target: "./executor.sock", network: "unix", addr: "./executor.sock"},
+		{target: "tcp://10.0.0.1:8877", network: "tcp", addr: "10.0.0.1:8877"},
+		{target: "host:8877", network: "tcp", addr: "host:8877"},
+		{target: "http://host:8877", fail: true},
+		{target: "", fail: true},
+	} {
+		network, addr, err := client.ParseTarget(c.target)
+		if c.fail {
+			if err == nil {
+				t.Errorf("%q: got %s %s, want error", c.target, network, addr)
+			}
+			continue
+		}
+		if err != nil || network != c.network || addr != c.addr {
+			t.Errorf("%q: got %q %q %v, want %q %q", c.target, network, addr, err, c.network, c.addr)
+		}
+	}
+	if _, err := client.New("ftp://host"); err == nil {
+		t.Errorf("New accepted an unsupported target")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var calls []string
+	record := func(name string) client.Hooks {
+		return client.Hooks{
+			BeforeStart: func(c *client.Cmd) error {
+				calls = append(calls, name+" before "+c.Path)
+				c.Args = append(c.Args, name)
+				return nil
+			},
+			AfterStart: func(c *client.Cmd, err error) {
+				calls = append(calls, name+" started")
+			},
+			AfterWait: func(c *client.Cmd, err error) {
+				calls = append(calls, name+" waited")
+			},
+		}
+	}
+	h := executortest.NewRealHarness(client.WithHooks(record("a")), client.WithHooks(record("b")))
+	out, err := h.Executor.Command("/bin/echo").Output()
+	h.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "a b" {
+		t.Errorf("args modified by hooks: got %q, want %q", got, "a b")
+	}
+	want := []string{"a before /bin/echo", "b before /bin/echo", "a started", "b started", "a waited", "b waited"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls: got %q, want %q", calls, want)
+	}
+
+	denied := errors.New("denied")
+	var started error
+	h2 := executortest.NewRealHarness(client.WithHooks(client.Hooks{
+		BeforeStart: func(c *client.Cmd) error { return denied },
+		AfterStart:  func(c *client.Cmd, err error) { started = err },
+	}))
+	defer h2.Close()
+	if err := h2.Executor.Command("/bin/true").Run(); err != denied {
+		t.Errorf("BeforeStart error: got %v, want %v", err, denied)
+	}
+	if started != nil {
+		t.Errorf("AfterStart called after BeforeStart failed")
+	}
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+	// StreamCombined is recorded when stdout and stderr share a writer
+	StreamCombined = "combined"
+)
+
+// Chunk is a piece of recorded stdin or output, Text is used when the
+// data is valid utf8, Data otherwise
+type Chunk struct {
+	Stream string `json:"stream,omitempty"`
+	AtMs   int64  `json:"at_ms"`
+	Text   string `json:"text,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+func newChunk(stream string, at time.Duration, p []byte) Chunk {
+	c := Chunk{Stream: stream, AtMs: int64(at / time.Millisecond)}
+	if utf8.Valid(p) {
+		c.Text = string(p)
+	} else {
+		c.Data = append([]byte(nil), p...)
+	}
+	return c
+}
+
+func (c Chunk) Bytes() []byte {
+	if c.Data != nil {
+		return c.Data
+	}
+	return []byte(c.Text)
+}
+
+// RecordedCommand is one command of a recording, values of env are
+// not recorded as they may carry secrets
+type RecordedCommand struct {
+	Path    string   `json:"path"`
+	Args    []string `json:"args,omitempty"`
+	EnvKeys []string `json:"env_keys,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+
+	Stdin  []Chunk `json:"stdin,omitempty"`
+	Output []Chunk `json:"output,omitempty"`
+
+	// StartError is set when command failed to start
+	StartError string `json:"start_error,omitempty"`
+	// Error is set when waiting failed for other reason than exit status
+	Error      string `json:"error,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	Signal     int    `json:"signal,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Recording struct {
+	Commands []*RecordedCommand `json:"commands"`
+}
+
+func LoadRecording(path string) (*Recording, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read recording")
+	}
+	rec := &Recording{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, errors.Wrapf(err, "parse recording %s", path)
+	}
+	return rec, nil
+}
+
+func (r *Recording) Save(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// keep shell commands readable in golden files
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Recorder captures commands of an executor it is installed on with
+// WithRecorder, commands are kept in the order they are started
+type Recorder struct {
+	lock     sync.Mutex
+	commands []*recording
+	running  map[*Cmd]*recording
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{running: make(map[*Cmd]*recording)}
+}
+
+// WithRecorder records every command of executor into r
+func WithRecorder(r *Recorder) Option {
+	return WithHooks(Hooks{
+		BeforeStart: r.beforeStart,
+		AfterStart:  r.afterStart,
+		AfterWait:   r.afterWait,
+	})
+}
+
+// Recording returns a snapshot of commands recorded so far
+func (r *Recorder) Recording() *Recording {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rec := &Recording{Commands: make([]*RecordedCommand, len(r.commands))}
+	for i, rc := range r.commands {
+		rc.lock.Lock()
+		c := *rc.cmd
+		c.Stdin = append([]Chunk(nil), c.Stdin...)
+		c.Output = append([]Chunk(nil), c.Output...)
+		rc.lock.Unlock()
+		rec.Commands[i] = &c
+	}
+	return rec
+}
+
+func (r *Recorder) Save(path string) error {
+	return r.Recording().Save(path)
+}
+
+type recording struct {
+	lock    sync.Mutex
+	cmd     *RecordedCommand
+	startAt time.Time
+}
+
+func (rc *recording) append(stdin bool, stream string, p []byte) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	chunk := newChunk(stream, time.Since(rc.startAt), p)
+	if stdin {
+		chunk.Stream = ""
+		rc.cmd.Stdin = append(rc.cmd.Stdin, chunk)
+	} else {
+		rc.cmd.Output = append(rc.cmd.Output, chunk)
+	}
+}
+
+type recordReader struct {
+	r   io.Reader
+	rec *recording
+}
+
+func (r *recordReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.rec.append(true, "", p[:n])
+	}
+	return n, err
+}
+
+// recordWriter tees output into recording, pipe ends of StdoutPipe
+// and StderrPipe are closed when copying finished so readers get EOF
+type recordWriter struct {
+	w      io.Writer
+	stream string
+	rec    *recording
+	owned  *os.File
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.rec.append(false, w.stream, p)
+	return w.w.Write(p)
+}
+
+func (w *recordWriter) closeAfterCopy() {
+	if w.owned != nil {
+		w.owned.Close()
+	}
+}
+
+func (c *Cmd) newRecordWriter(w io.Writer, stream string, rec *recording) *recordWriter {
+	rw := &recordWriter{w: w, stream: stream, rec: rec}
+	if w == nil {
+		rw.w = ioutil.Discard
+	} else if f, ok := w.(*os.File); ok && c.ownsFile(f) {
+		rw.owned = f
+	}
+	return rw
+}
+
+func (r *Recorder) beforeStart(c *Cmd) error {
+	rec := &recording{
+		cmd: &RecordedCommand{
+			Path: c.Path,
+			Args: c.Args,
+			Dir:  c.Dir,
+		},
+		startAt: time.Now(),
+	}
+	for _, env := range c.Env {
+		rec.cmd.EnvKeys = append(rec.cmd.EnvKeys, strings.SplitN(env, "=", 2)[0])
+	}
+
+	if c.Stdin != nil {
+		c.Stdin = &recordReader{r: c.Stdin, rec: rec}
+	}
+	if c.Stderr != nil && interfaceEqual(c.Stderr, c.Stdout) {
+		c.Stdout = c.newRecordWriter(c.Stdout, StreamCombined, rec)
+		c.Stderr = c.Stdout
+	} else {
+		c.Stdout = c.newRecordWriter(c.Stdout, StreamStdout, rec)
+		c.Stderr = c.newRecordWriter(c.Stderr, StreamStderr, rec)
+	}
+
+	r.lock.Lock()
+	r.commands = append(r.commands, rec)
+	r.running[c] = rec
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *Recorder) afterStart(c *Cmd, err error) {
+	if err == nil {
+		return
+	}
+	r.lock.Lock()
+	rec := r.running[c]
+	delete(r.running, c)
+	r.lock.Unlock()
+	if rec == nil {
+		return
+	}
+	rec.lock.Lock()
+	rec.cmd.StartError = err.Error()
+	rec.lock.Unlock()
+}
+
+func (r *Recorder) afterWait(c *Cmd, err error) {
+	r.lock.Lock()
+	rec := r.running[c]
+	delete(r.running, c)
+	r.lock.Unlock()
+	if rec == nil {
+		return
+	}
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.cmd.DurationMs = int64(time.Since(rec.startAt) / time.Millisecond)
+	if err == nil {
+		return
+	}
+	if e, ok := errors.Cause(err).(*ExitError); ok {
+		if e.ExitStatus.Signaled() {
+			rec.cmd.Signal = int(e.ExitStatus.Signal())
+			rec.cmd.ExitCode = -1
+		} else {
+			rec.cmd.ExitCode = e.ExitStatus.ExitStatus()
+		}
+		return
+	}
+	rec.cmd.Error = err.Error()
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/log"
+)
+
+// RetryPolicy controls retries of commands failed before the process
+// has started, e.g. while the executor server restarts
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// MaxElapsed bounds the time spent on retrying a command
+	MaxElapsed time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	MaxElapsed:     30 * time.Second,
+}
+
+// WithRetryPolicy sets policy of commands opting in retry with Cmd.Retry,
+// default is DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(e *Executor) {
+		e.retryPolicy = &policy
+	}
+}
+
+// notStartedError marks failures surely happened before the request
+// reached the server, they are safe to retry for any command
+type notStartedError struct {
+	cause error
+}
+
+func notStarted(err error) error {
+	return &notStartedError{cause: err}
+}
+
+func (e *notStartedError) Error() string { return e.cause.Error() }
+func (e *notStartedError) Cause() error  { return e.cause }
+
+// isAmbiguous reports whether err is a transport failure after which
+// the server may or may not have started the process
+func isAmbiguous(err error) bool {
+	switch status.Code(errors.Cause(err)) {
+	case codes.Unavailable, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// startedAs returns sn of the command an earlier attempt of the request
+// started, when the server refused err as duplicate of it
+func startedAs(err error) (uint32, bool) {
+	st, ok := status.FromError(errors.Cause(err))
+	if !ok || st.Code() != codes.AlreadyExists {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if sn, ok := detail.(*apis.Sn); ok {
+			return sn.Sn, true
+		}
+	}
+	return 0, false
+}
+
+type retrier struct {
+	c        *Cmd
+	policy   RetryPolicy
+	startAt  time.Time
+	backoff  time.Duration
+	attempts int
+}
+
+func (c *Cmd) newRetrier() *retrier {
+	if !c.Retry {
+		return nil
+	}
+	policy := DefaultRetryPolicy
+	if c.retryPolicy != nil {
+		policy = *c.retryPolicy
+	}
+	if !c.Idempotent && c.dedupId == "" {
+		// retries share one id for server deduplication, not the request
+		// id of the caller which other commands may carry too
+		c.dedupId = newDedupId()
+	}
+	return &retrier{
+		c:       c,
+		policy:  policy,
+		startAt: time.Now(),
+		backoff: policy.InitialBackoff,
+	}
+}
+
+// next waits before another attempt, false if err must not be retried
+func (r *retrier) next(err error) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := err.(*notStartedError); !ok && !isAmbiguous(err) {
+		return false
+	}
+	// ambiguous failures of other commands rely on server deduplication
+	if time.Since(r.startAt)+r.backoff > r.policy.MaxElapsed {
+		return false
+	}
+	r.attempts++
+	log.Warningf("retry %s in %s, attempt %d: %s", r.c.Path, r.backoff, r.attempts, err)
+	select {
+	case <-time.After(r.backoff):
+	case <-r.c.Context().Done():
+		return false
+	}
+	r.backoff = time.Duration(float64(r.backoff) * r.policy.Multiplier)
+	if r.backoff > r.policy.MaxBackoff {
+		r.backoff = r.policy.MaxBackoff
+	}
+	return true
+}
+
+func newDedupId() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,124 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestRetryAttachesToStartedCommand(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	// dedup ids are remembered by the server across runs of -count
+	suffix := fmt.Sprintf("-%d", time.Now().UnixNano())
+	// output is repeated, attaching misses what came before
+	script := "read line; for i in 1 2 3 4 5 6 7 8 9 10; do echo $line; sleep 0.05; done; exit 3"
+	first := h.Executor.Command("/bin/sh", "-c", script)
+	first.Retry = true
+	client.SetDedupId(first, "attach-started"+suffix)
+	var firstOut bytes.Buffer
+	first.Stdout = &firstOut
+	stdin, err := first.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a retry whose earlier attempt started the command follows it
+	retried := h.Executor.Command("/bin/sh", "-c", script)
+	retried.Retry = true
+	client.SetDedupId(retried, "attach-started"+suffix)
+	stdout, err := retried.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := retried.Start(); err != nil {
+		t.Fatalf("retry not attached: %v", err)
+	}
+	stdin.Write([]byte("from first\n"))
+	stdin.Close()
+	out, _ := ioutil.ReadAll(stdout)
+	if !strings.HasSuffix(string(out), "from first\n") {
+		t.Errorf("attached output %q", out)
+	}
+	for name, cmd := range map[string]*client.Cmd{"first": first, "retried": retried} {
+		err := cmd.Wait()
+		if code, ok := client.GetExitStatus(err); !ok || code != 3 {
+			t.Errorf("%s: got %v, want exit status 3", name, err)
+		}
+	}
+	if got := strings.Count(firstOut.String(), "from first\n"); got != 10 {
+		t.Errorf("first got %d lines, want 10", got)
+	}
+
+	first = h.Executor.Command("/bin/sleep", "60")
+	first.Retry = true
+	client.SetDedupId(first, "attach-kill"+suffix)
+	if err := first.Start(); err != nil {
+		t.Fatal(err)
+	}
+	retried = h.Executor.Command("/bin/sleep", "60")
+	retried.Retry = true
+	client.SetDedupId(retried, "attach-kill"+suffix)
+	if err := retried.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := retried.Kill(); err != nil {
+		t.Fatalf("kill attached: %v", err)
+	}
+	for name, cmd := range map[string]*client.Cmd{"first": first, "retried": retried} {
+		err := cmd.Wait()
+		if ee, ok := err.(*client.ExitError); !ok || ee.ExitStatus.Signal() != syscall.SIGKILL {
+			t.Errorf("%s: got %v, want killed", name, err)
+		}
+	}
+}
+
+func TestRetryRequestIdShared(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	// commands made for one request of the caller all run
+	ctx := client.WithRequestId(context.Background(), fmt.Sprintf("shared-%d", time.Now().UnixNano()))
+	for _, word := range []string{"first", "second"} {
+		cmd := h.Executor.CommandContext(ctx, "/bin/echo", word)
+		cmd.Retry = true
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", word, err)
+		}
+		if string(out) != word+"\n" {
+			t.Errorf("%s: output %q", word, out)
+		}
+	}
+
+	// an id reused by another command is refused
+	suffix := fmt.Sprintf("-%d", time.Now().UnixNano())
+	for i, word := range []string{"first", "second"} {
+		cmd := h.Executor.Command("/bin/echo", word)
+		cmd.Retry = true
+		client.SetDedupId(cmd, "reused"+suffix)
+		err := cmd.Run()
+		if i == 0 && err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 && status.Code(errors.Cause(err)) != codes.InvalidArgument {
+			t.Errorf("reused id: got %v, want InvalidArgument", err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestRetryClassification(t *testing.T) {
+	withSn, _ := status.New(codes.AlreadyExists, "request a already executed as 7").WithDetails(&apis.Sn{Sn: 7})
+	for _, c := range []struct {
+		name      string
+		err       error
+		retry     bool
+		startedAs uint32
+	}{
+		{name: "dial failure", err: notStarted(errors.New("connection refused")), retry: true},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "transport closing"), retry: true},
+		{name: "wrapped unavailable", err: errors.Wrap(status.Error(codes.Unavailable, "eof"), "grpc exec recv started"), retry: true},
+		{name: "in progress", err: status.Error(codes.Aborted, "request a in progress"), retry: true},
+		{name: "start failure", err: errors.New("exec: not found")},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "bad")},
+		{name: "already exists", err: errors.Wrap(withSn.Err(), "grpc exec recv started"), startedAs: 7},
+		{name: "already exists without sn", err: status.Error(codes.AlreadyExists, "request a already executed as 7")},
+	} {
+		r := &retrier{c: &Cmd{}, policy: RetryPolicy{MaxElapsed: time.Minute, Multiplier: 1}, startAt: time.Now()}
+		if got := r.next(c.err); got != c.retry {
+			t.Errorf("%s: retry %v, want %v", c.name, got, c.retry)
+		}
+		sn, ok := startedAs(c.err)
+		if ok != (c.startedAs != 0) || sn != c.startedAs {
+			t.Errorf("%s: started as %d %v, want %d", c.name, sn, ok, c.startedAs)
+		}
+	}
+
+	var r *retrier
+	if r.next(status.Error(codes.Unavailable, "")) {
+		t.Errorf("nil retrier retried")
+	}
+}
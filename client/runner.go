@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"io"
+	osexec "os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Commander is what a command offers, implemented by *Cmd running
+// on executor server and *LocalCmd running on local host
+type Commander interface {
+	Run() error
+	Output() ([]byte, error)
+	CombinedOutput() ([]byte, error)
+	Start() error
+	Wait() error
+	StdinPipe() (io.WriteCloser, error)
+	StdoutPipe() (io.ReadCloser, error)
+	StderrPipe() (io.ReadCloser, error)
+	Kill() error
+
+	SetEnv(env []string)
+	SetDir(dir string)
+	SetStdin(r io.Reader)
+	SetStdout(w io.Writer)
+	SetStderr(w io.Writer)
+}
+
+var (
+	_ Commander = (*Cmd)(nil)
+	_ Commander = (*LocalCmd)(nil)
+)
+
+// Runner creates commands, callers depending on Runner work the same
+// inside containers through executor and on bare metal
+type Runner interface {
+	Command(path string, args ...string) Commander
+	CommandContext(ctx context.Context, path string, args ...string) Commander
+}
+
+func (c *Cmd) SetEnv(env []string)   { c.Env = env }
+func (c *Cmd) SetDir(dir string)     { c.Dir = dir }
+func (c *Cmd) SetStdin(r io.Reader)  { c.Stdin = r }
+func (c *Cmd) SetStdout(w io.Writer) { c.Stdout = w }
+func (c *Cmd) SetStderr(w io.Writer) { c.Stderr = w }
+
+type remoteRunner struct {
+	e *Executor
+}
+
+// RemoteRunner runs commands on executor server
+func RemoteRunner(e *Executor) Runner {
+	return &remoteRunner{e: e}
+}
+
+func (r *remoteRunner) Command(path string, args ...string) Commander {
+	return r.e.Command(path, args...)
+}
+
+func (r *remoteRunner) CommandContext(ctx context.Context, path string, args ...string) Commander {
+	return r.e.CommandContext(ctx, path, args...)
+}
+
+type LocalCmd struct {
+	*osexec.Cmd
+}
+
+func (c *LocalCmd) Kill() error {
+	if c.Process == nil {
+		return errors.New("exec: not started")
+	}
+	return c.Process.Kill()
+}
+
+func (c *LocalCmd) SetEnv(env []string)   { c.Env = env }
+func (c *LocalCmd) SetDir(dir string)     { c.Dir = dir }
+func (c *LocalCmd) SetStdin(r io.Reader)  { c.Stdin = r }
+func (c *LocalCmd) SetStdout(w io.Writer) { c.Stdout = w }
+func (c *LocalCmd) SetStderr(w io.Writer) { c.Stderr = w }
+
+type localRunner struct{}
+
+// LocalRunner runs commands on local host with os/exec
+func LocalRunner() Runner {
+	return localRunner{}
+}
+
+func (localRunner) Command(path string, args ...string) Commander {
+	return &LocalCmd{Cmd: osexec.Command(path, args...)}
+}
+
+func (localRunner) CommandContext(ctx context.Context, path string, args ...string) Commander {
+	return &LocalCmd{Cmd: osexec.CommandContext(ctx, path, args...)}
+}
+
+const fallbackProbeInterval = 10 * time.Second
+
+type fallbackRunner struct {
+	e     *Executor
+	local Runner
+
+	lock      sync.Mutex
+	probedAt  time.Time
+	reachable bool
+}
+
+// FallbackRunner runs commands on executor server, or locally when
+// the server is unreachable, reachability is probed at most every 10
+// seconds when commands are created
+func FallbackRunner(e *Executor) Runner {
+	return &fallbackRunner{e: e, local: LocalRunner()}
+}
+
+func (r *fallbackRunner) runner() Runner {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if time.Since(r.probedAt) > fallbackProbeInterval {
+		r.reachable = r.e.Reachable()
+		r.probedAt = time.Now()
+	}
+	if r.reachable {
+		return RemoteRunner(r.e)
+	}
+	return r.local
+}
+
+func (r *fallbackRunner) Command(path string, args ...string) Commander {
+	return r.runner().Command(path, args...)
+}
+
+func (r *fallbackRunner) CommandContext(ctx context.Context, path string, args ...string) Commander {
+	return r.runner().CommandContext(ctx, path, args...)
+}
+
+// Reachable reports whether executor server accepts connections
+func (e *Executor) Reachable() bool {
+	e.connLock.Lock()
+	conn := e.conn
+	e.connLock.Unlock()
+	if conn != nil && conn.GetState() == connectivity.Ready {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.connectTimeout())
+	defer cancel()
+	c, err := e.dial(ctx, e.addr)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// GetExitStatus returns exit status of a command exited non-zero,
+// for both remote and local commands
+func GetExitStatus(err error) (int, bool) {
+	var sys interface{}
+	switch e := errors.Cause(err).(type) {
+	case *ExitError:
+		sys = e.Sys()
+	case *osexec.ExitError:
+		sys = e.Sys()
+	default:
+		return 0, false
+	}
+	ws, ok := sys.(syscall.WaitStatus)
+	if !ok {
+		return 0, false
+	}
+	return ws.ExitStatus(), true
+}
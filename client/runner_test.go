@@ -0,0 +1,90 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func testRunner(t *testing.T, name string, r client.Runner) {
+	out, err := r.Command("/bin/sh", "-c", "echo $FOO; pwd").Output()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if string(out) == "" {
+		t.Errorf("%s: no output", name)
+	}
+
+	cmd := r.Command("/bin/sh", "-c", "cat; echo $FOO; pwd")
+	cmd.SetEnv([]string{"FOO=bar"})
+	cmd.SetDir("/")
+	cmd.SetStdin(strings.NewReader("in\n"))
+	var stdout, stderr bytes.Buffer
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if got, want := stdout.String(), "in\nbar\n/\n"; got != want {
+		t.Errorf("%s: got %q, want %q", name, got, want)
+	}
+
+	err = r.Command("/bin/sh", "-c", "exit 5").Run()
+	if code, ok := client.GetExitStatus(err); !ok || code != 5 {
+		t.Errorf("%s: exit status got %d %v from %v, want 5", name, code, ok, err)
+	}
+	if _, ok := client.GetExitStatus(nil); ok {
+		t.Errorf("%s: exit status of nil error", name)
+	}
+
+	cmd = r.Command("/bin/sleep", "60")
+	if err := cmd.Kill(); err == nil {
+		t.Errorf("%s: killed a command not started", name)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if err := cmd.Kill(); err != nil {
+		t.Errorf("%s: kill: %v", name, err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("%s: killed command exited cleanly", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := r.CommandContext(ctx, "/bin/sleep", "60").Run(); err == nil {
+		t.Errorf("%s: command outlived its context", name)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("%s: context cancel took %s", name, d)
+	}
+}
+
+func TestRunners(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+
+	testRunner(t, "local", client.LocalRunner())
+	testRunner(t, "remote", client.RemoteRunner(h.Executor))
+	testRunner(t, "fallback to remote", client.FallbackRunner(h.Executor))
+
+	down, err := client.New("/nonexistent/executor.sock", client.WithTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down.Reachable() {
+		t.Fatal("missing socket reachable")
+	}
+	r := client.FallbackRunner(down)
+	if _, ok := r.Command("/bin/true").(*client.LocalCmd); !ok {
+		t.Errorf("fallback runner didn't run locally with executor down")
+	}
+	testRunner(t, "fallback to local", r)
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// StartService starts a command supervised by the executor server,
+// it is restarted by spec.Restart and its output goes to rotating logs
+func (e *Executor) StartService(ctx context.Context, spec *apis.ServiceSpec) (*apis.ServiceStatus, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := cli.StartService(ctx, spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc start service")
+	}
+	return st, nil
+}
+
+// StopService stops the service and removes it from the server
+func (e *Executor) StopService(ctx context.Context, name string) (*apis.ServiceStatus, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := cli.StopService(ctx, &apis.ServiceName{Name: name})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc stop service")
+	}
+	return st, nil
+}
+
+func (e *Executor) GetService(ctx context.Context, name string) (*apis.ServiceStatus, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st, err := cli.GetService(ctx, &apis.ServiceName{Name: name})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc get service")
+	}
+	return st, nil
+}
+
+func (e *Executor) ListServices(ctx context.Context) ([]*apis.ServiceStatus, error) {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := cli.ListServices(ctx, &apis.Empty{})
+	if err != nil {
+		return nil, errors.Wrap(err, "grpc list services")
+	}
+	return res.Services, nil
+}
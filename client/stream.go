@@ -0,0 +1,407 @@
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/internal/chunk"
+	"yunion.io/x/log"
+)
+
+const (
+	// time to wait for an upgraded server to take the command back
+	resumeTimeout       = 30 * time.Second
+	resumeRetryInterval = 200 * time.Millisecond
+)
+
+// startStream runs command on a single Exec stream, the returned error
+// keeps grpc status so caller can fall back to legacy rpcs
+func (c *Cmd) startStream(procIO [3]*os.File) error {
+	var (
+		files    *apis.PassedFiles
+		streamIO = procIO
+	)
+	if c.passesFiles() {
+		var err error
+		if files, err = c.passFiles(procIO); err != nil {
+			return err
+		}
+		if c.PassStdio {
+			streamIO = [3]*os.File{}
+		}
+	}
+	combined := streamIO[1] != nil && streamIO[2] == streamIO[1]
+	ctx, cancel := context.WithCancel(c.traceContext())
+	stream, err := c.client.Exec(ctx)
+	if err != nil {
+		cancel()
+		return notStarted(errors.Wrap(err, "grpc exec"))
+	}
+	err = stream.Send(&apis.ExecRequest{
+		Request: &apis.ExecRequest_Start{Start: &apis.ExecStart{
+			Command:        c.command(),
+			HasStdin:       streamIO[0] != nil,
+			HasStdout:      streamIO[1] != nil,
+			HasStderr:      streamIO[2] != nil && !combined,
+			CombinedOutput: combined,
+			Window:         c.getWindow(),
+			Files:          files,
+		}},
+	})
+	// on io.EOF the stream is broken, real status comes from Recv
+	if err != nil && err != io.EOF {
+		cancel()
+		return notStarted(errors.Wrap(err, "grpc exec send start"))
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, "grpc exec recv started")
+	}
+	started := res.GetStarted()
+	if started == nil {
+		cancel()
+		return errors.New("exec stream not started")
+	}
+	if !started.Success {
+		cancel()
+		return errors.New(string(started.Error))
+	}
+
+	c.sn = &apis.Sn{Sn: started.Sn}
+	c.LandlockStatus = started.Landlock
+	c.streamLock.Lock()
+	c.stream = stream
+	c.streamLock.Unlock()
+	c.cancelStream = cancel
+	c.streamDone = make(chan struct{})
+	c.streamResumed = make(chan struct{})
+	c.startFlow(started.Window)
+	if streamIO != procIO {
+		// the process holds the passed pipe ends, readers of stdout
+		// get EOF once it exits
+		for _, f := range procIO {
+			if f != nil && c.ownsFile(f) {
+				f.Close()
+			}
+		}
+	}
+	if streamIO[0] != nil {
+		go c.streamStdinFrom(streamIO[0])
+	}
+	go c.recvStream(streamIO[1], streamIO[2])
+	return nil
+}
+
+// attachedStream stands in for the Exec stream of a command started
+// by an earlier attempt, output comes from Attach and signals are sent
+// by rpc, stdin can't be sent
+type attachedStream struct {
+	apis.Executor_AttachClient
+	client apis.ExecutorClient
+	sn     uint32
+	// ctx of signals, the stream is canceled once it ends, which may
+	// be caused by the signal
+	ctx context.Context
+}
+
+func (s *attachedStream) Send(req *apis.ExecRequest) error {
+	sig, ok := req.Request.(*apis.ExecRequest_Signal)
+	if !ok {
+		return nil
+	}
+	res, err := s.client.Signal(s.ctx, &apis.SignalRequest{Sn: s.sn, Signal: sig.Signal})
+	if err != nil {
+		return err
+	}
+	if len(res.Error) > 0 {
+		return errors.New(string(res.Error))
+	}
+	return nil
+}
+
+// attachStream follows the command sn started by an earlier attempt
+// of a retried Start till it exits
+func (c *Cmd) attachStream(sn uint32, procIO [3]*os.File) error {
+	ctx, cancel := context.WithCancel(c.traceContext())
+	stream, err := c.client.Attach(ctx, &apis.Sn{Sn: sn})
+	if err == nil {
+		// the exit of the command is seen once watched by the server
+		_, err = stream.Header()
+	}
+	if err != nil {
+		cancel()
+		return errors.Wrapf(err, "grpc attach to %d", sn)
+	}
+	log.Warningf("%s already started as %d, attached", c.Path, sn)
+	c.sn = &apis.Sn{Sn: sn}
+	c.streamLock.Lock()
+	c.stream = &attachedStream{Executor_AttachClient: stream, client: c.client, sn: sn, ctx: c.traceContext()}
+	c.streamLock.Unlock()
+	c.cancelStream = cancel
+	c.streamDone = make(chan struct{})
+	c.streamResumed = make(chan struct{})
+	c.startFlow(0)
+	if procIO[0] != nil {
+		c.streamStdin = errors.Errorf("stdin not sent to %d started by an earlier attempt", sn)
+		if c.ownsFile(procIO[0]) {
+			procIO[0].Close()
+		}
+	}
+	go c.recvStream(procIO[1], procIO[2])
+	return nil
+}
+
+// sendRequest sends req on the stream, on the stream resumed if it
+// broke on an upgrade of the server
+func (c *Cmd) sendRequest(req *apis.ExecRequest) error {
+	for {
+		c.sendLock.Lock()
+		c.streamLock.Lock()
+		stream, resumed := c.stream, c.streamResumed
+		c.streamLock.Unlock()
+		err := stream.Send(req)
+		if err == nil && req.GetCloseStdin() {
+			c.stdinClosed = true
+		}
+		c.sendLock.Unlock()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-resumed:
+		case <-c.streamDone:
+			return err
+		}
+	}
+}
+
+// resumeStream takes the command back from the server upgraded while
+// it runs, output missed is sent again by the server
+func (c *Cmd) resumeStream() error {
+	deadline := time.Now().Add(resumeTimeout)
+	for {
+		err := c.tryResume()
+		if err == nil {
+			return nil
+		}
+		if status.Code(err) != codes.Unavailable || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(resumeRetryInterval)
+	}
+}
+
+func (c *Cmd) tryResume() error {
+	ctx, cancel := context.WithCancel(c.traceContext())
+	stream, err := c.client.Exec(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	err = stream.Send(&apis.ExecRequest{
+		Request: &apis.ExecRequest_Start{Start: &apis.ExecStart{
+			ResumeSn:     c.sn.Sn,
+			StdoutOffset: c.stdoutOffset,
+			StderrOffset: c.stderrOffset,
+			Window:       c.getWindow(),
+		}},
+	})
+	if err != nil && err != io.EOF {
+		cancel()
+		return err
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return err
+	}
+	started := res.GetStarted()
+	if started == nil || !started.Success {
+		cancel()
+		return errors.New("exec stream not resumed")
+	}
+
+	// canceling unblocks sends on the broken stream
+	c.cancelStream()
+	c.sendLock.Lock()
+	defer c.sendLock.Unlock()
+	c.streamLock.Lock()
+	c.stream = stream
+	c.cancelStream = cancel
+	if c.window > 0 {
+		if err := c.resendStdin(stream, started.StdinOffset); err != nil {
+			c.streamLock.Unlock()
+			return errors.Wrap(err, "send stdin again")
+		}
+	}
+	if c.stdinClosed {
+		// may be lost with the broken stream
+		c.stream.Send(&apis.ExecRequest{
+			Request: &apis.ExecRequest_CloseStdin{CloseStdin: true},
+		})
+	}
+	close(c.streamResumed)
+	c.streamResumed = make(chan struct{})
+	c.streamLock.Unlock()
+	return nil
+}
+
+// getStream returns the stream of the command, replaced on resume
+func (c *Cmd) getStream() apis.Executor_ExecClient {
+	c.streamLock.Lock()
+	defer c.streamLock.Unlock()
+	return c.stream
+}
+
+// Signal sends sig to the remote process
+func (c *Cmd) Signal(sig syscall.Signal) error {
+	if c.getStream() == nil {
+		if sig == syscall.SIGKILL && c.sn != nil {
+			return c.Kill()
+		}
+		return errors.New("cmd not executing on exec stream")
+	}
+	err := c.sendRequest(&apis.ExecRequest{
+		Request: &apis.ExecRequest_Signal{Signal: int32(sig)},
+	})
+	if err != nil {
+		return errors.Wrap(err, "grpc send signal")
+	}
+	return nil
+}
+
+func (c *Cmd) isStreamDone() bool {
+	select {
+	case <-c.streamDone:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Cmd) streamStdinFrom(r io.Reader) {
+	data := chunk.Get(c.getChunkSize())
+	defer chunk.Put(data)
+	for {
+		n, err := r.Read(data)
+		if n > 0 {
+			e := c.sendStdinData(data[:n])
+			if _, ok := e.(stdinStalled); ok {
+				// writers of stdin get EPIPE, the process is killed
+				// so it won't take truncated stdin as complete
+				c.streamStdin = e
+				if f, ok := r.(*os.File); ok && c.ownsFile(f) {
+					f.Close()
+				}
+				c.Signal(syscall.SIGKILL)
+				return
+			}
+			if e != nil {
+				// stream finished or broken, the receiver reports why,
+				// keep draining so writers of stdin won't block
+				io.Copy(ioutil.Discard, r)
+				return
+			}
+		}
+		if err == io.EOF {
+			c.sendRequest(&apis.ExecRequest{
+				Request: &apis.ExecRequest_CloseStdin{CloseStdin: true},
+			})
+			return
+		} else if err != nil {
+			if !c.isStreamDone() {
+				c.streamStdin = errors.Wrap(err, "read from stdin")
+			}
+			return
+		}
+	}
+}
+
+// ownsFile reports whether f is a pipe end created by this Cmd,
+// files provided by caller are never closed
+func (c *Cmd) ownsFile(f *os.File) bool {
+	for _, cl := range c.closeAfterWait {
+		if interfaceEqual(cl, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cmd) recvStream(stdout, stderr *os.File) {
+	defer close(c.streamDone)
+	defer c.stopFlow()
+	defer func() {
+		if stdout != nil && c.ownsFile(stdout) {
+			stdout.Close()
+		}
+		if stderr != nil && stderr != stdout && c.ownsFile(stderr) {
+			stderr.Close()
+		}
+	}()
+
+	var stdoutErr, stderrErr error
+	for {
+		res, err := c.getStream().Recv()
+		if err != nil {
+			if status.Code(err) == codes.Unavailable && c.handoff {
+				// server upgraded, the command keeps running
+				if err = c.resumeStream(); err == nil {
+					c.handoff = false
+					continue
+				}
+			}
+			c.streamExec = errors.Wrap(err, "grpc exec recv")
+			return
+		}
+		switch r := res.Response.(type) {
+		case *apis.ExecResponse_Stdout:
+			c.stdoutOffset += uint64(len(r.Stdout))
+			if stdout != nil && stdoutErr == nil {
+				if stdoutErr = writeTo(r.Stdout, stdout); stdoutErr != nil {
+					c.streamStdout = errors.Wrap(stdoutErr, "write to stdout")
+				}
+			}
+			c.ackOutput()
+		case *apis.ExecResponse_Stderr:
+			c.stderrOffset += uint64(len(r.Stderr))
+			if stderr != nil && stderrErr == nil {
+				if stderrErr = writeTo(r.Stderr, stderr); stderrErr != nil {
+					c.streamStderr = errors.Wrap(stderrErr, "write to stderr")
+				}
+			}
+			c.ackOutput()
+		case *apis.ExecResponse_StdinAck:
+			c.ackStdin(r.StdinAck)
+		case *apis.ExecResponse_Handoff:
+			c.handoff = r.Handoff
+		case *apis.ExecResponse_Exit:
+			c.exitRes = r.Exit
+			return
+		}
+	}
+}
+
+func (c *Cmd) waitStream() error {
+	<-c.streamDone
+
+	if c.waitDone != nil {
+		close(c.waitDone)
+	}
+
+	if err := c.streamError(); err != nil {
+		c.closeDescriptors()
+		return err
+	}
+	return c.finish(c.exitRes)
+}
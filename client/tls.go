@@ -0,0 +1,32 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// LoadTLSCredentials returns mutual tls credentials for executor servers
+// listening on tcp, certFile and keyFile identify the client, caFile
+// verifies servers
+func LoadTLSCredentials(certFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "load client certificate")
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ca")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("no certificate found in %s", caFile)
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}), nil
+}
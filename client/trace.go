@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	"yunion.io/x/executor/apis"
+)
+
+type traceparentKey struct{}
+type requestIdKey struct{}
+
+// WithTraceparent attaches a W3C traceparent to ctx, commands created
+// by CommandContext(ctx, ...) forward it to the executor server
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// WithRequestId attaches a request id to ctx, commands created
+// by CommandContext(ctx, ...) forward it to the executor server
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+func TraceparentFromContext(ctx context.Context) string {
+	tp, _ := ctx.Value(traceparentKey{}).(string)
+	return tp
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// traceContext returns a context carrying the command's trace metadata,
+// it is not derived from c.ctx so cancellation is still handled by Kill
+func (c *Cmd) traceContext() context.Context {
+	ctx := context.Background()
+	if c.ctx == nil && c.dedupId == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(c.Context())
+	md = md.Copy()
+	if tp := TraceparentFromContext(c.Context()); tp != "" {
+		md.Set(apis.MetadataTraceparent, tp)
+	}
+	if id := RequestIdFromContext(c.Context()); id != "" {
+		md.Set(apis.MetadataRequestId, id)
+	}
+	if c.dedupId != "" {
+		md.Set(apis.MetadataDedup, c.dedupId)
+	}
+	if md.Len() == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
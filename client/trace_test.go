@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestTraceContext(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	e := &Executor{}
+
+	if _, ok := metadata.FromOutgoingContext(e.Command("true").traceContext()); ok {
+		t.Errorf("metadata sent without trace context")
+	}
+
+	ctx := WithRequestId(WithTraceparent(context.Background(), tp), "req-1")
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-other", "kept")
+	ctx, cancel := context.WithCancel(ctx)
+	c := e.CommandContext(ctx, "true")
+	tctx := c.traceContext()
+	cancel()
+	if tctx.Err() != nil {
+		t.Errorf("trace context canceled with command context")
+	}
+	md, _ := metadata.FromOutgoingContext(tctx)
+	for key, want := range map[string]string{
+		apis.MetadataTraceparent: tp,
+		apis.MetadataRequestId:   "req-1",
+		"x-other":                "kept",
+	} {
+		if got := md.Get(key); len(got) != 1 || got[0] != want {
+			t.Errorf("metadata %s: got %q, want %q", key, got, want)
+		}
+	}
+	if got := md.Get(apis.MetadataDedup); len(got) != 0 {
+		t.Errorf("dedup without retry: %q", got)
+	}
+}
+
+func TestDedupIdPerCommand(t *testing.T) {
+	e := &Executor{}
+	ctx := WithRequestId(context.Background(), "req-1")
+	var ids []string
+	for i := 0; i < 2; i++ {
+		c := e.CommandContext(ctx, "true")
+		c.Retry = true
+		c.newRetrier()
+		md, _ := metadata.FromOutgoingContext(c.traceContext())
+		if got := md.Get(apis.MetadataRequestId); len(got) != 1 || got[0] != "req-1" {
+			t.Errorf("request id: got %q", got)
+		}
+		got := md.Get(apis.MetadataDedup)
+		if len(got) != 1 || len(got[0]) != 32 {
+			t.Fatalf("dedup id: got %q", got)
+		}
+		// attempts of a command share it
+		c.newRetrier()
+		if c.dedupId != got[0] {
+			t.Errorf("dedup id changed between attempts")
+		}
+		ids = append(ids, got[0])
+	}
+	// commands of one request are not taken for retries of each other
+	if ids[0] == ids[1] {
+		t.Errorf("commands share dedup id %s", ids[0])
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/executortest"
+)
+
+// captureStdout returns what fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	os.Stdout = stdout
+	w.Close()
+	return <-out
+}
+
+func newTestShell(t *testing.T, h *executortest.Harness) *shell {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	s := &shell{e: h.Executor, sigs: make(chan os.Signal, 1)}
+	s.input = newInputRouter(r, false)
+	s.initSession()
+	return s
+}
+
+func TestShellSession(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+	s := newTestShell(t, h)
+
+	wd, _ := os.Getwd()
+	if s.dir != wd {
+		t.Errorf("initial dir %q, want %q", s.dir, wd)
+	}
+	s.execLine("cd /tmp")
+	if s.dir != "/tmp" {
+		t.Errorf("cd /tmp: dir %q", s.dir)
+	}
+	s.execLine("cd /")
+	s.execLine("cd -")
+	if s.dir != "/tmp" || s.oldDir != "/" {
+		t.Errorf("cd -: dir %q old %q", s.dir, s.oldDir)
+	}
+	out := captureStdout(t, func() { s.execLine("cd /nonexistent") })
+	if s.dir != "/tmp" || !strings.Contains(out, "cd:") {
+		t.Errorf("cd to missing dir: dir %q, output %q", s.dir, out)
+	}
+
+	s.execLine("export GREETING=hello")
+	out = captureStdout(t, func() { s.execLine(`sh -c "echo $GREETING from $(pwd)"`) })
+	if out != "hello from /tmp\n" {
+		t.Errorf("env and dir of command: %q", out)
+	}
+	s.execLine("export GREETING=bye")
+	if got := s.getEnv("GREETING"); got != "bye" {
+		t.Errorf("export again: %q", got)
+	}
+	s.execLine("unset GREETING")
+	out = captureStdout(t, func() { s.execLine(`sh -c "echo ${GREETING-unset}"`) })
+	if out != "unset\n" {
+		t.Errorf("unset: %q", out)
+	}
+	out = captureStdout(t, func() { s.execLine("pwd") })
+	if out != "/tmp\n" {
+		t.Errorf("pwd: %q", out)
+	}
+}
+
+func TestShellJobs(t *testing.T) {
+	h := executortest.NewRealHarness()
+	defer h.Close()
+	s := newTestShell(t, h)
+
+	out := captureStdout(t, func() { s.execLine("sleep 60 &") })
+	if out != "[1] sleep 60 &\n" {
+		t.Errorf("background job: %q", out)
+	}
+	out = captureStdout(t, func() { s.execLine("jobs") })
+	if !strings.Contains(out, "[1] Running") {
+		t.Errorf("jobs: %q", out)
+	}
+	j := s.findJob([]string{"%1"})
+	if j == nil {
+		t.Fatal("job %1 not found")
+	}
+	captureStdout(t, func() {
+		if s.findJob([]string{"%2"}) != nil {
+			t.Errorf("found missing job")
+		}
+	})
+	j.cmd.Kill()
+	select {
+	case <-j.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("job not killed")
+	}
+	out = captureStdout(t, func() { s.reportJobs() })
+	if !strings.Contains(out, "[1] Killed(killed)") {
+		t.Errorf("report: %q", out)
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("reported job kept")
+	}
+
+	out = captureStdout(t, func() { s.execLine("sh -c 'exit 3'") })
+	if out != "" {
+		t.Errorf("foreground job: %q", out)
+	}
+	out = captureStdout(t, func() { s.execLine("/nonexistent/command") })
+	if !strings.HasPrefix(out, "/nonexistent/command: ") {
+		t.Errorf("start failure: %q", out)
+	}
+}
+
+func TestInputRouterReadLine(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newInputRouter(r, false)
+	go func() {
+		io.WriteString(w, "first\nsecond")
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, " part\nrest")
+		w.Close()
+	}()
+	for _, want := range []string{"first", "second part", "rest"} {
+		line, err := router.readLine()
+		if err != nil || line != want {
+			t.Errorf("got %q %v, want %q", line, err, want)
+		}
+	}
+	if _, err := router.readLine(); err != io.EOF {
+		t.Errorf("after end: %v", err)
+	}
+}
@@ -0,0 +1,439 @@
+// Package executortest provides an in-memory executor for tests of code
+// running commands through package client.
+//
+// A FakeServer answers commands with canned output:
+//
+//	fake := executortest.NewFakeServer()
+//	fake.On("ovs-vsctl", "list-br").Stdout("br0\n")
+//	fake.On("ip", "link", "del", "tap0").Stderr("Cannot find device\n").Exit(1)
+//	h := executortest.NewHarness(fake)
+//	defer h.Close()
+//	out, err := h.Executor.Command("ovs-vsctl", "list-br").Output()
+//
+// NewRealHarness runs the real server.Executor in process instead.
+package executortest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+// Invocation is passed to custom handlers of an expectation
+type Invocation struct {
+	Command *apis.Command
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	// Signals receives signals sent to the command by client
+	Signals <-chan syscall.Signal
+}
+
+type Expectation struct {
+	match    func(*apis.Command) bool
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+	delay    time.Duration
+	startErr string
+	waitErr  string
+	signal   syscall.Signal
+	run      func(*Invocation) int
+	times    int
+	used     int
+
+	// output is written after stdout and stderr, at recorded offsets
+	// when realtime
+	output   []client.Chunk
+	realtime bool
+}
+
+func (e *Expectation) Stdout(s string) *Expectation {
+	e.stdout = []byte(s)
+	return e
+}
+
+func (e *Expectation) Stderr(s string) *Expectation {
+	e.stderr = []byte(s)
+	return e
+}
+
+func (e *Expectation) Exit(code int) *Expectation {
+	e.exitCode = code
+	return e
+}
+
+// Delay postpones output and exit, a signal from client ends the
+// command early as killed by that signal
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// StartError makes the command fail to start with msg
+func (e *Expectation) StartError(msg string) *Expectation {
+	e.startErr = msg
+	return e
+}
+
+// Killed makes the command end as killed by sig
+func (e *Expectation) Killed(sig syscall.Signal) *Expectation {
+	e.signal = sig
+	return e
+}
+
+// WaitError makes waiting for the command fail with msg
+func (e *Expectation) WaitError(msg string) *Expectation {
+	e.waitErr = msg
+	return e
+}
+
+// Run handles the command with fn instead of canned output, the
+// returned value is the exit code
+func (e *Expectation) Run(fn func(*Invocation) int) *Expectation {
+	e.run = fn
+	return e
+}
+
+// Times limits how many commands the expectation answers, unlimited
+// by default, later expectations of the same command take over
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+type Call struct {
+	Command  *apis.Command
+	Stdin    []byte
+	Status   syscall.WaitStatus
+	Finished bool
+}
+
+// FakeServer implements apis.ExecutorServer answering commands by
+// expectations, commands without a matching one fail to start
+type FakeServer struct {
+	apis.UnimplementedExecutorServer
+
+	lock         sync.Mutex
+	expectations []*Expectation
+	calls        []*Call
+	sn           uint32
+}
+
+func NewFakeServer() *FakeServer {
+	return &FakeServer{}
+}
+
+func pathMatch(pattern, path string) bool {
+	if pattern == path {
+		return true
+	}
+	// bare names match executables anywhere in PATH
+	return filepath.Base(pattern) == pattern && filepath.Base(path) == pattern
+}
+
+// On matches commands by path and exact args, a path without slash
+// matches the basename of command path
+func (f *FakeServer) On(path string, args ...string) *Expectation {
+	return f.OnMatch(func(cmd *apis.Command) bool {
+		if !pathMatch(path, string(cmd.Path)) || len(cmd.Args) != len(args) {
+			return false
+		}
+		for i := range args {
+			if string(cmd.Args[i]) != args[i] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// OnAnyArgs matches commands by path only
+func (f *FakeServer) OnAnyArgs(path string) *Expectation {
+	return f.OnMatch(func(cmd *apis.Command) bool {
+		return pathMatch(path, string(cmd.Path))
+	})
+}
+
+func (f *FakeServer) OnMatch(match func(*apis.Command) bool) *Expectation {
+	e := &Expectation{match: match}
+	f.lock.Lock()
+	f.expectations = append(f.expectations, e)
+	f.lock.Unlock()
+	return e
+}
+
+func (f *FakeServer) find(cmd *apis.Command, consume bool) *Expectation {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, e := range f.expectations {
+		if e.times > 0 && e.used >= e.times {
+			continue
+		}
+		if e.match(cmd) {
+			if consume {
+				e.used++
+			}
+			return e
+		}
+	}
+	return nil
+}
+
+// Calls returns commands received so far in order
+func (f *FakeServer) Calls() []Call {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	calls := make([]Call, len(f.calls))
+	for i := range f.calls {
+		calls[i] = *f.calls[i]
+	}
+	return calls
+}
+
+func (f *FakeServer) record(cmd *apis.Command) *Call {
+	call := &Call{Command: cmd}
+	f.lock.Lock()
+	f.calls = append(f.calls, call)
+	f.lock.Unlock()
+	return call
+}
+
+func (f *FakeServer) finish(call *Call, stdin []byte, status syscall.WaitStatus) {
+	f.lock.Lock()
+	call.Stdin = stdin
+	call.Status = status
+	call.Finished = true
+	f.lock.Unlock()
+}
+
+func (f *FakeServer) LookPath(ctx context.Context, cmd *apis.Command) (*apis.LookPathResponse, error) {
+	if f.find(cmd, false) == nil {
+		return &apis.LookPathResponse{
+			NotFound: true,
+			Error:    []byte("executable file not found in $PATH"),
+		}, nil
+	}
+	path := string(cmd.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join("/usr/bin", path)
+	}
+	return &apis.LookPathResponse{Path: []byte(path)}, nil
+}
+
+// stdinBuffer collects stdin without blocking the stream receiver,
+// reads block until data arrives or stdin is closed
+type stdinBuffer struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	buf    bytes.Buffer
+	all    bytes.Buffer
+	closed bool
+}
+
+func newStdinBuffer() *stdinBuffer {
+	b := &stdinBuffer{}
+	b.cond = sync.NewCond(&b.lock)
+	return b
+}
+
+func (b *stdinBuffer) Write(p []byte) {
+	b.lock.Lock()
+	b.buf.Write(p)
+	b.all.Write(p)
+	b.lock.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *stdinBuffer) Close() {
+	b.lock.Lock()
+	b.closed = true
+	b.lock.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *stdinBuffer) Read(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for b.buf.Len() == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if b.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return b.buf.Read(p)
+}
+
+func (b *stdinBuffer) Bytes() []byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]byte(nil), b.all.Bytes()...)
+}
+
+type frameWriter struct {
+	lock  *sync.Mutex
+	s     apis.Executor_ExecServer
+	frame func([]byte) *apis.ExecResponse
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.s.Send(w.frame(append([]byte(nil), p...))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func startedResponse(sn uint32, err string) *apis.ExecResponse {
+	return &apis.ExecResponse{
+		Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{
+			Success: err == "",
+			Error:   []byte(err),
+			Sn:      sn,
+		}},
+	}
+}
+
+func (f *FakeServer) Exec(s apis.Executor_ExecServer) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	start := req.GetStart()
+	if start == nil || start.Command == nil {
+		return errors.New("exec stream must begin with start")
+	}
+	cmd := start.Command
+	sn := atomic.AddUint32(&f.sn, 1)
+	call := f.record(cmd)
+
+	e := f.find(cmd, true)
+	if e == nil {
+		msg := fmt.Sprintf("exec: %q: executable file not found in $PATH", cmd.Path)
+		return s.Send(startedResponse(sn, msg))
+	}
+	if e.startErr != "" {
+		return s.Send(startedResponse(sn, e.startErr))
+	}
+	if err := s.Send(startedResponse(sn, "")); err != nil {
+		return err
+	}
+
+	stdin := newStdinBuffer()
+	signals := make(chan syscall.Signal, 8)
+	go func() {
+		defer stdin.Close()
+		for {
+			req, err := s.Recv()
+			if err != nil {
+				return
+			}
+			switch r := req.Request.(type) {
+			case *apis.ExecRequest_Stdin:
+				stdin.Write(r.Stdin)
+			case *apis.ExecRequest_CloseStdin:
+				stdin.Close()
+			case *apis.ExecRequest_Signal:
+				select {
+				case signals <- syscall.Signal(r.Signal):
+				default:
+				}
+			}
+		}
+	}()
+
+	var (
+		sendLock sync.Mutex
+		stdout   = &frameWriter{lock: &sendLock, s: s, frame: func(p []byte) *apis.ExecResponse {
+			return &apis.ExecResponse{Response: &apis.ExecResponse_Stdout{Stdout: p}}
+		}}
+		stderr = &frameWriter{lock: &sendLock, s: s, frame: func(p []byte) *apis.ExecResponse {
+			return &apis.ExecResponse{Response: &apis.ExecResponse_Stderr{Stderr: p}}
+		}}
+		status syscall.WaitStatus
+	)
+	if e.run != nil {
+		code := e.run(&Invocation{
+			Command: cmd,
+			Stdin:   stdin,
+			Stdout:  stdout,
+			Stderr:  stderr,
+			Signals: signals,
+		})
+		status = syscall.WaitStatus(code << 8)
+	} else {
+		status, err = e.respond(s.Context(), stdout, stderr, signals)
+		if err != nil {
+			return err
+		}
+	}
+	f.finish(call, stdin.Bytes(), status)
+
+	sendLock.Lock()
+	defer sendLock.Unlock()
+	return s.Send(&apis.ExecResponse{
+		Response: &apis.ExecResponse_Exit{Exit: &apis.WaitResponse{
+			ExitStatus: uint32(status),
+			ErrContent: []byte(e.waitErr),
+		}},
+	})
+}
+
+// respond writes canned output, a signal from client during delays
+// ends the command as killed by that signal
+func (e *Expectation) respond(
+	ctx context.Context, stdout, stderr io.Writer, signals <-chan syscall.Signal,
+) (syscall.WaitStatus, error) {
+	wait := func(d time.Duration) (syscall.Signal, error) {
+		if d <= 0 {
+			return 0, nil
+		}
+		select {
+		case <-time.After(d):
+			return 0, nil
+		case sig := <-signals:
+			return sig, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	if sig, err := wait(e.delay); err != nil || sig != 0 {
+		return syscall.WaitStatus(sig), err
+	}
+	startAt := time.Now()
+	stdout.Write(e.stdout)
+	stderr.Write(e.stderr)
+	for _, chunk := range e.output {
+		if e.realtime {
+			at := time.Duration(chunk.AtMs) * time.Millisecond
+			if sig, err := wait(at - time.Since(startAt)); err != nil || sig != 0 {
+				return syscall.WaitStatus(sig), err
+			}
+		}
+		if chunk.Stream == client.StreamStderr {
+			stderr.Write(chunk.Bytes())
+		} else {
+			stdout.Write(chunk.Bytes())
+		}
+	}
+	if e.signal != 0 {
+		return syscall.WaitStatus(e.signal), nil
+	}
+	return syscall.WaitStatus(e.exitCode << 8), nil
+}
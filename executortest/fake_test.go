@@ -0,0 +1,183 @@
+package executortest_test
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestFakeServerMatching(t *testing.T) {
+	fake := executortest.NewFakeServer()
+	fake.On("ovs-vsctl", "list-br").Stdout("br0\n")
+	fake.On("/sbin/ip", "link").Stdout("lo\n")
+	fake.OnAnyArgs("ip").Stderr("Cannot find device\n").Exit(1)
+	fake.OnMatch(func(cmd *apis.Command) bool { return string(cmd.Dir) == "/tmp" }).Stdout("in tmp\n")
+	h := executortest.NewHarness(fake)
+	defer h.Close()
+
+	for _, c := range []struct {
+		path string
+		args []string
+		dir  string
+		out  string
+		code int
+	}{
+		{path: "ovs-vsctl", args: []string{"list-br"}, out: "br0\n"},
+		{path: "/usr/bin/ovs-vsctl", args: []string{"list-br"}, out: "br0\n"},
+		{path: "/sbin/ip", args: []string{"link"}, out: "lo\n"},
+		{path: "/usr/sbin/ip", args: []string{"link"}, out: "Cannot find device\n", code: 1},
+		{path: "ip", args: []string{"link", "del", "tap0"}, out: "Cannot find device\n", code: 1},
+		{path: "ls", dir: "/tmp", out: "in tmp\n"},
+	} {
+		cmd := h.Executor.Command(c.path, c.args...)
+		cmd.Dir = c.dir
+		out, err := cmd.CombinedOutput()
+		code, _ := client.GetExitStatus(err)
+		if string(out) != c.out || code != c.code {
+			t.Errorf("%s %q: got %q %v, want %q exit %d", c.path, c.args, out, err, c.out, c.code)
+		}
+	}
+
+	// without expectation commands fail to start
+	if err := h.Executor.Command("ovs-vsctl", "del-br", "br0").Start(); err == nil {
+		t.Errorf("unexpected command started")
+	}
+	if _, err := h.Executor.LookPath("missing"); err == nil {
+		t.Errorf("unexpected command found")
+	}
+	if path, err := h.Executor.LookPath("ip"); err != nil || path != "/usr/bin/ip" {
+		t.Errorf("LookPath: got %q %v", path, err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 7 {
+		t.Fatalf("got %d calls, want 7", len(calls))
+	}
+	if !calls[0].Finished || string(calls[0].Command.Path) != "ovs-vsctl" || calls[6].Finished {
+		t.Errorf("calls recorded wrong: %+v", calls)
+	}
+}
+
+func TestFakeServerTimes(t *testing.T) {
+	fake := executortest.NewFakeServer()
+	fake.On("systemctl", "is-active", "sshd").Stdout("activating\n").Times(2)
+	fake.On("systemctl", "is-active", "sshd").Stdout("active\n")
+	fake.On("once").Times(1)
+	h := executortest.NewHarness(fake)
+	defer h.Close()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		out, err := h.Executor.Command("systemctl", "is-active", "sshd").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.TrimSpace(string(out)))
+	}
+	if want := "activating activating active active"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := h.Executor.Command("once").Run(); err != nil {
+		t.Errorf("first run: %v", err)
+	}
+	if err := h.Executor.Command("once").Run(); err == nil {
+		t.Errorf("expectation used more than Times")
+	}
+}
+
+func TestFakeServerDelayKill(t *testing.T) {
+	fake := executortest.NewFakeServer()
+	fake.OnAnyArgs("sleep").Delay(time.Hour).Stdout("woke up\n")
+	h := executortest.NewHarness(fake)
+	defer h.Close()
+
+	cmd := h.Executor.Command("sleep", "3600")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	err := cmd.Wait()
+	ee, ok := err.(*client.ExitError)
+	if !ok || !ee.ExitStatus.Signaled() || ee.ExitStatus.Signal() != syscall.SIGKILL {
+		t.Errorf("got %v, want killed", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	out, err := h.Executor.CommandContext(ctx, "sleep", "3600").Output()
+	if err == nil || len(out) > 0 {
+		t.Errorf("command outlived context: %q %v", out, err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("cancel took %s", d)
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 || calls[0].Status.Signal() != syscall.SIGKILL {
+		t.Errorf("calls: %+v", calls)
+	}
+}
+
+func TestFakeServerRun(t *testing.T) {
+	fake := executortest.NewFakeServer()
+	fake.On("tr", "a-z", "A-Z").Run(func(inv *executortest.Invocation) int {
+		buf := make([]byte, 64)
+		for {
+			n, err := inv.Stdin.Read(buf)
+			inv.Stdout.Write([]byte(strings.ToUpper(string(buf[:n]))))
+			if err != nil {
+				return 0
+			}
+		}
+	})
+	h := executortest.NewHarness(fake)
+	defer h.Close()
+
+	cmd := h.Executor.Command("tr", "a-z", "A-Z")
+	cmd.Stdin = strings.NewReader("hello")
+	out, err := cmd.Output()
+	if err != nil || string(out) != "HELLO" {
+		t.Errorf("got %q %v", out, err)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || string(calls[0].Stdin) != "hello" {
+		t.Errorf("stdin not recorded: %+v", calls)
+	}
+}
+
+func TestRealHarness(t *testing.T) {
+	h := executortest.NewRealHarness()
+	out, err := h.Executor.Command("/bin/echo", "real").Output()
+	if err != nil || string(out) != "real\n" {
+		t.Errorf("got %q %v", out, err)
+	}
+
+	// a second real harness waits for the first one
+	opened := make(chan *executortest.Harness)
+	go func() {
+		opened <- executortest.NewRealHarness()
+	}()
+	select {
+	case h2 := <-opened:
+		h2.Close()
+		t.Fatal("real harnesses opened at the same time")
+	case <-time.After(100 * time.Millisecond):
+	}
+	h.Close()
+	select {
+	case h2 := <-opened:
+		defer h2.Close()
+		if err := h2.Executor.Command("/bin/true").Run(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("real harness not opened after the previous closed")
+	}
+}
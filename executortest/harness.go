@@ -0,0 +1,74 @@
+package executortest
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/server"
+)
+
+const bufconnSize = 1024 * 1024
+
+// Harness serves an executor in process over an in-memory connection,
+// Executor is a client of it
+type Harness struct {
+	Server   *grpc.Server
+	Listener *bufconn.Listener
+	Executor *client.Executor
+
+	unlock func()
+}
+
+// realLock is held by the real harness in use, commands, services and
+// request ids of server.Executor are process wide
+var realLock sync.Mutex
+
+// NewHarness serves srv, e.g. a FakeServer, client options are passed
+// to the returned client executor
+func NewHarness(srv apis.ExecutorServer, opts ...client.Option) *Harness {
+	lis := bufconn.Listen(bufconnSize)
+	grpcServer := grpc.NewServer()
+	apis.RegisterExecutorServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	opts = append([]client.Option{client.WithDialer(dialer)}, opts...)
+	e, err := client.New("bufconn", opts...)
+	if err != nil {
+		// target is constant, never happens
+		panic(err)
+	}
+	return &Harness{
+		Server:   grpcServer,
+		Listener: lis,
+		Executor: e,
+	}
+}
+
+// NewRealHarness serves the real server.Executor, commands run on
+// local host. Server state is process wide, so it waits until the real
+// harness in use, e.g. of a parallel test, is closed
+func NewRealHarness(opts ...client.Option) *Harness {
+	realLock.Lock()
+	h := NewHarness(&server.Executor{}, opts...)
+	h.unlock = realLock.Unlock
+	return h
+}
+
+func (h *Harness) Close() {
+	h.Executor.Close()
+	h.Server.Stop()
+	h.Listener.Close()
+	if h.unlock != nil {
+		h.unlock()
+		h.unlock = nil
+	}
+}
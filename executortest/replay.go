@@ -0,0 +1,59 @@
+package executortest
+
+import (
+	"syscall"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+// NewReplayServer answers commands with recordings of rec, a command
+// gets the first unused recording of the same path, args and dir, so
+// repeated commands replay in recorded order. Output is written at
+// once unless realtime, which keeps recorded timing.
+func NewReplayServer(rec *client.Recording, realtime bool) *FakeServer {
+	f := NewFakeServer()
+	for _, cmd := range rec.Commands {
+		f.replay(cmd, realtime)
+	}
+	return f
+}
+
+// LoadReplayServer reads a recording saved by client.Recorder
+func LoadReplayServer(path string, realtime bool) (*FakeServer, error) {
+	rec, err := client.LoadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayServer(rec, realtime), nil
+}
+
+func (f *FakeServer) replay(rc *client.RecordedCommand, realtime bool) {
+	e := f.OnMatch(func(cmd *apis.Command) bool {
+		if !pathMatch(rc.Path, string(cmd.Path)) || string(cmd.Dir) != rc.Dir {
+			return false
+		}
+		if len(cmd.Args) != len(rc.Args) {
+			return false
+		}
+		for i := range rc.Args {
+			if string(cmd.Args[i]) != rc.Args[i] {
+				return false
+			}
+		}
+		return true
+	}).Times(1)
+
+	e.output = rc.Output
+	e.realtime = realtime
+	switch {
+	case rc.StartError != "":
+		e.StartError(rc.StartError)
+	case rc.Error != "":
+		e.WaitError(rc.Error)
+	case rc.Signal != 0:
+		e.Killed(syscall.Signal(rc.Signal))
+	default:
+		e.Exit(rc.ExitCode)
+	}
+}
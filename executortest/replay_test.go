@@ -0,0 +1,93 @@
+package executortest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"yunion.io/x/executor/client"
+	"yunion.io/x/executor/executortest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	type result struct {
+		out  string
+		code int
+		err  bool
+	}
+	session := func(e *client.Executor) []result {
+		var res []result
+		run := func(cmd *client.Cmd) {
+			out, err := cmd.CombinedOutput()
+			code, _ := client.GetExitStatus(err)
+			res = append(res, result{string(out), code, err != nil})
+		}
+		cmd := e.Command("/bin/sh", "-c", "echo $SECRET; cat")
+		cmd.Env = []string{"SECRET=hunter2"}
+		cmd.Stdin = strings.NewReader("from stdin\n")
+		run(cmd)
+		run(e.Command("/bin/sh", "-c", "echo err >&2; exit 3"))
+		run(e.Command("/bin/echo", "again"))
+		run(e.Command("/bin/echo", "again"))
+		run(e.Command("/bin/printf", `\377\376`))
+		run(e.Command("/nonexistent"))
+		return res
+	}
+
+	rec := client.NewRecorder()
+	h := executortest.NewRealHarness(client.WithRecorder(rec))
+	recorded := session(h.Executor)
+	h.Close()
+
+	path := filepath.Join(dir, "session.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2=") || !strings.Contains(string(data), `"SECRET"`) {
+		t.Errorf("env recorded wrong: %s", data)
+	}
+	loaded, err := client.LoadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(loaded.Commands); got != 6 {
+		t.Fatalf("recorded %d commands, want 6", got)
+	}
+	if got := string(loaded.Commands[0].Stdin[0].Bytes()); got != "from stdin\n" {
+		t.Errorf("recorded stdin %q", got)
+	}
+	if c := loaded.Commands[4]; len(c.Output) == 0 || c.Output[0].Data == nil {
+		t.Errorf("binary output not recorded as data: %+v", c.Output)
+	}
+	if loaded.Commands[5].StartError == "" {
+		t.Errorf("start error not recorded")
+	}
+
+	srv, err := executortest.LoadReplayServer(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = executortest.NewHarness(srv)
+	defer h.Close()
+	replayed := session(h.Executor)
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+	// each recording is used once
+	if err := h.Executor.Command("/bin/echo", "again").Run(); err == nil {
+		t.Errorf("recording replayed twice")
+	}
+}
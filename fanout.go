@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+// cliFanout runs a command on many executors, the default executor of
+// -socket-path is not used
+func cliFanout(_ *client.Executor, args []string) int {
+	var (
+		targets     envFlag
+		targetsFile string
+		env         envFlag
+		dir         string
+		concurrency int
+		timeout     time.Duration
+		failFast    bool
+		asJSON      bool
+		sendStdin   bool
+		tlsCert     string
+		tlsKey      string
+		tlsCA       string
+	)
+	fs := newFlagSet("fanout", usageFanout)
+	fs.Var(&targets, "target", "executor target, unix socket path or host:port, can be repeated")
+	fs.StringVar(&targetsFile, "targets-file", "", "file of targets, one per line")
+	fs.Var(&env, "env", "KEY=VALUE added to the server environment, can be repeated")
+	fs.StringVar(&dir, "dir", "", "working directory")
+	fs.IntVar(&concurrency, "concurrency", 16, "hosts running at the same time")
+	fs.DurationVar(&timeout, "timeout", 0, "kill command on a host after timeout")
+	fs.BoolVar(&failFast, "fail-fast", false, "stop all hosts on the first failure, default continues")
+	fs.BoolVar(&asJSON, "json", false, "print aggregated results as json instead of streaming prefixed output")
+	fs.BoolVar(&sendStdin, "stdin", false, "read stdin and send it to every host")
+	fs.StringVar(&tlsCert, "tls-cert", "", "client certificate for tcp targets")
+	fs.StringVar(&tlsKey, "tls-key", "", "client key for tcp targets")
+	fs.StringVar(&tlsCA, "tls-ca", "", "ca verifying tcp executor servers")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(targetsFile) > 0 {
+		lines, err := readTargets(targetsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		targets = append(targets, lines...)
+	}
+	if fs.NArg() == 0 || len(targets) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	f := &client.Fanout{
+		Targets:     targets,
+		Concurrency: concurrency,
+		Timeout:     timeout,
+		FailFast:    failFast,
+		Dir:         dir,
+	}
+	if len(env) > 0 {
+		f.Env = env
+		f.EnvMode = apis.EnvMode_ENV_INHERIT_OVERRIDE
+	}
+	if len(tlsCert) > 0 || len(tlsKey) > 0 || len(tlsCA) > 0 {
+		creds, err := client.LoadTLSCredentials(tlsCert, tlsKey, tlsCA)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		f.Options = append(f.Options, client.WithTransportCredentials(creds))
+	}
+	if sendStdin {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		f.Stdin = data
+	}
+	var out *prefixOutput
+	if !asJSON {
+		out = newPrefixOutput()
+		f.Output = out.write
+	}
+
+	// interrupt kills commands on all hosts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	results := f.Run(ctx, fs.Arg(0), fs.Args()[1:]...)
+	ret := 0
+	for _, res := range results {
+		if res.Failed() {
+			ret = 1
+		}
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return ret
+	}
+	out.flush()
+	for _, res := range results {
+		fmt.Fprintf(os.Stderr, "%s: %s (%dms)\n", res.Target, hostStatus(res), res.DurationMs)
+	}
+	return ret
+}
+
+func hostStatus(res *client.HostResult) string {
+	switch {
+	case res.Error != "":
+		return res.Error
+	case res.Signal != 0:
+		return fmt.Sprintf("killed by signal %d", res.Signal)
+	default:
+		return fmt.Sprintf("exit %d", res.ExitCode)
+	}
+}
+
+func readTargets(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var targets []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// prefixOutput prints output of hosts line by line prefixed with the
+// target, so lines of different hosts don't interleave
+type prefixOutput struct {
+	lock    sync.Mutex
+	pending map[string]*bytes.Buffer
+}
+
+func newPrefixOutput() *prefixOutput {
+	return &prefixOutput{pending: make(map[string]*bytes.Buffer)}
+}
+
+func (o *prefixOutput) writer(stream string) io.Writer {
+	if stream == client.StreamStderr {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func (o *prefixOutput) write(target, stream string, data []byte) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	key := target + "\x00" + stream
+	buf, ok := o.pending[key]
+	if !ok {
+		buf = new(bytes.Buffer)
+		o.pending[key] = buf
+	}
+	buf.Write(data)
+	for {
+		i := bytes.IndexByte(buf.Bytes(), '\n')
+		if i < 0 {
+			return
+		}
+		fmt.Fprintf(o.writer(stream), "%s: %s", target, buf.Next(i+1))
+	}
+}
+
+// flush prints incomplete last lines
+func (o *prefixOutput) flush() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for key, buf := range o.pending {
+		if buf.Len() > 0 {
+			parts := strings.SplitN(key, "\x00", 2)
+			fmt.Fprintf(o.writer(parts[1]), "%s: %s\n", parts[0], buf.Bytes())
+		}
+	}
+}
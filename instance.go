@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"yunion.io/x/log"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+const (
+	instanceProbeTimeout = 3 * time.Second
+	// time the previous server has to exit on takeover before SIGKILL
+	takeoverTimeout = 10 * time.Second
+)
+
+// instanceLock is held by the server owning the socket for its
+// lifetime, it contains the pid of the server
+type instanceLock struct {
+	file *os.File
+}
+
+func lockFilePath() string {
+	return socketPath + ".lock"
+}
+
+func (l *instanceLock) tryLock() (bool, error) {
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *instanceLock) holderPid() int {
+	data, err := ioutil.ReadFile(l.file.Name())
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+func (l *instanceLock) writePid() error {
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := l.file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
+
+// probeInstance asks the server listening on the socket for its info,
+// live tells whether anything accepts connections on it
+func probeInstance() (info *apis.InfoResponse, live bool) {
+	conn, err := net.DialTimeout("unix", socketPath, instanceProbeTimeout)
+	if err != nil {
+		return nil, false
+	}
+	conn.Close()
+	e, err := client.New("unix:"+socketPath, client.WithTimeout(instanceProbeTimeout))
+	if err != nil {
+		return nil, true
+	}
+	defer e.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), instanceProbeTimeout)
+	defer cancel()
+	info, err = e.Info(ctx)
+	if err != nil {
+		log.Warningf("server on %s not answering: %s", socketPath, err)
+		return nil, true
+	}
+	return info, true
+}
+
+func processExists(pid int) bool {
+	return syscall.Kill(pid, 0) != syscall.ESRCH
+}
+
+// stopInstance terminates the server pid, killing it if it doesn't
+// exit in takeoverTimeout
+func stopInstance(pid int) error {
+	if pid <= 0 || pid == os.Getpid() {
+		return errors.Errorf("invalid server pid %d", pid)
+	}
+	log.Warningf("take over from server pid %d, terminating it", pid)
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		if err == syscall.ESRCH {
+			return nil
+		}
+		return errors.Wrapf(err, "terminate server pid %d", pid)
+	}
+	deadline := time.Now().Add(takeoverTimeout)
+	for processExists(pid) {
+		if time.Now().After(deadline) {
+			log.Warningf("server pid %d not terminated in %s, killed", pid, takeoverTimeout)
+			syscall.Kill(pid, syscall.SIGKILL)
+			deadline = time.Now().Add(takeoverTimeout)
+			for processExists(pid) {
+				if time.Now().After(deadline) {
+					return errors.Errorf("server pid %d not exited after SIGKILL", pid)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// acquireSocket makes this server the single one on the socket path,
+// another live server is an error unless takeover, a stale socket of
+// a dead one is removed
+func acquireSocket(takeover bool) (*instanceLock, error) {
+	file, err := os.OpenFile(lockFilePath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open lock file")
+	}
+	lock := &instanceLock{file: file}
+	locked, err := lock.tryLock()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "lock file")
+	}
+
+	// servers before the lock file existed only show up by the probe
+	info, live := probeInstance()
+	pid := 0
+	switch {
+	case info != nil:
+		pid = int(info.Pid)
+		if !takeover {
+			file.Close()
+			return nil, errors.Errorf("executor %s pid %d with %d running commands is serving %s, use -takeover to replace it",
+				info.Version, info.Pid, info.Running, socketPath)
+		}
+	case live || !locked:
+		if !locked {
+			pid = lock.holderPid()
+		}
+		if !takeover {
+			file.Close()
+			return nil, errors.Errorf("server pid %d holds %s but doesn't answer, use -takeover to replace it",
+				pid, lockFilePath())
+		}
+		if pid == 0 {
+			file.Close()
+			return nil, errors.Errorf("unknown pid of server on %s", socketPath)
+		}
+	}
+	if pid > 0 {
+		if err := stopInstance(pid); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if !locked {
+		// released when the previous server exited
+		if locked, err = lock.tryLock(); !locked {
+			file.Close()
+			return nil, errors.Errorf("lock file still held: %v", err)
+		}
+	}
+	if err := lock.writePid(); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "write lock file")
+	}
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		log.Infof("remove stale socket %s", socketPath)
+		if err := os.Remove(socketPath); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return lock, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"yunion.io/x/executor/apis"
+)
+
+// infoServer answers Info as a server of pid
+type infoServer struct {
+	apis.UnimplementedExecutorServer
+	pid int
+}
+
+func (s *infoServer) Info(ctx context.Context, _ *apis.Empty) (*apis.InfoResponse, error) {
+	return &apis.InfoResponse{Pid: int32(s.pid), Version: "test"}, nil
+}
+
+func withSocketPath(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := socketPath
+	socketPath = filepath.Join(dir, "exec.sock")
+	return func() {
+		socketPath = saved
+		os.RemoveAll(dir)
+	}
+}
+
+func serveInfo(t *testing.T, pid int) func() {
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	apis.RegisterExecutorServer(srv, &infoServer{pid: pid})
+	go srv.Serve(lis)
+	return srv.Stop
+}
+
+func TestAcquireSocketStale(t *testing.T) {
+	defer withSocketPath(t)()
+	// a socket left by a dead server
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	lis.Close()
+
+	lock, err := acquireSocket(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.file.Close()
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("stale socket kept: %v", err)
+	}
+	if pid := lock.holderPid(); pid != os.Getpid() {
+		t.Errorf("pid in lock file: got %d, want %d", pid, os.Getpid())
+	}
+
+	// the lock is held, the holder doesn't answer
+	_, err = acquireSocket(false)
+	if err == nil || !strings.Contains(err.Error(), "holds") {
+		t.Errorf("second server: got %v", err)
+	}
+}
+
+func TestAcquireSocketLive(t *testing.T) {
+	defer withSocketPath(t)()
+	old := exec.Command("/bin/sleep", "60")
+	if err := old.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- old.Wait() }()
+	defer old.Process.Kill()
+	stop := serveInfo(t, old.Process.Pid)
+	defer stop()
+
+	_, err := acquireSocket(false)
+	if err == nil || !strings.Contains(err.Error(), "pid "+strconv.Itoa(old.Process.Pid)) {
+		t.Fatalf("live server: got %v", err)
+	}
+	select {
+	case <-exited:
+		t.Fatal("live server terminated without takeover")
+	default:
+	}
+
+	lock, err := acquireSocket(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.file.Close()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Errorf("previous server still running after takeover")
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket of previous server kept: %v", err)
+	}
+}
+
+func TestStopInstanceInvalid(t *testing.T) {
+	for _, pid := range []int{0, -1, os.Getpid()} {
+		if err := stopInstance(pid); err == nil {
+			t.Errorf("stopInstance(%d) succeeded", pid)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package chunk recycles the read buffers of server and client streams
+package chunk
+
+import (
+	"sync"
+)
+
+// pools recycle read buffers by size
+var pools sync.Map
+
+func pool(size int) *sync.Pool {
+	if p, ok := pools.Load(size); ok {
+		return p.(*sync.Pool)
+	}
+	p, _ := pools.LoadOrStore(size, &sync.Pool{
+		New: func() interface{} {
+			return make([]byte, size)
+		},
+	})
+	return p.(*sync.Pool)
+}
+
+// Get returns a buffer of size bytes
+func Get(size int) []byte {
+	return pool(size).Get().([]byte)
+}
+
+// Put recycles data, nothing may refer to it afterwards
+func Put(data []byte) {
+	pool(cap(data)).Put(data[:cap(data)])
+}
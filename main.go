@@ -4,19 +4,77 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/util/signalutils"
 	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/server"
 )
 
 var isServer bool
 var socketPath string
+var metricsAddr string
+var metricsCommands string
+var injectTraceEnv bool
+var defaultEnv envFlag
+var defaultEnvFile string
+var loginProfile string
+var listenAddr string
+var tlsCert string
+var tlsKey string
+var tlsClientCA string
+var serviceLogDir string
+var seccompProfiles string
+var takeover bool
+var chunkSize int
+var coalesceDelay time.Duration
+var maxBuffered int
+var stallTimeout time.Duration
+
+// upgradeRequests are sent on SIGUSR2
+var upgradeRequests = make(chan struct{}, 1)
+
+type envFlag []string
+
+func (f *envFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *envFlag) Set(kv string) error {
+	*f = append(*f, kv)
+	return nil
+}
 
 func init() {
 	flag.BoolVar(&isServer, "is-server", false, "execute server")
 	flag.StringVar(&socketPath, "socket-path", "/var/run/exec.sock", "execute service listen socket path")
+	flag.BoolVar(&injectTraceEnv, "inject-trace-env", false, "export caller trace context to commands as TRACEPARENT and EXECUTOR_REQUEST_ID")
+	flag.Var(&defaultEnv, "default-env", "KEY=VALUE merged into command env, can be repeated")
+	flag.StringVar(&defaultEnvFile, "default-env-file", "", "file of KEY=VALUE lines merged into command env")
+	flag.StringVar(&loginProfile, "login-profile", "/etc/profile", "profile sourced for commands in login env mode")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "serve prometheus metrics on this address, host:port or unix:/path/to/socket, disabled if empty")
+	flag.StringVar(&metricsCommands, "metrics-commands", "", "comma separated executable names labelled in metrics, others are counted as other, the first 64 names seen if empty")
+	flag.StringVar(&listenAddr, "listen-addr", "", "also serve on tcp host:port, requires -tls-cert, -tls-key and -tls-client-ca")
+	flag.StringVar(&tlsCert, "tls-cert", "", "server certificate of tcp listener")
+	flag.StringVar(&tlsKey, "tls-key", "", "server key of tcp listener")
+	flag.StringVar(&tlsClientCA, "tls-client-ca", "", "ca verifying client certificates of tcp listener")
+	flag.StringVar(&serviceLogDir, "service-log-dir", server.DefaultServiceLogDir, "directory of rotating logs of supervised services")
+	flag.StringVar(&seccompProfiles, "seccomp-profiles", "", "json file of named seccomp profiles, in addition to no-network and read-only-fs-syscalls")
+	flag.BoolVar(&takeover, "takeover", false, "terminate another server owning the socket path instead of refusing to start")
+	flag.IntVar(&chunkSize, "chunk-size", apis.DefaultChunkSize, "max bytes of an output message")
+	flag.DurationVar(&coalesceDelay, "coalesce-delay", 0, "hold output after a short read up to this delay to fill a message, e.g. 2ms, 0 sends output at once")
+	flag.IntVar(&maxBuffered, "max-buffered", apis.DefaultMaxBuffered, "max bytes of a command buffered for its stdin, stdout and stderr, a third each")
+	flag.DurationVar(&stallTimeout, "stall-timeout", 0, "server closes output of commands whose client acknowledged nothing for so long, client kills commands which read none of stdin for so long, 0 waits forever")
+}
+
+// setup parses flags and prepares the process, it runs in main
+// instead of init so package tests get their own flags
+func setup() {
 	flag.Parse()
 
 	var err error
@@ -33,10 +91,20 @@ func init() {
 		log.Errorln("ALL GO ROUTINE STACK")
 		utils.DumpAllGoroutineStack(log.Logger().Out)
 	}, syscall.SIGUSR1)
+	if isServer {
+		// upgrades once the server is up
+		signalutils.RegisterSignal(func() {
+			select {
+			case upgradeRequests <- struct{}{}:
+			default:
+			}
+		}, syscall.SIGUSR2)
+	}
 	signalutils.StartTrap()
 }
 
 func main() {
+	setup()
 	if isServer {
 		Server()
 	} else {
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"yunion.io/x/log"
 
 	"yunion.io/x/executor/apis"
@@ -13,8 +23,25 @@ import (
 )
 
 type SExecuteService struct {
+	defaultEnv      []string
+	tlsCreds        credentials.TransportCredentials
+	seccompProfiles map[string]*server.SeccompProfile
+	instance        *instanceLock
+	// handoff is set when exec'ed by an upgrade of the previous server
+	handoff *server.Handoff
+
+	lock        sync.Mutex
+	listener    net.Listener
+	tcpListener net.Listener
 }
 
+// names of files handed over on upgrade
+const (
+	handoffListener    = "listener"
+	handoffTCPListener = "tcp-listener"
+	handoffLock        = "lock"
+)
+
 func NewExecuteService() *SExecuteService {
 	return &SExecuteService{}
 }
@@ -35,24 +62,141 @@ func (s *SExecuteService) prepareEnv() error {
 	if err := s.fixPathEnv(); err != nil {
 		return err
 	}
+	if len(defaultEnvFile) > 0 {
+		env, err := server.ParseEnvFile(defaultEnvFile)
+		if err != nil {
+			return err
+		}
+		s.defaultEnv = append(s.defaultEnv, env...)
+	}
+	s.defaultEnv = server.MergeEnv(s.defaultEnv, defaultEnv)
+	if len(seccompProfiles) > 0 {
+		profiles, err := server.LoadSeccompProfiles(seccompProfiles)
+		if err != nil {
+			return err
+		}
+		s.seccompProfiles = profiles
+	}
 	return nil
 }
 
-func (s *SExecuteService) runService() {
-	grpcServer := grpc.NewServer()
-	apis.RegisterExecutorServer(grpcServer, &server.Executor{})
-	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
-		// socket file already exist, remove first
-		if err := os.Remove(socketPath); err != nil {
-			log.Fatalln(err)
+func (s *SExecuteService) metricsListener() (net.Listener, error) {
+	if strings.HasPrefix(metricsAddr, "unix:") {
+		path := strings.TrimPrefix(metricsAddr, "unix:")
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
 		}
+		return net.Listen("unix", path)
 	}
+	return net.Listen("tcp", metricsAddr)
+}
 
-	listener, err := net.Listen("unix", socketPath)
+func (s *SExecuteService) runMetrics() {
+	listener, err := s.metricsListener()
+	if err != nil {
+		log.Fatalf("failed listen metrics on %s: %s", metricsAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server.MetricsHandler())
+	log.Infof("Serve metrics on %s", metricsAddr)
+	if err := http.Serve(listener, mux); err != nil {
+		log.Errorf("metrics server: %s", err)
+	}
+}
+
+func (s *SExecuteService) newGrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(server.MetricsUnaryInterceptor),
+		grpc.StreamInterceptor(server.MetricsStreamInterceptor),
+		grpc.MaxRecvMsgSize(apis.MaxMessageSize),
+		grpc.MaxSendMsgSize(apis.MaxMessageSize),
+		// clients share long lived connections, allow them to keep alive
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	}, opts...)
+	grpcServer := grpc.NewServer(opts...)
+	apis.RegisterExecutorServer(grpcServer, &server.Executor{
+		InjectTraceEnv:  injectTraceEnv,
+		DefaultEnv:      s.defaultEnv,
+		LoginProfile:    loginProfile,
+		ServiceLogDir:   serviceLogDir,
+		SeccompProfiles: s.seccompProfiles,
+		ChunkSize:       chunkSize,
+		CoalesceDelay:   coalesceDelay,
+		MaxBuffered:     maxBuffered,
+		StallTimeout:    stallTimeout,
+	})
+	return grpcServer
+}
+
+// tlsCredentials requires clients presenting a certificate signed by
+// the client ca, tcp listener executes commands for anyone reaching it
+func (s *SExecuteService) tlsCredentials() (credentials.TransportCredentials, error) {
+	if len(tlsCert) == 0 || len(tlsKey) == 0 || len(tlsClientCA) == 0 {
+		return nil, errors.New("tcp listener requires -tls-cert, -tls-key and -tls-client-ca")
+	}
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
 	if err != nil {
+		return nil, errors.Wrap(err, "load server certificate")
+	}
+	ca, err := ioutil.ReadFile(tlsClientCA)
+	if err != nil {
+		return nil, errors.Wrap(err, "read client ca")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("no certificate found in %s", tlsClientCA)
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// listen takes over the listener of the previous server on upgrade
+func (s *SExecuteService) listen(network, addr, name string) (net.Listener, error) {
+	if s.handoff != nil {
+		if f := s.handoff.File(name); f != nil {
+			defer f.Close()
+			return net.FileListener(f)
+		}
+	}
+	return net.Listen(network, addr)
+}
+
+func (s *SExecuteService) runTCPService() {
+	listener, err := s.listen("tcp", listenAddr, handoffTCPListener)
+	if err != nil {
+		log.Fatalf("failed listen on %s: %s", listenAddr, err)
+	}
+	defer listener.Close()
+	s.lock.Lock()
+	s.tcpListener = listener
+	s.lock.Unlock()
+	log.Infof("Init tls listener on %s succ", listenAddr)
+	if err := s.newGrpcServer(grpc.Creds(s.tlsCreds)).Serve(listener); err != nil {
 		log.Fatalln(err)
 	}
+}
+
+func (s *SExecuteService) runService() {
+	grpcServer := s.newGrpcServer()
+	l, err := s.listen("unix", socketPath, handoffListener)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// clients pass files to commands on the same socket
+	listener := server.NewFilesListener(l)
 	defer listener.Close()
+	s.lock.Lock()
+	s.listener = listener
+	s.lock.Unlock()
 	log.Infof("Init net listener on %s succ", socketPath)
 	err = grpcServer.Serve(listener)
 	if err != nil {
@@ -64,13 +208,117 @@ func (s *SExecuteService) initService() {
 	if len(socketPath) == 0 {
 		log.Fatalf("missing socket path")
 	}
+	if chunkSize < 512 || chunkSize > apis.MaxChunkSize {
+		log.Fatalf("chunk size must be within 512 and %d", apis.MaxChunkSize)
+	}
+	if maxBuffered < 3*512 {
+		log.Fatalf("max buffered must be at least %d", 3*512)
+	}
 	if err := s.prepareEnv(); err != nil {
 		log.Fatalln(err)
 	}
+	handoff, err := server.RestoreHandoff()
+	if err != nil {
+		log.Fatalf("restore upgrade: %s", err)
+	}
+	if handoff != nil {
+		lock := handoff.File(handoffLock)
+		if lock == nil {
+			log.Fatalf("lock file not handed over on upgrade")
+		}
+		s.handoff = handoff
+		s.instance = &instanceLock{file: lock}
+	} else {
+		instance, err := acquireSocket(takeover)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		s.instance = instance
+	}
+	if len(listenAddr) > 0 {
+		creds, err := s.tlsCredentials()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		s.tlsCreds = creds
+	}
+}
+
+// upgrade execs the server binary in place, the new binary takes over
+// listeners, commands and services, it returns only on failure
+func (s *SExecuteService) upgrade() {
+	path, err := os.Executable()
+	if err != nil {
+		log.Errorf("upgrade: %s", err)
+		return
+	}
+	log.Infof("Upgrade to %s", path)
+	h, err := server.PrepareHandoff()
+	if err != nil {
+		log.Errorf("upgrade: %s", err)
+		return
+	}
+	if err := s.handoffFiles(h); err != nil {
+		h.Abort()
+		log.Errorf("upgrade: %s", err)
+		return
+	}
+	err = h.Exec(path, os.Args)
+	log.Errorf("upgrade: %s", err)
+}
+
+func (s *SExecuteService) handoffFiles(h *server.Handoff) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listener == nil {
+		return errors.New("not serving yet")
+	}
+	listeners := []struct {
+		name     string
+		listener net.Listener
+	}{
+		{handoffListener, s.listener},
+		{handoffTCPListener, s.tcpListener},
+	}
+	for _, l := range listeners {
+		if l.listener == nil {
+			continue
+		}
+		fl, ok := l.listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return errors.Errorf("%s can't be handed over", l.name)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return errors.Wrap(err, l.name)
+		}
+		if err := h.AddFile(l.name, f); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	// a dup holds the same lock, closed if the upgrade fails
+	fd, err := syscall.Dup(int(s.instance.file.Fd()))
+	if err != nil {
+		return errors.Wrap(err, "dup lock file")
+	}
+	return h.AddFile(handoffLock, os.NewFile(uintptr(fd), s.instance.file.Name()))
 }
 
 func (s *SExecuteService) Run() {
 	s.initService()
+	go func() {
+		for range upgradeRequests {
+			s.upgrade()
+		}
+	}()
+	if len(metricsAddr) > 0 {
+		server.SetMetricsCommands(splitList(metricsCommands))
+		go s.runMetrics()
+	}
+	if len(listenAddr) > 0 {
+		go s.runTCPService()
+	}
 	s.runService()
 }
 
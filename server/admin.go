@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/log"
+)
+
+// Version is reported by Info, set at build time with
+// -ldflags "-X yunion.io/x/executor/server.Version=..."
+var Version = "dev"
+
+var serverStartedAt = time.Now()
+
+// frames buffered for a slow attached client before dropping output
+const watcherBuffer = 256
+
+type watcher struct {
+	ch      chan *apis.ExecResponse
+	dropped int
+}
+
+// watch registers a watcher of output, nil if command has exited
+func (m *Commander) watch() *watcher {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.exitRes != nil {
+		return nil
+	}
+	if m.watchers == nil {
+		m.watchers = make(map[*watcher]struct{})
+	}
+	w := &watcher{ch: make(chan *apis.ExecResponse, watcherBuffer)}
+	m.watchers[w] = struct{}{}
+	return w
+}
+
+func (m *Commander) unwatch(w *watcher) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.watchers[w]; ok {
+		delete(m.watchers, w)
+		close(w.ch)
+	}
+}
+
+// broadcast copies an output frame to watchers, never blocks the
+// command's own stream
+func (m *Commander) broadcast(res *apis.ExecResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(m.watchers) == 0 {
+		return
+	}
+	var frame *apis.ExecResponse
+	switch r := res.Response.(type) {
+	case *apis.ExecResponse_Stdout:
+		frame = stdoutFrame(append([]byte(nil), r.Stdout...))
+	case *apis.ExecResponse_Stderr:
+		frame = stderrFrame(append([]byte(nil), r.Stderr...))
+	default:
+		return
+	}
+	for w := range m.watchers {
+		select {
+		case w.ch <- frame:
+		default:
+			w.dropped++
+		}
+	}
+}
+
+func (m *Commander) closeWatchers(res *apis.WaitResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.exitRes = res
+	for w := range m.watchers {
+		close(w.ch)
+	}
+	m.watchers = nil
+}
+
+func (m *Commander) info() *apis.ProcessInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	path, args := m.c.Path, m.c.Args
+	if m.sandbox != nil && len(m.sandbox.Path) > 0 {
+		// started through the sandbox helper
+		path, args = m.sandbox.Path, m.sandbox.Args
+	}
+	info := &apis.ProcessInfo{
+		Sn:        m.sn,
+		Pid:       int32(m.pid),
+		Path:      []byte(path),
+		RequestId: m.trace.RequestId,
+	}
+	if len(args) > 1 {
+		info.Args = strArrayToBytesArray(args[1:])
+	}
+	if !m.startedAt.IsZero() {
+		info.StartedAt = m.startedAt.Unix()
+	}
+	return info
+}
+
+func strArrayToBytesArray(sa []string) [][]byte {
+	res := make([][]byte, len(sa))
+	for i := range sa {
+		res[i] = []byte(sa[i])
+	}
+	return res
+}
+
+func loadCommander(sn uint32) (*Commander, error) {
+	icm, ok := cmds.Load(sn)
+	if !ok {
+		return nil, errors.Errorf("unknown sn %d", sn)
+	}
+	return icm.(*Commander), nil
+}
+
+func (e *Executor) List(ctx context.Context, _ *apis.Empty) (*apis.ListResponse, error) {
+	res := &apis.ListResponse{}
+	cmds.Range(func(key, value interface{}) bool {
+		res.Processes = append(res.Processes, value.(*Commander).info())
+		return true
+	})
+	return res, nil
+}
+
+func (e *Executor) Info(ctx context.Context, _ *apis.Empty) (*apis.InfoResponse, error) {
+	return &apis.InfoResponse{
+		Version:   Version,
+		Pid:       int32(os.Getpid()),
+		StartedAt: serverStartedAt.Unix(),
+		Running:   uint32(Len(cmds)),
+		GoVersion: runtime.Version(),
+	}, nil
+}
+
+func (e *Executor) Signal(ctx context.Context, req *apis.SignalRequest) (*apis.Error, error) {
+	m, err := loadCommander(req.Sn)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	started := m.pid != 0
+	m.lock.Unlock()
+	if !started {
+		return &apis.Error{Error: []byte("process not started")}, nil
+	}
+	log.Infof("%d Signal %d%s", req.Sn, req.Signal, TraceContextFromIncoming(ctx))
+	if err := m.c.Process.Signal(syscall.Signal(req.Signal)); err != nil {
+		return &apis.Error{Error: []byte(err.Error())}, nil
+	}
+	return &apis.Error{}, nil
+}
+
+func (e *Executor) Attach(sn *apis.Sn, s apis.Executor_AttachServer) error {
+	m, err := loadCommander(sn.Sn)
+	if err != nil {
+		return err
+	}
+	w := m.watch()
+	if w == nil {
+		return errors.Errorf("sn %d exited", sn.Sn)
+	}
+	defer m.unwatch(w)
+	log.Infof("%d Attached%s", sn.Sn, TraceContextFromIncoming(s.Context()))
+	// headers tell the client frames from now on are watched
+	if err := s.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	var sendLock sync.Mutex
+	send := func(res *apis.ExecResponse) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return s.Send(res)
+	}
+	defer noticeHandoff(send)()
+
+	for {
+		select {
+		case frame, ok := <-w.ch:
+			if !ok {
+				if w.dropped > 0 {
+					log.Warningf("%d attach dropped %d frames", sn.Sn, w.dropped)
+				}
+				m.lock.Lock()
+				res := m.exitRes
+				m.lock.Unlock()
+				if res == nil {
+					return nil
+				}
+				return send(&apis.ExecResponse{
+					Response: &apis.ExecResponse_Exit{Exit: res},
+				})
+			}
+			if err := send(frame); err != nil {
+				return err
+			}
+		case <-s.Context().Done():
+			return s.Context().Err()
+		}
+	}
+}
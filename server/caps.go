@@ -0,0 +1,152 @@
+package server
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// capability numbers of linux/capability.h
+var capNames = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_DAC_READ_SEARCH",
+	"CAP_FOWNER",
+	"CAP_FSETID",
+	"CAP_KILL",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETPCAP",
+	"CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN",
+	"CAP_NET_RAW",
+	"CAP_IPC_LOCK",
+	"CAP_IPC_OWNER",
+	"CAP_SYS_MODULE",
+	"CAP_SYS_RAWIO",
+	"CAP_SYS_CHROOT",
+	"CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT",
+	"CAP_SYS_ADMIN",
+	"CAP_SYS_BOOT",
+	"CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME",
+	"CAP_SYS_TTY_CONFIG",
+	"CAP_MKNOD",
+	"CAP_LEASE",
+	"CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL",
+	"CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE",
+	"CAP_MAC_ADMIN",
+	"CAP_SYSLOG",
+	"CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND",
+	"CAP_AUDIT_READ",
+	"CAP_PERFMON",
+	"CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+func parseCap(name string) (uint, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "CAP_") {
+		name = "CAP_" + name
+	}
+	for i, n := range capNames {
+		if n == name {
+			return uint(i), nil
+		}
+	}
+	return 0, errors.Errorf("unknown capability %s", name)
+}
+
+func parseCapMask(names []string) (uint64, error) {
+	var mask uint64
+	for _, name := range names {
+		c, err := parseCap(name)
+		if err != nil {
+			return 0, err
+		}
+		mask |= 1 << c
+	}
+	return mask, nil
+}
+
+func capMaskNames(mask uint64) []string {
+	var names []string
+	for i := uint(0); i < 64; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		if int(i) < len(capNames) {
+			names = append(names, capNames[i])
+		} else {
+			names = append(names, "CAP_"+strconv.Itoa(int(i)))
+		}
+	}
+	return names
+}
+
+// capLastCap is the highest capability of the running kernel
+func capLastCap() uint {
+	data, err := ioutil.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			return uint(n)
+		}
+	}
+	return uint(len(capNames) - 1)
+}
+
+// capsSpec resolves capability settings to the sets of the helper,
+// root gets the bounding set on exec, other users the ambient set
+func capsSpec(in *apis.Capabilities, spec *sandboxSpec) error {
+	keep, err := parseCapMask(in.Keep)
+	if err != nil {
+		return err
+	}
+	drop, err := parseCapMask(in.Drop)
+	if err != nil {
+		return err
+	}
+	ambient, err := parseCapMask(in.Ambient)
+	if err != nil {
+		return err
+	}
+	if ambient&drop != 0 {
+		return errors.Errorf("ambient %s dropped", strings.Join(capMaskNames(ambient&drop), ","))
+	}
+
+	var all uint64
+	for c := uint(0); c <= capLastCap(); c++ {
+		all |= 1 << c
+	}
+	bounding := all
+	if len(in.Keep) > 0 {
+		bounding = keep | ambient
+	}
+	bounding &^= drop
+	for c := uint(0); c <= capLastCap(); c++ {
+		if all&^bounding&(1<<c) != 0 {
+			spec.DropBounding = append(spec.DropBounding, c)
+		}
+	}
+	spec.Caps = &capSets{
+		Permitted:   bounding,
+		Inheritable: ambient,
+	}
+	for c := uint(0); c < 64; c++ {
+		if ambient&(1<<c) != 0 {
+			spec.Caps.Ambient = append(spec.Caps.Ambient, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestParseCap(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		want uint
+	}{
+		{"CAP_CHOWN", 0},
+		{"net_raw", 13},
+		{"cap_sys_admin", 21},
+		{"CHECKPOINT_RESTORE", 40},
+	} {
+		got, err := parseCap(c.name)
+		if err != nil || got != c.want {
+			t.Errorf("parseCap(%s): got %d %v, want %d", c.name, got, err, c.want)
+		}
+	}
+	if _, err := parseCap("CAP_FLY"); err == nil {
+		t.Errorf("unknown capability parsed")
+	}
+	if _, err := parseCapMask([]string{"chown", "nope"}); err == nil {
+		t.Errorf("mask with unknown capability parsed")
+	}
+	if got, want := capMaskNames(1<<13|1<<0|1<<63), []string{"CAP_CHOWN", "CAP_NET_RAW", "CAP_63"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("capMaskNames: got %v, want %v", got, want)
+	}
+}
+
+func capBits(caps ...uint) uint64 {
+	var mask uint64
+	for _, c := range caps {
+		mask |= 1 << c
+	}
+	return mask
+}
+
+func TestCapsSpec(t *testing.T) {
+	last := capLastCap()
+	var all uint64
+	for c := uint(0); c <= last; c++ {
+		all |= 1 << c
+	}
+	const chown, netBind, netAdmin, netRaw = 0, 10, 12, 13
+
+	for _, c := range []struct {
+		name      string
+		in        *apis.Capabilities
+		permitted uint64
+		ambient   []uint
+	}{
+		{
+			name:      "drop",
+			in:        &apis.Capabilities{Drop: []string{"NET_RAW", "NET_ADMIN"}},
+			permitted: all &^ capBits(netRaw, netAdmin),
+		},
+		{
+			name:      "keep",
+			in:        &apis.Capabilities{Keep: []string{"CHOWN", "NET_RAW"}, Drop: []string{"NET_RAW"}},
+			permitted: capBits(chown),
+		},
+		{
+			name:      "ambient kept without keep",
+			in:        &apis.Capabilities{Keep: []string{"CHOWN"}, Ambient: []string{"NET_BIND_SERVICE"}},
+			permitted: capBits(chown, netBind),
+			ambient:   []uint{netBind},
+		},
+	} {
+		spec := &sandboxSpec{}
+		if err := capsSpec(c.in, spec); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if spec.Caps.Permitted != c.permitted {
+			t.Errorf("%s: permitted %v, want %v", c.name, capMaskNames(spec.Caps.Permitted), capMaskNames(c.permitted))
+		}
+		if spec.Caps.Inheritable != capBits(c.ambient...) || !reflect.DeepEqual(spec.Caps.Ambient, c.ambient) {
+			t.Errorf("%s: ambient %v inheritable %v, want %v", c.name, spec.Caps.Ambient, capMaskNames(spec.Caps.Inheritable), c.ambient)
+		}
+		// the bounding set drops exactly what isn't permitted
+		var dropped uint64
+		for _, cap := range spec.DropBounding {
+			dropped |= 1 << cap
+		}
+		if dropped != all&^c.permitted {
+			t.Errorf("%s: bounding dropped %v", c.name, capMaskNames(dropped))
+		}
+	}
+
+	for _, in := range []*apis.Capabilities{
+		{Drop: []string{"NET_RAW"}, Ambient: []string{"net_raw"}},
+		{Keep: []string{"FLY"}},
+		{Ambient: []string{"FLY"}},
+	} {
+		if err := capsSpec(in, &sandboxSpec{}); err == nil {
+			t.Errorf("capsSpec(%v) succeeded", in)
+		}
+	}
+}
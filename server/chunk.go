@@ -0,0 +1,36 @@
+package server
+
+import (
+	"os"
+	"time"
+
+	"yunion.io/x/executor/apis"
+)
+
+func (e *Executor) chunkSize() int {
+	if e.ChunkSize > 0 && e.ChunkSize <= apis.MaxChunkSize {
+		return e.ChunkSize
+	}
+	return apis.DefaultChunkSize
+}
+
+// coalesce keeps reading f after a short read of n bytes into data,
+// until data is full or delay passed, to send fewer larger messages
+func coalesce(f *os.File, data []byte, n int, delay time.Duration) (int, error) {
+	var err error
+	f.SetReadDeadline(time.Now().Add(delay))
+	for n < len(data) && err == nil {
+		var m int
+		m, err = f.Read(data[n:])
+		n += m
+	}
+	f.SetReadDeadline(time.Time{})
+	if gate.isClosing() {
+		// a handoff may have set the deadline cleared above
+		f.SetReadDeadline(time.Now())
+	}
+	if os.IsTimeout(err) {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+)
+
+const (
+	// how long a request id is remembered, longer than any client retry
+	dedupTTL = 10 * time.Minute
+	// how often expired request ids are forgotten
+	dedupSweepInterval = time.Minute
+)
+
+type dedupEntry struct {
+	// sn is 0 while the command is being created
+	sn uint32
+	at time.Time
+	// command the id was claimed for, a retry must repeat it
+	path []byte
+	args [][]byte
+}
+
+func newDedupEntry(req *apis.Command, at time.Time) *dedupEntry {
+	return &dedupEntry{at: at, path: req.Path, args: req.Args}
+}
+
+// matches tells whether req is the command the entry was claimed for
+func (ent *dedupEntry) matches(req *apis.Command) bool {
+	if !bytes.Equal(ent.path, req.Path) || len(ent.args) != len(req.Args) {
+		return false
+	}
+	for i := range ent.args {
+		if !bytes.Equal(ent.args[i], req.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type dedupTable struct {
+	lock     sync.Mutex
+	requests map[string]*dedupEntry
+	// sweep is scheduled while requests is not empty
+	sweep *time.Timer
+}
+
+var requests = &dedupTable{requests: make(map[string]*dedupEntry)}
+
+// dedupIdFromIncoming returns the dedup id of ctx if caller asked for
+// deduplication, empty otherwise
+func dedupIdFromIncoming(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	return firstMetadata(md, apis.MetadataDedup)
+}
+
+// claim registers id for req, an earlier entry of the same id is
+// returned when it was already claimed
+func (t *dedupTable) claim(id string, req *apis.Command) (*dedupEntry, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := time.Now()
+	if prev, ok := t.requests[id]; ok && now.Sub(prev.at) <= dedupTTL {
+		ent := *prev
+		return &ent, true
+	}
+	t.requests[id] = newDedupEntry(req, now)
+	t.scheduleSweep()
+	return nil, false
+}
+
+// add keeps an entry handed over by the previous server
+func (t *dedupTable) add(id string, ent *dedupEntry) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.requests[id] = ent
+	t.scheduleSweep()
+}
+
+func (t *dedupTable) scheduleSweep() {
+	if t.sweep == nil {
+		t.sweep = time.AfterFunc(dedupSweepInterval, t.sweepExpired)
+	}
+}
+
+// sweepExpired forgets expired request ids, it runs until none is left
+func (t *dedupTable) sweepExpired() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := time.Now()
+	for k, v := range t.requests {
+		if now.Sub(v.at) > dedupTTL {
+			delete(t.requests, k)
+		}
+	}
+	t.sweep = nil
+	if len(t.requests) > 0 {
+		t.scheduleSweep()
+	}
+}
+
+// lookup returns sn of the command id was claimed for, if it is req
+func (t *dedupTable) lookup(id string, req *apis.Command) (uint32, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ent, ok := t.requests[id]
+	if !ok || ent.sn == 0 || time.Since(ent.at) > dedupTTL || !ent.matches(req) {
+		return 0, false
+	}
+	return ent.sn, true
+}
+
+func (t *dedupTable) set(id string, sn uint32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if ent, ok := t.requests[id]; ok {
+		ent.sn = sn
+	}
+}
+
+// release forgets id of a request whose command failed to be created
+// or started, so retries of it may run
+func (t *dedupTable) release(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.requests, id)
+}
+
+func duplicateRequestError(id string, prev *dedupEntry, req *apis.Command) error {
+	if !prev.matches(req) {
+		// not a retry, the id was reused by another command
+		return status.Errorf(codes.InvalidArgument, "request %s reused for another command", id)
+	}
+	if prev.sn == 0 {
+		// retryable, becomes AlreadyExists once created
+		return status.Errorf(codes.Aborted, "request %s in progress", id)
+	}
+	st := status.Newf(codes.AlreadyExists, "request %s already executed as %d", id, prev.sn)
+	// clients attach to the command by the sn in details
+	if withSn, err := st.WithDetails(&apis.Sn{Sn: prev.sn}); err == nil {
+		st = withSn
+	}
+	return st.Err()
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+)
+
+var (
+	dedupCmd  = &apis.Command{Path: []byte("/bin/echo"), Args: [][]byte{[]byte("a")}}
+	dedupCmd2 = &apis.Command{Path: []byte("/bin/echo"), Args: [][]byte{[]byte("b")}}
+)
+
+func TestDedupTable(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		// prepare runs on a table with "a" claimed
+		prepare func(tbl *dedupTable)
+		dup     bool
+		code    codes.Code
+	}{
+		{"claimed", func(tbl *dedupTable) {}, true, codes.Aborted},
+		{"created", func(tbl *dedupTable) { tbl.set("a", 7) }, true, codes.AlreadyExists},
+		{"released", func(tbl *dedupTable) { tbl.release("a") }, false, codes.OK},
+		{"created and released", func(tbl *dedupTable) { tbl.set("a", 7); tbl.release("a") }, false, codes.OK},
+		{"expired", func(tbl *dedupTable) {
+			tbl.set("a", 7)
+			tbl.requests["a"].at = time.Now().Add(-dedupTTL - time.Second)
+		}, false, codes.OK},
+		{"expired and swept", func(tbl *dedupTable) {
+			tbl.requests["a"].at = time.Now().Add(-dedupTTL - time.Second)
+			tbl.sweepExpired()
+		}, false, codes.OK},
+	} {
+		tbl := &dedupTable{requests: make(map[string]*dedupEntry)}
+		if _, dup := tbl.claim("a", dedupCmd); dup {
+			t.Fatalf("%s: first claim is duplicate", c.name)
+		}
+		c.prepare(tbl)
+		prev, dup := tbl.claim("a", dedupCmd)
+		if dup != c.dup {
+			t.Errorf("%s: duplicate %v, want %v", c.name, dup, c.dup)
+			continue
+		}
+		if !dup {
+			continue
+		}
+		err := duplicateRequestError("a", prev, dedupCmd)
+		if code := status.Code(err); code != c.code {
+			t.Errorf("%s: got %s, want %s", c.name, code, c.code)
+		}
+		if c.code == codes.AlreadyExists {
+			details := status.Convert(err).Details()
+			if len(details) != 1 || details[0].(*apis.Sn).Sn != 7 {
+				t.Errorf("%s: details %v, want sn 7", c.name, details)
+			}
+		}
+		if _, dup := tbl.claim("b", dedupCmd); dup {
+			t.Errorf("%s: other id is duplicate", c.name)
+		}
+	}
+}
+
+func TestDedupLookup(t *testing.T) {
+	tbl := &dedupTable{requests: make(map[string]*dedupEntry)}
+	tbl.claim("a", dedupCmd)
+	if _, ok := tbl.lookup("a", dedupCmd); ok {
+		t.Errorf("lookup of a command being created")
+	}
+	tbl.set("a", 3)
+	if sn, ok := tbl.lookup("a", dedupCmd); !ok || sn != 3 {
+		t.Errorf("lookup: got %d %v", sn, ok)
+	}
+	if _, ok := tbl.lookup("a", dedupCmd2); ok {
+		t.Errorf("lookup of another command")
+	}
+	tbl.requests["a"].at = time.Now().Add(-dedupTTL - time.Second)
+	if _, ok := tbl.lookup("a", dedupCmd); ok {
+		t.Errorf("lookup of an expired id")
+	}
+}
+
+func TestDedupOtherCommand(t *testing.T) {
+	tbl := &dedupTable{requests: make(map[string]*dedupEntry)}
+	tbl.claim("a", dedupCmd)
+	tbl.sweep.Stop()
+	tbl.set("a", 7)
+	for _, req := range []*apis.Command{
+		dedupCmd2,
+		{Path: []byte("/bin/ls"), Args: dedupCmd.Args},
+		{Path: dedupCmd.Path},
+	} {
+		prev, dup := tbl.claim("a", req)
+		if !dup {
+			t.Fatalf("%s: id not claimed", req)
+		}
+		if err := duplicateRequestError("a", prev, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", req, err)
+		}
+	}
+	// the entry is still the first command's
+	if sn, ok := tbl.lookup("a", dedupCmd); !ok || sn != 7 {
+		t.Errorf("lookup after reuse: got %d %v", sn, ok)
+	}
+}
+
+func TestDedupSweep(t *testing.T) {
+	tbl := &dedupTable{requests: make(map[string]*dedupEntry)}
+	tbl.claim("old", dedupCmd)
+	tbl.claim("new", dedupCmd)
+	if tbl.sweep == nil {
+		t.Fatal("sweep not scheduled")
+	}
+	tbl.sweep.Stop()
+	tbl.requests["old"].at = time.Now().Add(-dedupTTL - time.Second)
+	tbl.sweepExpired()
+	if _, ok := tbl.requests["old"]; ok {
+		t.Errorf("expired id kept")
+	}
+	if _, ok := tbl.requests["new"]; !ok {
+		t.Errorf("live id swept")
+	}
+	if tbl.sweep == nil {
+		t.Errorf("sweep not scheduled again with ids left")
+	}
+	tbl.sweep.Stop()
+	tbl.requests["new"].at = time.Now().Add(-dedupTTL - time.Second)
+	tbl.sweepExpired()
+	if len(tbl.requests) != 0 || tbl.sweep != nil {
+		t.Errorf("sweep scheduled on empty table")
+	}
+}
+
+func TestDedupReleasedOnStartFailure(t *testing.T) {
+	e := &Executor{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		apis.MetadataDedup, "start-failure",
+	))
+	missing := &apis.Command{Path: []byte("/nonexistent/command")}
+	m, err := e.newCommander(ctx, missing, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmds.Delete(m.sn)
+	if _, err := e.newCommander(ctx, missing, true, nil); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("duplicate before start: got %v, want AlreadyExists", err)
+	}
+	if _, err := e.newCommander(ctx, &apis.Command{Path: []byte("/bin/true")}, true, nil); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("other command before start: got %v, want InvalidArgument", err)
+	}
+	if err := m.start(&apis.StartInput{Sn: m.sn}); err == nil {
+		t.Fatal("missing command started")
+	}
+	m2, err := e.newCommander(ctx, &apis.Command{Path: []byte("/bin/true")}, true, nil)
+	if err != nil {
+		t.Fatalf("retry after start failure: %v", err)
+	}
+	cmds.Delete(m2.sn)
+	requests.release("start-failure")
+}
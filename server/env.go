@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+const systemEnvironmentFile = "/etc/environment"
+
+// MergeEnv merges KEY=VALUE lists, later values override earlier ones
+// and keys keep the position of their first appearance
+func MergeEnv(envs ...[]string) []string {
+	var (
+		res   []string
+		index = map[string]int{}
+	)
+	for _, env := range envs {
+		for _, kv := range env {
+			k := kv
+			if i := strings.IndexByte(kv, '='); i >= 0 {
+				k = kv[:i]
+			}
+			if i, ok := index[k]; ok {
+				res[i] = kv
+				continue
+			}
+			index[k] = len(res)
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+// ParseEnvFile reads KEY=VALUE lines in the format of /etc/environment,
+// blank lines, comments and an optional export prefix are accepted
+func ParseEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			continue
+		}
+		k, v := line[:i], line[i+1:]
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env = append(env, k+"="+v)
+	}
+	return env, scanner.Err()
+}
+
+type loginEnvCall struct {
+	done chan struct{}
+	env  []string
+	err  error
+}
+
+type loginEnvCache struct {
+	lock sync.Mutex
+	key  string
+	env  []string
+	// calls are profiles being sourced by key, callers of the same key
+	// wait for the one in flight
+	calls map[string]*loginEnvCall
+}
+
+var loginEnv = &loginEnvCache{}
+
+// variables of the shell sourcing the profile, not of the login env
+var shellOnlyEnv = map[string]bool{
+	"_":     true,
+	"SHLVL": true,
+	"PWD":   true,
+}
+
+func fileStamp(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return path + ":-"
+	}
+	return fmt.Sprintf("%s:%d:%d", path, fi.Size(), fi.ModTime().UnixNano())
+}
+
+// get returns /etc/environment overlaid by what the profile exports,
+// the result is cached until either file changes
+func (l *loginEnvCache) get(profile string) ([]string, error) {
+	key := fileStamp(systemEnvironmentFile)
+	if profile != "" {
+		key += ";" + fileStamp(profile)
+	}
+
+	l.lock.Lock()
+	if l.env != nil && l.key == key {
+		env := l.env
+		l.lock.Unlock()
+		return env, nil
+	}
+	if call, ok := l.calls[key]; ok {
+		l.lock.Unlock()
+		<-call.done
+		return call.env, call.err
+	}
+	if l.calls == nil {
+		l.calls = make(map[string]*loginEnvCall)
+	}
+	call := &loginEnvCall{done: make(chan struct{})}
+	l.calls[key] = call
+	l.lock.Unlock()
+
+	call.env, call.err = loadLoginEnv(profile)
+
+	l.lock.Lock()
+	delete(l.calls, key)
+	if call.err == nil {
+		l.key = key
+		l.env = call.env
+	}
+	l.lock.Unlock()
+	close(call.done)
+	return call.env, call.err
+}
+
+func loadLoginEnv(profile string) ([]string, error) {
+	env, err := ParseEnvFile(systemEnvironmentFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "parse %s", systemEnvironmentFile)
+	}
+	var base []string
+	for _, k := range []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL"} {
+		if v, ok := os.LookupEnv(k); ok {
+			base = append(base, k+"="+v)
+		}
+	}
+	env = MergeEnv(base, env)
+	if profile != "" {
+		if _, err := os.Stat(profile); err == nil {
+			env, err = sourceProfile(profile, env)
+			if err != nil {
+				return nil, errors.Wrapf(err, "source %s", profile)
+			}
+		}
+	}
+	return env, nil
+}
+
+func sourceProfile(profile string, env []string) ([]string, error) {
+	cmd := exec.Command("/bin/sh", "-c", `. "$0" >/dev/null 2>&1; exec env -0`, profile)
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, kv := range bytes.Split(out, []byte{0}) {
+		if len(kv) == 0 {
+			continue
+		}
+		k := kv
+		if i := bytes.IndexByte(kv, '='); i >= 0 {
+			k = kv[:i]
+		}
+		if shellOnlyEnv[string(k)] {
+			continue
+		}
+		res = append(res, string(kv))
+	}
+	return res, nil
+}
+
+// CommandEnv resolves the environment a command runs with according to
+// its env mode, nil means inheriting the server environment as is
+func (e *Executor) CommandEnv(in *apis.Command) ([]string, error) {
+	reqEnv := BytesArrayToStrArray(in.Env)
+	switch in.EnvMode {
+	case apis.EnvMode_ENV_DEFAULT:
+		if len(reqEnv) > 0 {
+			return reqEnv, nil
+		}
+		if len(e.DefaultEnv) == 0 {
+			return nil, nil
+		}
+		return MergeEnv(os.Environ(), e.DefaultEnv), nil
+	case apis.EnvMode_ENV_INHERIT:
+		return MergeEnv(os.Environ(), e.DefaultEnv), nil
+	case apis.EnvMode_ENV_INHERIT_OVERRIDE:
+		return MergeEnv(os.Environ(), e.DefaultEnv, reqEnv), nil
+	case apis.EnvMode_ENV_CLEAN:
+		// empty but not nil, exec.Cmd treats nil as inherit
+		return append([]string{}, reqEnv...), nil
+	case apis.EnvMode_ENV_LOGIN:
+		env, err := loginEnv.get(e.LoginProfile)
+		if err != nil {
+			return nil, errors.Wrap(err, "login env")
+		}
+		return MergeEnv(env, e.DefaultEnv, reqEnv), nil
+	default:
+		return nil, errors.Errorf("unknown env mode %d", in.EnvMode)
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestMergeEnv(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		envs [][]string
+		want []string
+	}{
+		{"empty", nil, nil},
+		{"override keeps position", [][]string{{"A=1", "B=2"}, {"C=3", "A=4"}}, []string{"A=4", "B=2", "C=3"}},
+		{"last wins", [][]string{{"A=1"}, {"A=2"}, {"A=3"}}, []string{"A=3"}},
+		{"duplicates in one list", [][]string{{"A=1", "A=2"}}, []string{"A=2"}},
+		{"empty value", [][]string{{"A=1"}, {"A="}}, []string{"A="}},
+		{"no equal sign", [][]string{{"A", "B=1"}, {"A=2"}}, []string{"A=2", "B=1"}},
+		{"value with equal sign", [][]string{{"A=x=y"}}, []string{"A=x=y"}},
+	} {
+		if got := MergeEnv(c.envs...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func writeTemp(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeTemp(t, dir, "environment", strings.Join([]string{
+		"# comment",
+		"",
+		"PATH=/usr/bin:/bin",
+		"  export LANG=C.UTF-8  ",
+		`QUOTED="a b"`,
+		`SINGLE='c d'`,
+		`MISMATCHED="e'`,
+		`ONE="`,
+		"EMPTY=",
+		"=novalue",
+		"garbage",
+		"EQ=x=y",
+	}, "\n"))
+	got, err := ParseEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"PATH=/usr/bin:/bin",
+		"LANG=C.UTF-8",
+		"QUOTED=a b",
+		"SINGLE=c d",
+		`MISMATCHED="e'`,
+		`ONE="`,
+		"EMPTY=",
+		"EQ=x=y",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := ParseEnvFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func lookupEnv(env []string, key string) (string, bool) {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return kv[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+func TestLoginEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	count := filepath.Join(dir, "count")
+	profile := writeTemp(t, dir, "profile", "echo x >>"+count+"\nsleep 0.2\nexport FROM_PROFILE=1\n")
+
+	l := &loginEnvCache{}
+	var wg sync.WaitGroup
+	envs := make([][]string, 8)
+	errs := make([]error, len(envs))
+	for i := range envs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			envs[i], errs[i] = l.get(profile)
+		}(i)
+	}
+	wg.Wait()
+	for i := range envs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if v, _ := lookupEnv(envs[i], "FROM_PROFILE"); v != "1" {
+			t.Errorf("get %d: FROM_PROFILE not exported: %q", i, envs[i])
+		}
+		for _, k := range []string{"_", "SHLVL", "PWD"} {
+			if v, ok := lookupEnv(envs[i], k); ok {
+				t.Errorf("get %d: shell variable %s=%s in login env", i, k, v)
+			}
+		}
+	}
+	data, err := ioutil.ReadFile(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("profile sourced %d times by concurrent gets, want 1", n)
+	}
+
+	// cached until the profile changes
+	if _, err := l.get(profile); err != nil {
+		t.Fatal(err)
+	}
+	writeTemp(t, dir, "profile", "echo x >>"+count+"\nexport FROM_PROFILE=2\n")
+	env, err := l.get(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := lookupEnv(env, "FROM_PROFILE"); v != "2" {
+		t.Errorf("changed profile not sourced: FROM_PROFILE=%q", v)
+	}
+	data, _ = ioutil.ReadFile(count)
+	if n := strings.Count(string(data), "x"); n != 2 {
+		t.Errorf("profile sourced %d times, want 2", n)
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	os.Setenv("EXECUTOR_TEST_SERVER", "server")
+	defer os.Unsetenv("EXECUTOR_TEST_SERVER")
+	e := &Executor{DefaultEnv: []string{"DEFAULT=1"}}
+	req := [][]byte{[]byte("REQ=1"), []byte("DEFAULT=2")}
+	for _, c := range []struct {
+		mode    apis.EnvMode
+		env     [][]byte
+		server  bool
+		want    map[string]string
+		wantNil bool
+	}{
+		{mode: apis.EnvMode_ENV_DEFAULT, env: req, want: map[string]string{"REQ": "1", "DEFAULT": "2"}},
+		{mode: apis.EnvMode_ENV_DEFAULT, server: true, want: map[string]string{"DEFAULT": "1"}},
+		{mode: apis.EnvMode_ENV_INHERIT, env: req, server: true, want: map[string]string{"DEFAULT": "1"}},
+		{mode: apis.EnvMode_ENV_INHERIT_OVERRIDE, env: req, server: true, want: map[string]string{"REQ": "1", "DEFAULT": "2"}},
+		{mode: apis.EnvMode_ENV_CLEAN, env: req, want: map[string]string{"REQ": "1", "DEFAULT": "2"}},
+	} {
+		env, err := e.CommandEnv(&apis.Command{EnvMode: c.mode, Env: c.env})
+		if err != nil {
+			t.Fatalf("%s: %v", c.mode, err)
+		}
+		for k, want := range c.want {
+			if got, _ := lookupEnv(env, k); got != want {
+				t.Errorf("%s: %s=%q, want %q", c.mode, k, got, want)
+			}
+		}
+		if _, ok := lookupEnv(env, "EXECUTOR_TEST_SERVER"); ok != c.server {
+			t.Errorf("%s: server env inherited %v, want %v", c.mode, ok, c.server)
+		}
+	}
+	// env of the command replaces default env as well in legacy mode
+	env, err := e.CommandEnv(&apis.Command{Env: [][]byte{[]byte("REQ=1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lookupEnv(env, "DEFAULT"); ok || len(env) != 1 {
+		t.Errorf("default mode with request env: %q", env)
+	}
+	if env, err := e.CommandEnv(&apis.Command{EnvMode: apis.EnvMode_ENV_CLEAN}); err != nil || env == nil || len(env) != 0 {
+		t.Errorf("clean env without request env: %q %v", env, err)
+	}
+	if env, err := (&Executor{}).CommandEnv(&apis.Command{}); err != nil || env != nil {
+		t.Errorf("default env without defaults: %q %v", env, err)
+	}
+	if _, err := e.CommandEnv(&apis.Command{EnvMode: 100}); err == nil {
+		t.Errorf("unknown env mode accepted")
+	}
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/internal/chunk"
+	"yunion.io/x/log"
+)
+
+type execStream struct {
+	e *Executor
+	s apis.Executor_ExecServer
+	m *Commander
+
+	sendLock sync.Mutex
+	exited   chan struct{}
+
+	// err ends the stream instead of the exit status
+	errLock sync.Mutex
+	err     error
+}
+
+func (es *execStream) send(res *apis.ExecResponse) error {
+	es.sendLock.Lock()
+	defer es.sendLock.Unlock()
+	return es.s.Send(res)
+}
+
+func (es *execStream) kill() {
+	select {
+	case <-es.exited:
+	default:
+		if err := es.m.c.Process.Kill(); err != nil {
+			log.Warningf("%d kill on stream closed: %s", es.m.sn, err)
+		}
+	}
+}
+
+func (es *execStream) fail(err error) {
+	es.errLock.Lock()
+	es.err = err
+	es.errLock.Unlock()
+}
+
+func (es *execStream) failure() error {
+	es.errLock.Lock()
+	defer es.errLock.Unlock()
+	return es.err
+}
+
+// recvLoop handles stdin, signals and acks from client, the process is
+// killed if the stream breaks or stdin overflows the queue, a half
+// closed stream keeps process running
+func (es *execStream) recvLoop() {
+	m := es.m
+	defer func() {
+		// no acks are coming, output is sent until a send fails
+		for _, w := range []*flowWindow{m.stdoutWindow, m.stderrWindow} {
+			if w != nil {
+				w.release()
+			}
+		}
+	}()
+	for {
+		req, err := es.s.Recv()
+		if err != nil {
+			if err != io.EOF {
+				es.kill()
+			}
+			return
+		}
+		switch r := req.Request.(type) {
+		case *apis.ExecRequest_Stdin:
+			if m.stdinQueue != nil && !m.stdinQueue.push(r.Stdin) {
+				// a client without flow control writes more than the
+				// process reads
+				log.Warningf("%d stdin over %d bytes buffered, killed", m.sn, es.e.streamLimit())
+				es.fail(status.Errorf(codes.ResourceExhausted, "stdin over %d bytes buffered", es.e.streamLimit()))
+				es.kill()
+				return
+			}
+		case *apis.ExecRequest_CloseStdin:
+			if m.stdinQueue != nil {
+				m.stdinQueue.close()
+			}
+		case *apis.ExecRequest_Signal:
+			if err := m.c.Process.Signal(syscall.Signal(r.Signal)); err != nil {
+				log.Warningf("%d signal %d: %s", m.sn, r.Signal, err)
+			}
+		case *apis.ExecRequest_Ack:
+			if m.stdoutWindow != nil {
+				m.stdoutWindow.ack(r.Ack.Stdout)
+				m.stdoutTail.ack(r.Ack.Stdout)
+			}
+			if m.stderrWindow != nil {
+				m.stderrWindow.ack(r.Ack.Stderr)
+				m.stderrTail.ack(r.Ack.Stderr)
+			}
+		}
+	}
+}
+
+// writeLoop writes queued stdin to the process and acknowledges it,
+// stdin is dropped once the process exited or closed its stdin
+func (es *execStream) writeLoop() {
+	m, q := es.m, es.m.stdinQueue
+	var broken bool
+	if _, err := m.writeStdin(nil); err != nil {
+		log.Debugf("%d write stdin: %s", m.sn, err)
+		broken = true
+	}
+	acked := m.getStdinOffset()
+	for {
+		data, ok := q.pop()
+		if !ok {
+			return
+		}
+		if data == nil {
+			if !broken {
+				m.stdin.Close()
+			}
+			return
+		}
+		if !broken {
+			n, err := m.writeStdin(data)
+			stdinBytes.Add(float64(n))
+			if err != nil {
+				log.Debugf("%d write stdin: %s", m.sn, err)
+				broken = true
+			}
+		}
+		if broken {
+			m.dropStdin(q)
+		}
+		if m.window == 0 {
+			continue
+		}
+		// the client waits only with a window unacknowledged
+		offset := m.getStdinOffset()
+		if offset-acked >= uint64(m.window/4) {
+			es.send(&apis.ExecResponse{
+				Response: &apis.ExecResponse_StdinAck{StdinAck: offset},
+			})
+			acked = offset
+		}
+	}
+}
+
+// pumpOutput sends output of r, it returns an error if the client
+// stalled and output was closed
+func (es *execStream) pumpOutput(r io.ReadCloser, name string, tail *outputTail, w *flowWindow, frame func([]byte) *apis.ExecResponse, counter func(float64)) error {
+	data := chunk.Get(es.e.chunkSize())
+	defer chunk.Put(data)
+	var reserve func(int) (int, error)
+	if w != nil {
+		reserve = func(max int) (int, error) {
+			return w.reserve(max, es.e.StallTimeout)
+		}
+	}
+	err, sendErr := pumpPipe(r, data, es.e.CoalesceDelay, reserve, func(data []byte) {
+		counter(float64(len(data)))
+		es.m.broadcast(frame(data))
+		tail.add(data)
+		if w != nil {
+			w.add(len(data))
+		}
+	}, func(data []byte) error {
+		return es.send(frame(data))
+	})
+	if sendErr == errStalled {
+		// the process gets EPIPE like writing to a reader gone
+		buffered, _ := w.status()
+		streamStalls.WithLabelValues(name).Inc()
+		err := errors.Errorf("%s stalled, client consumed none of %d bytes in %s, output closed", name, buffered, es.e.StallTimeout)
+		log.Warningf("%d %s", es.m.sn, err)
+		r.Close()
+		return err
+	}
+	if sendErr != nil {
+		// client gone, drain so the process won't block on a full pipe
+		es.kill()
+		io.Copy(ioutil.Discard, r)
+		return nil
+	}
+	if err != io.EOF {
+		log.Errorf("%d read output: %s", es.m.sn, err)
+	}
+	return nil
+}
+
+// replay sends output the resuming client missed, from its offset
+func (es *execStream) replay(tail *outputTail, offset uint64, frame func([]byte) *apis.ExecResponse) error {
+	data, missing := tail.since(offset)
+	if missing > 0 {
+		log.Warningf("%d %d bytes of output lost on resume", es.m.sn, missing)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return es.send(frame(data))
+}
+
+func stdoutFrame(data []byte) *apis.ExecResponse {
+	return &apis.ExecResponse{Response: &apis.ExecResponse_Stdout{Stdout: data}}
+}
+
+func stderrFrame(data []byte) *apis.ExecResponse {
+	return &apis.ExecResponse{Response: &apis.ExecResponse_Stderr{Stderr: data}}
+}
+
+func (e *Executor) Exec(s apis.Executor_ExecServer) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	start := req.GetStart()
+	if start != nil && start.ResumeSn != 0 {
+		return e.resumeExec(s, start)
+	}
+	if start == nil || start.Command == nil {
+		return errors.New("exec stream must begin with start")
+	}
+	var files *commandFiles
+	if start.Files != nil {
+		if files, err = claimCommandFiles(start); err != nil {
+			return err
+		}
+	}
+	m, err := e.newCommander(s.Context(), start.Command, true, files)
+	if err != nil {
+		files.close()
+		return err
+	}
+	defer cmds.Delete(m.sn)
+
+	err = m.start(&apis.StartInput{
+		Sn:             m.sn,
+		HasStdin:       start.HasStdin,
+		HasStdout:      start.HasStdout,
+		HasStderr:      start.HasStderr,
+		CombinedOutput: start.CombinedOutput,
+	})
+	if err != nil {
+		return s.Send(&apis.ExecResponse{
+			Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{
+				Success: false,
+				Error:   []byte(err.Error()),
+				Sn:      m.sn,
+			}},
+		})
+	}
+
+	es := &execStream{e: e, s: s, m: m, exited: make(chan struct{})}
+	es.setupFlow(e.grantWindow(start.Window), 0, 0)
+	err = es.send(&apis.ExecResponse{
+		Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{
+			Success:  true,
+			Sn:       m.sn,
+			Landlock: m.landlockStatus(),
+			Window:   m.window,
+		}},
+	})
+	if err != nil {
+		es.kill()
+	}
+	return es.serve()
+}
+
+// resumeExec continues the stream of a command handed over by the
+// previous server, for the client whose stream broke on the upgrade
+func (e *Executor) resumeExec(s apis.Executor_ExecServer, start *apis.ExecStart) error {
+	m, err := loadCommander(start.ResumeSn)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	m.lock.Lock()
+	started := m.pid != 0
+	m.lock.Unlock()
+	if !m.streamed || !started || !m.claimResume() {
+		return status.Errorf(codes.FailedPrecondition, "sn %d can't be resumed", m.sn)
+	}
+	defer cmds.Delete(m.sn)
+	log.Infof("%d Resumed%s", m.sn, TraceContextFromIncoming(s.Context()))
+
+	es := &execStream{e: e, s: s, m: m, exited: make(chan struct{})}
+	stdinOffset := es.setupFlow(e.grantWindow(start.Window), start.StdoutOffset, start.StderrOffset)
+	err = es.send(&apis.ExecResponse{
+		Response: &apis.ExecResponse_Started{Started: &apis.StartResponse{
+			Success:     true,
+			Sn:          m.sn,
+			Landlock:    m.landlockStatus(),
+			Window:      m.window,
+			StdinOffset: stdinOffset,
+		}},
+	})
+	if err == nil {
+		err = es.replay(m.stdoutTail, start.StdoutOffset, stdoutFrame)
+	}
+	if err == nil {
+		err = es.replay(m.stderrTail, start.StderrOffset, stderrFrame)
+	}
+	if err != nil {
+		es.kill()
+	}
+	return es.serve()
+}
+
+// setupFlow creates the stdin queue and the output windows of the
+// stream, output up to the offsets is taken as acknowledged, it
+// returns the offset of stdin the client sends from
+func (es *execStream) setupFlow(window uint32, stdoutOffset, stderrOffset uint64) uint64 {
+	m := es.m
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.window = window
+	if m.stdin != nil {
+		m.stdinQueue = newStdinQueue(es.e.streamLimit(), m.stdinOffset)
+	}
+	if window > 0 {
+		m.stdoutTail.ack(stdoutOffset)
+		m.stderrTail.ack(stderrOffset)
+		m.stdoutWindow = newFlowWindow(window, m.stdoutTail.sent(), stdoutOffset)
+		m.stderrWindow = newFlowWindow(window, m.stderrTail.sent(), stderrOffset)
+	}
+	return m.stdinOffset
+}
+
+// serve pumps stdin, output and signals of the started command until
+// it exits and sends its exit status
+func (es *execStream) serve() error {
+	m := es.m
+	defer noticeHandoff(es.send)()
+	go es.recvLoop()
+	if m.stdinQueue != nil {
+		go es.writeLoop()
+	}
+
+	var wg sync.WaitGroup
+	var stdoutErr, stderrErr error
+	if m.stdout != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stdoutErr = es.pumpOutput(m.stdout, "stdout", m.stdoutTail, m.stdoutWindow, stdoutFrame, stdoutBytes.Add)
+		}()
+	}
+	if m.stderr != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stderrErr = es.pumpOutput(m.stderr, "stderr", m.stderrTail, m.stderrWindow, stderrFrame, stderrBytes.Add)
+		}()
+	}
+	wg.Wait()
+
+	res := m.wait()
+	close(es.exited)
+	if m.stdinQueue != nil {
+		m.stdinQueue.stop()
+	}
+	if err := es.failure(); err != nil {
+		return err
+	}
+	for _, err := range []error{stdoutErr, stderrErr} {
+		if err != nil && len(res.ErrContent) == 0 {
+			res = &apis.WaitResponse{ExitStatus: res.ExitStatus, ErrContent: []byte(err.Error())}
+		}
+	}
+	return es.send(&apis.ExecResponse{
+		Response: &apis.ExecResponse_Exit{Exit: res},
+	})
+}
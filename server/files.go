@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/log"
+)
+
+// Clients on the unix socket pass files to commands on a connection
+// of their own, started by apis.FilesPreface instead of the grpc
+// preface. The files are kept under a token the client names in
+// ExecStart
+const (
+	// time given to a client to start the command after passing files
+	passedFilesTimeout = time.Minute
+	// time given to a connection to send its preface
+	prefaceTimeout = 10 * time.Second
+)
+
+type stashedFiles struct {
+	files []*os.File
+	timer *time.Timer
+}
+
+type filesStash struct {
+	lock  sync.Mutex
+	files map[string]*stashedFiles
+}
+
+var passedFiles = &filesStash{files: make(map[string]*stashedFiles)}
+
+// add keeps files until claimed, they are closed if not claimed in
+// time. Files passed during a handoff wait for it, so they are either
+// handed over or the connection is cut by exec
+func (s *filesStash) add(files []*os.File) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	gate.acquire()
+	defer gate.release()
+	s.keep(token, files)
+	return token, nil
+}
+
+func (s *filesStash) keep(token string, files []*os.File) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[token] = &stashedFiles{
+		files: files,
+		timer: time.AfterFunc(passedFilesTimeout, func() {
+			if files := s.claim(token); files != nil {
+				log.Warningf("%d files passed not claimed in %s, closed", len(files), passedFilesTimeout)
+				closeFiles(files)
+			}
+		}),
+	}
+}
+
+// handoff marks files not claimed to be inherited, by token
+func (s *filesStash) handoff(h *Handoff) map[string][]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tokens := map[string][]int{}
+	for token, ent := range s.files {
+		fds := make([]int, len(ent.files))
+		for i, f := range ent.files {
+			fds[i] = h.inherit(f)
+		}
+		tokens[token] = fds
+	}
+	return tokens
+}
+
+func (s *filesStash) claim(token string) []*os.File {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ent, ok := s.files[token]
+	if !ok {
+		return nil
+	}
+	ent.timer.Stop()
+	delete(s.files, token)
+	return ent.files
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// commandFiles are files passed by client for a command, the child
+// gets them on start and the server closes its copies
+type commandFiles struct {
+	stdin  *os.File
+	stdout *os.File
+	stderr *os.File
+	extra  []*os.File
+}
+
+// claimCommandFiles takes the files named by start
+func claimCommandFiles(start *apis.ExecStart) (*commandFiles, error) {
+	in := start.Files
+	files := passedFiles.claim(in.Token)
+	if files == nil {
+		return nil, status.Error(codes.NotFound, "unknown files token")
+	}
+	cf := &commandFiles{}
+	next := func() *os.File {
+		if len(files) == 0 {
+			return nil
+		}
+		f := files[0]
+		files = files[1:]
+		return f
+	}
+	var want int
+	for _, p := range []struct {
+		passed bool
+		f      **os.File
+	}{{in.Stdin, &cf.stdin}, {in.Stdout, &cf.stdout}, {in.Stderr, &cf.stderr}} {
+		if p.passed {
+			want++
+			*p.f = next()
+		}
+	}
+	want += int(in.Extra)
+	cf.extra = files
+	if cf.count() != want {
+		cf.close()
+		return nil, status.Errorf(codes.InvalidArgument, "%d files passed, %d named", cf.count(), want)
+	}
+	if in.Stdin && start.HasStdin || in.Stdout && (start.HasStdout || start.CombinedOutput) ||
+		in.Stderr && (start.HasStderr || start.CombinedOutput) {
+		cf.close()
+		return nil, status.Error(codes.InvalidArgument, "stdio both passed and streamed")
+	}
+	return cf, nil
+}
+
+func (cf *commandFiles) count() int {
+	n := len(cf.extra)
+	for _, f := range []*os.File{cf.stdin, cf.stdout, cf.stderr} {
+		if f != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (cf *commandFiles) close() {
+	if cf == nil {
+		return
+	}
+	closeFiles([]*os.File{cf.stdin, cf.stdout, cf.stderr})
+	closeFiles(cf.extra)
+}
+
+// FilesListener serves grpc on connections of a unix socket listener
+// and takes files passed on connections starting with
+// apis.FilesPreface
+type FilesListener struct {
+	net.Listener
+
+	conns  chan net.Conn
+	done   chan struct{}
+	err    error
+	closed chan struct{}
+	once   sync.Once
+}
+
+func NewFilesListener(l net.Listener) *FilesListener {
+	fl := &FilesListener{
+		Listener: l,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go fl.acceptLoop()
+	return fl
+}
+
+func (l *FilesListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				log.Warningf("accept: %s", err)
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			l.err = err
+			close(l.done)
+			return
+		}
+		go l.sniff(conn)
+	}
+}
+
+// sniff tells connections passing files from grpc ones by the preface
+func (l *FilesListener) sniff(conn net.Conn) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		l.deliver(conn)
+		return
+	}
+	uc.SetReadDeadline(time.Now().Add(prefaceTimeout))
+	files, prefix, err := readFilesPreface(uc)
+	uc.SetReadDeadline(time.Time{})
+	if err != nil {
+		log.Debugf("read preface: %s", err)
+		if files != nil {
+			fmt.Fprintf(uc, "error %s\n", err)
+		}
+		uc.Close()
+		return
+	}
+	if files == nil {
+		l.deliver(&prefixConn{Conn: uc, prefix: prefix})
+		return
+	}
+	defer uc.Close()
+	token, err := passedFiles.add(files)
+	if err != nil {
+		closeFiles(files)
+		fmt.Fprintf(uc, "error %s\n", err)
+		return
+	}
+	fmt.Fprintf(uc, "ok %s\n", token)
+}
+
+func (l *FilesListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+func (l *FilesListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, l.err
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *FilesListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// File returns the file of the unix socket for the handoff
+func (l *FilesListener) File() (*os.File, error) {
+	fl, ok := l.Listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("listener has no file")
+	}
+	return fl.File()
+}
+
+// prefixConn is a grpc connection whose preface was read while sniffing
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,71 @@
+// +build !windows
+
+package server
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// readFilesPreface reads the preface of conn, files is not nil on a
+// connection passing files, prefix is the data read of others
+func readFilesPreface(conn *net.UnixConn) (files []*os.File, prefix []byte, err error) {
+	preface := []byte(apis.FilesPreface)
+	buf := make([]byte, len(preface))
+	oob := make([]byte, syscall.CmsgSpace(apis.MaxPassedFiles*4))
+	var got int
+	for got < len(buf) {
+		n, oobn, flags, _, rerr := conn.ReadMsgUnix(buf[got:], oob)
+		got += n
+		if oobn > 0 {
+			fds, perr := parseRights(oob[:oobn])
+			for _, fd := range fds {
+				files = append(files, os.NewFile(uintptr(fd), "passed"))
+			}
+			if perr == nil && flags&syscall.MSG_CTRUNC != 0 {
+				perr = errors.Errorf("more than %d files passed", apis.MaxPassedFiles)
+			}
+			if perr != nil {
+				closeFiles(files)
+				return []*os.File{}, nil, perr
+			}
+		}
+		if !bytes.HasPrefix(preface, buf[:got]) {
+			closeFiles(files)
+			return nil, buf[:got], nil
+		}
+		if rerr != nil {
+			closeFiles(files)
+			return nil, nil, rerr
+		}
+	}
+	if files == nil {
+		files = []*os.File{}
+	}
+	return files, nil, nil
+}
+
+func parseRights(oob []byte) ([]int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int
+	for i := range msgs {
+		rights, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			continue
+		}
+		for _, fd := range rights {
+			syscall.CloseOnExec(fd)
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
@@ -0,0 +1,182 @@
+// +build !windows
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+// passFiles sends the files preface with fds on a connection to l,
+// it returns the reply
+func passFiles(t *testing.T, l *FilesListener, fds []int) string {
+	conn, err := net.DialUnix("unix", nil, l.Addr().(*net.UnixAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.WriteMsgUnix([]byte(apis.FilesPreface), syscall.UnixRights(fds...), nil); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(reply, "\n")
+}
+
+func listenFiles(t *testing.T) (*FilesListener, func()) {
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("unix", filepath.Join(dir, "exec.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	l := NewFilesListener(lis)
+	return l, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPassFilesRoundTrip(t *testing.T) {
+	l, cleanup := listenFiles(t)
+	defer cleanup()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	reply := passFiles(t, l, []int{int(w.Fd())})
+	if !strings.HasPrefix(reply, "ok ") {
+		t.Fatalf("reply: %s", reply)
+	}
+	files := passedFiles.claim(strings.TrimPrefix(reply, "ok "))
+	if len(files) != 1 {
+		t.Fatalf("%d files passed", len(files))
+	}
+	defer closeFiles(files)
+	// not to leak into commands started by the server
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, files[0].Fd(), syscall.F_GETFD, 0)
+	if errno != 0 || flags&syscall.FD_CLOEXEC == 0 {
+		t.Errorf("passed fd flags %#x %v, want close on exec", flags, errno)
+	}
+	if _, err := files[0].Write([]byte("passed")); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 6)
+	if _, err := r.Read(data); err != nil || string(data) != "passed" {
+		t.Errorf("read of passed pipe: %q %v", data, err)
+	}
+	if files := passedFiles.claim(strings.TrimPrefix(reply, "ok ")); files != nil {
+		t.Errorf("files claimed twice")
+	}
+}
+
+func TestPassFilesOverMax(t *testing.T) {
+	l, cleanup := listenFiles(t)
+	defer cleanup()
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fds := make([]int, apis.MaxPassedFiles+1)
+	for i := range fds {
+		fds[i] = int(f.Fd())
+	}
+	passedFiles.lock.Lock()
+	stashed := len(passedFiles.files)
+	passedFiles.lock.Unlock()
+
+	if reply := passFiles(t, l, fds); !strings.HasPrefix(reply, "error more than") {
+		t.Errorf("reply to %d files: %s", len(fds), reply)
+	}
+	passedFiles.lock.Lock()
+	defer passedFiles.lock.Unlock()
+	if len(passedFiles.files) != stashed {
+		t.Errorf("files over max stashed")
+	}
+}
+
+func TestParseRightsTruncated(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	oob := syscall.UnixRights(int(f.Fd()), int(f.Fd()))
+	fds, err := parseRights(oob)
+	if err != nil || len(fds) != 2 {
+		t.Errorf("parse of rights: %v %v", fds, err)
+	}
+	// the header claims more than was received
+	if fds, err := parseRights(oob[:len(oob)-4]); err == nil {
+		t.Errorf("parse of truncated rights: %v", fds)
+	}
+}
+
+func TestHandoffPassedFiles(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	const token = "handoff-token"
+	passedFiles.keep(token, []*os.File{w})
+	h := &Handoff{state: handoffState{
+		StartedAt: serverStartedAt,
+		Sn:        atomic.LoadUint32(&globalSn),
+	}}
+	h.state.PassedFiles = passedFiles.handoff(h)
+	fds := h.state.PassedFiles[token]
+	if len(fds) != 1 || !reflect.DeepEqual(h.fds, fds) {
+		t.Fatalf("handed over %v, inherited %v", h.state.PassedFiles, h.fds)
+	}
+	// the new server takes the fds over, the old files are gone with exec
+	fds[0] = dupFd(t, fds[0])
+	closeFiles(passedFiles.claim(token))
+
+	state, err := ioutil.TempFile("", "handoff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(state.Name())
+	if err := json.NewEncoder(state).Encode(&h.state); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(handoffEnv, strconv.Itoa(dupFd(t, int(state.Fd()))))
+	state.Close()
+	if _, err := RestoreHandoff(); err != nil {
+		t.Fatal(err)
+	}
+	files := passedFiles.claim(token)
+	if len(files) != 1 {
+		t.Fatalf("%d passed files restored", len(files))
+	}
+	defer closeFiles(files)
+	if _, err := files[0].Write([]byte("kept")); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 4)
+	if _, err := r.Read(data); err != nil || string(data) != "kept" {
+		t.Errorf("read of restored pipe: %q %v", data, err)
+	}
+}
@@ -0,0 +1,13 @@
+package server
+
+import (
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// readFilesPreface fails, files can't be passed without unix rights
+func readFilesPreface(conn *net.UnixConn) (files []*os.File, prefix []byte, err error) {
+	return nil, nil, errors.New("files can only be passed on unix socket")
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// errStalled is returned by a window full for longer than the stall
+// timeout
+var errStalled = errors.New("stalled")
+
+// flowWindow counts output sent to and acknowledged by the client of
+// an Exec stream, output waits while a window of it is unacknowledged
+type flowWindow struct {
+	lock  sync.Mutex
+	cond  *sync.Cond
+	size  uint64
+	sent  uint64
+	acked uint64
+	// open stops waiting, no acks are coming any more
+	open bool
+	// stalledAt is when the window got full, zero while it isn't
+	stalledAt time.Time
+}
+
+func newFlowWindow(size uint32, sent, acked uint64) *flowWindow {
+	w := &flowWindow{size: uint64(size), sent: sent, acked: acked}
+	if w.acked > w.sent {
+		w.acked = w.sent
+	}
+	w.cond = sync.NewCond(&w.lock)
+	return w
+}
+
+// reserve waits for room in the window and returns how many of max
+// bytes may be sent, errStalled after waiting timeout, 0 waits forever
+func (w *flowWindow) reserve(max int, timeout time.Duration) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var timedOut bool
+	for !w.open && w.sent-w.acked >= w.size {
+		if timedOut {
+			return 0, errStalled
+		}
+		if w.stalledAt.IsZero() {
+			w.stalledAt = time.Now()
+			if timeout > 0 {
+				timer := time.AfterFunc(timeout, func() {
+					w.lock.Lock()
+					timedOut = true
+					w.cond.Broadcast()
+					w.lock.Unlock()
+				})
+				defer timer.Stop()
+			}
+		}
+		w.cond.Wait()
+	}
+	w.stalledAt = time.Time{}
+	if room := w.size - (w.sent - w.acked); !w.open && uint64(max) > room {
+		max = int(room)
+	}
+	return max, nil
+}
+
+// add counts n bytes sent
+func (w *flowWindow) add(n int) {
+	w.lock.Lock()
+	w.sent += uint64(n)
+	w.lock.Unlock()
+}
+
+// ack takes the total bytes the client consumed
+func (w *flowWindow) ack(offset uint64) {
+	w.lock.Lock()
+	if offset > w.acked && offset <= w.sent {
+		w.acked = offset
+		w.cond.Broadcast()
+	}
+	w.lock.Unlock()
+}
+
+// release stops limiting output when the client stops acknowledging
+func (w *flowWindow) release() {
+	w.lock.Lock()
+	w.open = true
+	w.cond.Broadcast()
+	w.lock.Unlock()
+}
+
+// status returns bytes unacknowledged and how long the window is full
+func (w *flowWindow) status() (uint64, time.Duration) {
+	if w == nil {
+		return 0, 0
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var stalled time.Duration
+	if !w.stalledAt.IsZero() {
+		stalled = time.Since(w.stalledAt)
+	}
+	return w.sent - w.acked, stalled
+}
+
+// stdinQueue passes stdin from the stream to its writer, so the stream
+// keeps taking signals and acks while the process doesn't read stdin
+type stdinQueue struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	// size is bytes queued, received counts bytes of the stream
+	size     int
+	limit    int
+	received uint64
+	// closeStdin is queued after the chunks
+	closeStdin bool
+	stopped    bool
+}
+
+func newStdinQueue(limit int, received uint64) *stdinQueue {
+	q := &stdinQueue{limit: limit, received: received}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+// push queues data, false if the queue is full, which clients with
+// flow control never fill. Waiting would stop the stream taking signals
+func (q *stdinQueue) push(data []byte) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.size > 0 && q.size+len(data) > q.limit {
+		return false
+	}
+	q.chunks = append(q.chunks, data)
+	q.size += len(data)
+	q.received += uint64(len(data))
+	q.cond.Broadcast()
+	return true
+}
+
+func (q *stdinQueue) close() {
+	q.lock.Lock()
+	q.closeStdin = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+// stop ends the writer, the chunks left are dropped
+func (q *stdinQueue) stop() {
+	q.lock.Lock()
+	q.stopped = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+// pop waits for the next chunk, nil data if stdin is to be closed,
+// false once stopped
+func (q *stdinQueue) pop() ([]byte, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for !q.stopped && len(q.chunks) == 0 && !q.closeStdin {
+		q.cond.Wait()
+	}
+	if q.stopped {
+		return nil, false
+	}
+	if len(q.chunks) == 0 {
+		return nil, true
+	}
+	data := q.chunks[0]
+	q.chunks[0] = nil
+	q.chunks = q.chunks[1:]
+	q.size -= len(data)
+	q.cond.Broadcast()
+	return data, true
+}
+
+// pending returns the chunks not taken by the writer
+func (q *stdinQueue) pending() []byte {
+	if q == nil {
+		return nil
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var data []byte
+	for _, chunk := range q.chunks {
+		data = append(data, chunk...)
+	}
+	return data
+}
+
+func (q *stdinQueue) receivedBytes() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.received
+}
+
+// buffered returns bytes of stdin received and not written yet
+func (q *stdinQueue) buffered(written uint64) uint64 {
+	if q == nil {
+		return 0
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.received < written {
+		return 0
+	}
+	return q.received - written
+}
+
+func (m *Commander) addStdinOffset(n int) {
+	m.lock.Lock()
+	m.stdinOffset += uint64(n)
+	m.lock.Unlock()
+}
+
+func (m *Commander) getStdinOffset() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stdinOffset
+}
+
+// dropStdin counts stdin received as written once the process stopped
+// taking it, so the client isn't left waiting for acks
+func (m *Commander) dropStdin(q *stdinQueue) {
+	received := q.receivedBytes()
+	m.lock.Lock()
+	if received > m.stdinOffset {
+		m.stdinOffset = received
+	}
+	m.lock.Unlock()
+}
+
+// flowStatus reports buffers of the Exec stream with flow control
+func (m *Commander) flowStatus() *apis.FlowStatus {
+	m.lock.Lock()
+	window, queue := m.window, m.stdinQueue
+	stdout, stderr := m.stdoutWindow, m.stderrWindow
+	offset := m.stdinOffset
+	m.lock.Unlock()
+	if window == 0 {
+		return nil
+	}
+	st := &apis.FlowStatus{
+		Window:        window,
+		StdinBuffered: queue.buffered(offset),
+	}
+	var stalled time.Duration
+	st.StdoutBuffered, stalled = stdout.status()
+	st.StdoutStalledMs = int64(stalled / time.Millisecond)
+	st.StderrBuffered, stalled = stderr.status()
+	st.StderrStalledMs = int64(stalled / time.Millisecond)
+	return st
+}
+
+// streamLimit is the bytes buffered for one stream of a command
+func (e *Executor) streamLimit() int {
+	if e.MaxBuffered > 0 {
+		return e.MaxBuffered / 3
+	}
+	return apis.DefaultMaxBuffered / 3
+}
+
+// grantWindow limits the window asked by client to the buffer cap,
+// 0 for clients without flow control
+func (e *Executor) grantWindow(asked uint32) uint32 {
+	if limit := uint32(e.streamLimit()); asked > limit {
+		return limit
+	}
+	return asked
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestFlowWindowCredit(t *testing.T) {
+	// acks beyond what was sent are clamped
+	w := newFlowWindow(8, 4, 10)
+	if n, err := w.reserve(16, 0); n != 8 || err != nil {
+		t.Errorf("reserve of empty window: got %d %v", n, err)
+	}
+	w.add(6)
+	if n, _ := w.reserve(16, 0); n != 2 {
+		t.Errorf("reserve with 6 unacked: got %d, want 2", n)
+	}
+	w.add(2)
+
+	// full, waits for credit
+	reserved := make(chan int, 1)
+	go func() {
+		n, _ := w.reserve(16, 0)
+		reserved <- n
+	}()
+	select {
+	case n := <-reserved:
+		t.Fatalf("reserved %d of a full window", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if unacked, stalled := w.status(); unacked != 8 || stalled == 0 {
+		t.Errorf("status of full window: %d unacked, stalled %s", unacked, stalled)
+	}
+	// stale and future acks are ignored
+	w.ack(2)
+	w.ack(100)
+	w.ack(9)
+	select {
+	case n := <-reserved:
+		if n != 5 {
+			t.Errorf("reserve after ack: got %d, want 5", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ack didn't give credit")
+	}
+	if _, stalled := w.status(); stalled != 0 {
+		t.Errorf("window not full stalled %s", stalled)
+	}
+}
+
+func TestFlowWindowStall(t *testing.T) {
+	w := newFlowWindow(4, 0, 0)
+	w.add(4)
+	if _, err := w.reserve(4, 20*time.Millisecond); err != errStalled {
+		t.Errorf("reserve of full window: got %v, want stalled", err)
+	}
+
+	// released windows don't wait for acks nor limit output
+	reserved := make(chan int, 1)
+	go func() {
+		n, _ := w.reserve(16, 0)
+		reserved <- n
+	}()
+	time.Sleep(20 * time.Millisecond)
+	w.release()
+	select {
+	case n := <-reserved:
+		if n != 16 {
+			t.Errorf("reserve of released window: got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release didn't wake up reserve")
+	}
+}
+
+func TestStdinQueue(t *testing.T) {
+	q := newStdinQueue(8, 100)
+	// a chunk over the limit passes on an empty queue
+	if !q.push([]byte("0123456789")) {
+		t.Fatal("chunk refused by empty queue")
+	}
+	if q.push([]byte("a")) {
+		t.Errorf("push over limit accepted")
+	}
+	if data, ok := q.pop(); !ok || string(data) != "0123456789" {
+		t.Errorf("pop: %q %v", data, ok)
+	}
+	for _, chunk := range []string{"abc", "defgh"} {
+		if !q.push([]byte(chunk)) {
+			t.Errorf("push of %s refused", chunk)
+		}
+	}
+	if q.push([]byte("i")) {
+		t.Errorf("push over limit accepted")
+	}
+	if got := string(q.pending()); got != "abcdefgh" {
+		t.Errorf("pending: %q", got)
+	}
+	if got := q.buffered(105); got != 13 {
+		t.Errorf("buffered: got %d, want 13", got)
+	}
+	q.close()
+	for _, want := range []string{"abc", "defgh"} {
+		if data, ok := q.pop(); !ok || string(data) != want {
+			t.Errorf("pop: got %q %v, want %s", data, ok, want)
+		}
+	}
+	// closing stdin comes after the chunks
+	if data, ok := q.pop(); !ok || data != nil {
+		t.Errorf("pop of closed queue: %q %v", data, ok)
+	}
+	q.stop()
+	if _, ok := q.pop(); ok {
+		t.Errorf("pop of stopped queue")
+	}
+}
+
+// rawExec starts an Exec stream without flow control, as clients
+// before it do
+func rawExec(t *testing.T, e *Executor, start *apis.ExecStart) (apis.Executor_ExecClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	apis.RegisterExecutorServer(srv, e)
+	go srv.Serve(lis)
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(
+		func(ctx context.Context, addr string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cleanup := func() {
+		cancel()
+		conn.Close()
+		srv.Stop()
+	}
+	stream, err := apis.NewExecutorClient(conn).Exec(ctx)
+	if err == nil {
+		err = stream.Send(&apis.ExecRequest{Request: &apis.ExecRequest_Start{Start: start}})
+	}
+	var res *apis.ExecResponse
+	if err == nil {
+		res, err = stream.Recv()
+	}
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	if started := res.GetStarted(); !started.GetSuccess() || started.Window != 0 {
+		cleanup()
+		t.Fatalf("started: %v", res)
+	}
+	return stream, cleanup
+}
+
+// waitExit receives till the exit status, it fails after timeout
+func waitExit(t *testing.T, stream apis.Executor_ExecClient) (*apis.WaitResponse, error) {
+	type result struct {
+		exit *apis.WaitResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			if exit := res.GetExit(); exit != nil {
+				done <- result{exit: exit}
+				return
+			}
+		}
+	}()
+	select {
+	case r := <-done:
+		return r.exit, r.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not ended")
+		return nil, nil
+	}
+}
+
+func TestStdinOverflowWithoutWindow(t *testing.T) {
+	e := &Executor{MaxBuffered: 3 << 10}
+	stream, cleanup := rawExec(t, e, &apis.ExecStart{
+		Command:  &apis.Command{Path: []byte("/bin/sleep"), Args: [][]byte{[]byte("60")}},
+		HasStdin: true,
+	})
+	defer cleanup()
+
+	// sleep reads none of it, the pipe takes 64KiB
+	chunk := make([]byte, 1<<10)
+	for i := 0; i < 256; i++ {
+		err := stream.Send(&apis.ExecRequest{Request: &apis.ExecRequest_Stdin{Stdin: chunk}})
+		if err != nil {
+			break
+		}
+	}
+	_, err := waitExit(t, stream)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("stream of overflowing stdin ended with %v", err)
+	}
+}
+
+func TestSignalWithoutWindow(t *testing.T) {
+	e := &Executor{MaxBuffered: 3 << 20}
+	stream, cleanup := rawExec(t, e, &apis.ExecStart{
+		Command:  &apis.Command{Path: []byte("/bin/sleep"), Args: [][]byte{[]byte("60")}},
+		HasStdin: true,
+	})
+	defer cleanup()
+
+	// stdin left in the queue doesn't hold up the signal
+	chunk := make([]byte, 32<<10)
+	for i := 0; i < 16; i++ {
+		if err := stream.Send(&apis.ExecRequest{Request: &apis.ExecRequest_Stdin{Stdin: chunk}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := stream.Send(&apis.ExecRequest{Request: &apis.ExecRequest_Signal{Signal: int32(syscall.SIGTERM)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exit, err := waitExit(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws := syscall.WaitStatus(exit.ExitStatus); !ws.Signaled() || ws.Signal() != syscall.SIGTERM {
+		t.Errorf("exit: %v", exit)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// handoffGate is held while output is in flight or a process is being
+// started or reaped, a handoff closes it so none of them is cut by exec.
+// Holders only touch atomics while it's open, the lock is taken by
+// those waiting for a handoff to end
+type handoffGate struct {
+	closing int32
+	active  int32
+
+	lock sync.Mutex
+	cond *sync.Cond
+}
+
+var gate = newHandoffGate()
+
+func newHandoffGate() *handoffGate {
+	g := &handoffGate{}
+	g.cond = sync.NewCond(&g.lock)
+	return g
+}
+
+// acquire waits while a handoff is being prepared
+func (g *handoffGate) acquire() {
+	// counted before closing is checked, so close sees either the
+	// holder or acquire sees closing
+	atomic.AddInt32(&g.active, 1)
+	if atomic.LoadInt32(&g.closing) == 0 {
+		return
+	}
+	atomic.AddInt32(&g.active, -1)
+	g.lock.Lock()
+	for atomic.LoadInt32(&g.closing) != 0 {
+		g.cond.Wait()
+	}
+	atomic.AddInt32(&g.active, 1)
+	g.lock.Unlock()
+}
+
+func (g *handoffGate) release() {
+	atomic.AddInt32(&g.active, -1)
+}
+
+// close blocks acquire, calls interrupt to wake up blocked holders and
+// waits for all of them to release within timeout
+func (g *handoffGate) close(timeout time.Duration, interrupt func()) error {
+	g.lock.Lock()
+	atomic.StoreInt32(&g.closing, 1)
+	g.lock.Unlock()
+	interrupt()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		active := atomic.LoadInt32(&g.active)
+		if active <= 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("%d transfers still in flight after %s", active, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (g *handoffGate) isClosing() bool {
+	return atomic.LoadInt32(&g.closing) != 0
+}
+
+func (g *handoffGate) open() {
+	g.lock.Lock()
+	atomic.StoreInt32(&g.closing, 0)
+	g.cond.Broadcast()
+	g.lock.Unlock()
+}
+
+// pumpPipe reads r until an error and passes data to keep and then to
+// send, reads are paused during a handoff, data read is always kept
+// before it, send may block and runs after the gate is released.
+// With delay short reads of a file are coalesced within delay, with
+// reserve reads are limited to the bytes it allows
+func pumpPipe(r io.Reader, data []byte, delay time.Duration, reserve func(int) (int, error), keep func([]byte), send func([]byte) error) (readErr, sendErr error) {
+	f, _ := r.(*os.File)
+	for {
+		buf := data
+		if reserve != nil {
+			n, err := reserve(len(data))
+			if err != nil {
+				return nil, err
+			}
+			buf = data[:n]
+		}
+		gate.acquire()
+		n, err := r.Read(buf)
+		if f != nil && delay > 0 && err == nil && n > 0 && n < len(buf) {
+			n, err = coalesce(f, buf, n, delay)
+		}
+		if n > 0 && keep != nil {
+			keep(buf[:n])
+		}
+		gate.release()
+		if n > 0 && send != nil {
+			if sendErr = send(buf[:n]); sendErr != nil {
+				return nil, sendErr
+			}
+		}
+		if err != nil {
+			if os.IsTimeout(err) {
+				// interrupted by a handoff
+				continue
+			}
+			return err, nil
+		}
+	}
+}
@@ -0,0 +1,686 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/log"
+)
+
+// On upgrade the server execs the new binary in place, keeping its pid
+// so running commands stay its children. The state and the pipes of
+// commands and services are inherited by the new process, which keeps
+// serving their sns
+const (
+	handoffEnv = "EXECUTOR_HANDOFF_FD"
+	// time given to output in flight to be passed on before exec
+	handoffTimeout = 5 * time.Second
+	// time given to clients of streams to be told of a handoff, and
+	// to the transport to write the notices out before exec
+	handoffNoticeTimeout = time.Second
+	handoffNoticeDelay   = 100 * time.Millisecond
+	// time given to clients to come back to commands handed over,
+	// commands of clients not back are killed
+	resumeTimeout = time.Minute
+	// output kept for clients resuming, output in flight on upgrade
+	// beyond it is lost
+	outputTailBytes = 64 << 10
+)
+
+// outputTail keeps the last bytes sent of an output stream, or all
+// bytes not acknowledged by a client with flow control
+type outputTail struct {
+	lock sync.Mutex
+	Sent uint64 `json:"sent"`
+	Data []byte `json:"data,omitempty"`
+	// Acked is set on streams with flow control
+	Acked bool `json:"acked,omitempty"`
+	// skip is acknowledged at the start of Data, dropped when Data
+	// runs out of room
+	skip int
+}
+
+func (t *outputTail) add(data []byte) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.Sent += uint64(len(data))
+	if t.skip > 0 && len(t.Data)+len(data) > cap(t.Data) {
+		n := copy(t.Data, t.Data[t.skip:])
+		t.Data = t.Data[:n]
+		t.skip = 0
+	}
+	t.Data = append(t.Data, data...)
+	if !t.Acked && len(t.Data) > 2*outputTailBytes {
+		n := copy(t.Data, t.Data[len(t.Data)-outputTailBytes:])
+		t.Data = t.Data[:n]
+	}
+}
+
+// ack drops output the client consumed, data is kept from now on
+// until acknowledged
+func (t *outputTail) ack(offset uint64) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.Acked = true
+	first := t.Sent - uint64(len(t.Data)-t.skip)
+	if offset > first && offset <= t.Sent {
+		t.skip += int(offset - first)
+	}
+}
+
+// since returns output sent after offset, missing is the part of it
+// no longer kept
+func (t *outputTail) since(offset uint64) (data []byte, missing uint64) {
+	if t == nil {
+		return nil, 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if offset >= t.Sent {
+		return nil, 0
+	}
+	kept := t.Data[t.skip:]
+	first := t.Sent - uint64(len(kept))
+	if offset < first {
+		return kept, first - offset
+	}
+	return kept[offset-first:], 0
+}
+
+func (t *outputTail) sent() uint64 {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.Sent
+}
+
+// snapshot copies the tail for the handoff
+func (t *outputTail) snapshot() *outputTail {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return &outputTail{
+		Sent:  t.Sent,
+		Data:  append([]byte(nil), t.Data[t.skip:]...),
+		Acked: t.Acked,
+	}
+}
+
+// resumeWait holds a command handed over until its client comes back
+type resumeWait struct {
+	timer   *time.Timer
+	claimed bool
+}
+
+// claimResume stops waiting for the client, false if another client
+// came back first or the command was given up
+func (m *Commander) claimResume() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.resume == nil || m.resume.claimed {
+		return false
+	}
+	m.resume.claimed = true
+	return m.resume.timer.Stop()
+}
+
+// claimLegacy keeps a command handed over for callers of the legacy
+// rpcs, which come back by sn
+func (m *Commander) claimLegacy() {
+	if !m.streamed {
+		m.claimResume()
+	}
+}
+
+// abandon kills a command handed over whose client didn't come back
+func (m *Commander) abandon() {
+	m.lock.Lock()
+	running := m.pid != 0 && m.exitRes == nil
+	m.lock.Unlock()
+	log.Warningf("%d not resumed in %s after upgrade, removed", m.sn, resumeTimeout)
+	if running {
+		if err := m.c.Process.Kill(); err != nil {
+			log.Warningf("%d kill: %s", m.sn, err)
+		}
+		for _, r := range []io.Reader{m.stdout, m.stderr} {
+			if r != nil {
+				go io.Copy(ioutil.Discard, r)
+			}
+		}
+		m.wait()
+	}
+	cmds.Delete(m.sn)
+}
+
+type handoffCommand struct {
+	Sn         uint32       `json:"sn"`
+	Name       string       `json:"name"`
+	Path       string       `json:"path"`
+	Args       []string     `json:"args"`
+	Env        []string     `json:"env,omitempty"`
+	Dir        string       `json:"dir,omitempty"`
+	Cloneflags uintptr      `json:"cloneflags,omitempty"`
+	Trace      TraceContext `json:"trace"`
+	DedupId    string       `json:"dedup_id,omitempty"`
+	Sandbox    *sandboxSpec `json:"sandbox,omitempty"`
+	// not exported by sandboxSpec
+	SeccompProfile string               `json:"seccomp_profile,omitempty"`
+	Landlock       *apis.LandlockStatus `json:"landlock,omitempty"`
+
+	Pid       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// pipe fds, -1 for none
+	Stdin        int                `json:"stdin"`
+	Stdout       int                `json:"stdout"`
+	Stderr       int                `json:"stderr"`
+	StdinPending []byte             `json:"stdin_pending,omitempty"`
+	StdinOffset  uint64             `json:"stdin_offset,omitempty"`
+	Streamed     bool               `json:"streamed,omitempty"`
+	StdoutTail   *outputTail        `json:"stdout_tail,omitempty"`
+	StderrTail   *outputTail        `json:"stderr_tail,omitempty"`
+	Exit         *apis.WaitResponse `json:"exit,omitempty"`
+	// files passed by client to a command not started
+	Files *handoffFiles `json:"files,omitempty"`
+}
+
+// handoffFiles are fds of commandFiles, -1 for none
+type handoffFiles struct {
+	Stdin  int   `json:"stdin"`
+	Stdout int   `json:"stdout"`
+	Stderr int   `json:"stderr"`
+	Extra  []int `json:"extra,omitempty"`
+}
+
+type handoffService struct {
+	Spec       *apis.ServiceSpec `json:"spec"`
+	Path       string            `json:"path"`
+	Env        []string          `json:"env,omitempty"`
+	Sandbox    *sandboxSpec      `json:"sandbox,omitempty"`
+	LogPath    string            `json:"log_path"`
+	State      string            `json:"state"`
+	Pid        int               `json:"pid,omitempty"`
+	Restarts   uint32            `json:"restarts"`
+	StartedAt  time.Time         `json:"started_at"`
+	LastStatus uint32            `json:"last_status"`
+	LastError  string            `json:"last_error,omitempty"`
+	// output pipe fd of the running process, -1 for none
+	Output   int  `json:"output"`
+	Stopping bool `json:"stopping,omitempty"`
+	Finished bool `json:"finished,omitempty"`
+}
+
+type handoffRequest struct {
+	Sn   uint32    `json:"sn"`
+	At   time.Time `json:"at"`
+	Path []byte    `json:"path"`
+	Args [][]byte  `json:"args,omitempty"`
+}
+
+type handoffState struct {
+	StartedAt time.Time                 `json:"started_at"`
+	Sn        uint32                    `json:"sn"`
+	Commands  []*handoffCommand         `json:"commands"`
+	Services  []*handoffService         `json:"services"`
+	Requests  map[string]handoffRequest `json:"requests"`
+	// fds of files added by the caller
+	Files map[string]int `json:"files"`
+	// fds of files passed by clients and not claimed yet, by token
+	PassedFiles map[string][]int `json:"passed_files,omitempty"`
+}
+
+// Handoff is the state passed to the binary exec'ed on upgrade
+type Handoff struct {
+	state handoffState
+	// fds inherited by the new process
+	fds []int
+	// files added by the caller, closed if exec fails
+	added []*os.File
+	files map[string]*os.File
+}
+
+// handoffNotices holds the senders of streams whose clients are told
+// of a handoff, they resume commands only when told so
+var handoffNotices sync.Map
+
+type handoffNotice struct {
+	send func(*apis.ExecResponse) error
+}
+
+// noticeHandoff registers send to tell the client of the stream of a
+// handoff, the returned func unregisters it
+func noticeHandoff(send func(*apis.ExecResponse) error) func() {
+	n := &handoffNotice{send: send}
+	handoffNotices.Store(n, struct{}{})
+	return func() {
+		handoffNotices.Delete(n)
+	}
+}
+
+// notifyHandoff tells the clients of streams whether the server is
+// about to be upgraded, clients not told within timeout fail when
+// their stream breaks
+func notifyHandoff(handoff bool, timeout time.Duration) {
+	frame := &apis.ExecResponse{Response: &apis.ExecResponse_Handoff{Handoff: handoff}}
+	var wg sync.WaitGroup
+	handoffNotices.Range(func(key, value interface{}) bool {
+		wg.Add(1)
+		go func(n *handoffNotice) {
+			defer wg.Done()
+			n.send(frame)
+		}(key.(*handoffNotice))
+		return true
+	})
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warningf("Clients of streams not told of handoff in %s", timeout)
+	}
+}
+
+// pipeFiles returns the pipe files of the command
+func (m *Commander) pipeFiles() []*os.File {
+	var files []*os.File
+	for _, p := range []interface{}{m.stdin, m.stdout, m.stderr} {
+		if f, ok := p.(*os.File); ok {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// setPipeDeadlines wakes up or restores transfers on the pipes of
+// commands and services
+func setPipeDeadlines(t time.Time) {
+	cmds.Range(func(key, value interface{}) bool {
+		for _, f := range value.(*Commander).pipeFiles() {
+			f.SetDeadline(t)
+		}
+		return true
+	})
+	servicesLock.Lock()
+	for _, s := range services {
+		s.lock.Lock()
+		if s.output != nil {
+			s.output.SetDeadline(t)
+		}
+		s.lock.Unlock()
+	}
+	servicesLock.Unlock()
+}
+
+// PrepareHandoff pauses transfers of commands and services and takes
+// their state, Exec or Abort must follow
+func PrepareHandoff() (*Handoff, error) {
+	if err := handoffSupported(); err != nil {
+		return nil, err
+	}
+	err := gate.close(handoffTimeout, func() {
+		setPipeDeadlines(time.Now())
+	})
+	if err != nil {
+		setPipeDeadlines(time.Time{})
+		gate.open()
+		return nil, errors.Wrap(err, "pause transfers")
+	}
+	h := &Handoff{state: handoffState{
+		StartedAt: serverStartedAt,
+		Sn:        atomic.LoadUint32(&globalSn),
+		Requests:  map[string]handoffRequest{},
+		Files:     map[string]int{},
+	}}
+	cmds.Range(func(key, value interface{}) bool {
+		h.state.Commands = append(h.state.Commands, h.command(value.(*Commander)))
+		return true
+	})
+	servicesLock.Lock()
+	for _, s := range services {
+		h.state.Services = append(h.state.Services, h.service(s))
+	}
+	servicesLock.Unlock()
+	requests.lock.Lock()
+	for id, ent := range requests.requests {
+		if ent.sn != 0 {
+			h.state.Requests[id] = handoffRequest{Sn: ent.sn, At: ent.at, Path: ent.path, Args: ent.args}
+		}
+	}
+	requests.lock.Unlock()
+	h.state.PassedFiles = passedFiles.handoff(h)
+	notifyHandoff(true, handoffNoticeTimeout)
+	// sends only queue the notices on the transport
+	time.Sleep(handoffNoticeDelay)
+	return h, nil
+}
+
+func fileFd(f *os.File) (int, error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+	fd := -1
+	// Fd() would put the file in blocking mode
+	err = rc.Control(func(s uintptr) {
+		fd = int(s)
+	})
+	return fd, err
+}
+
+// inherit marks f to be inherited by the new process, -1 if f is nil
+// or closed
+func (h *Handoff) inherit(f interface{}) int {
+	file, ok := f.(*os.File)
+	if !ok || file == nil {
+		return -1
+	}
+	fd, err := fileFd(file)
+	if err != nil {
+		return -1
+	}
+	h.fds = append(h.fds, fd)
+	return fd
+}
+
+func (h *Handoff) command(m *Commander) *handoffCommand {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	hc := &handoffCommand{
+		Sn:          m.sn,
+		Name:        m.name,
+		Path:        m.c.Path,
+		Args:        m.c.Args,
+		Env:         m.c.Env,
+		Dir:         m.c.Dir,
+		Trace:       m.trace,
+		DedupId:     m.dedupId,
+		Sandbox:     m.sandbox,
+		Pid:         m.pid,
+		StartedAt:   m.startedAt,
+		Stdin:       -1,
+		Stdout:      -1,
+		Stderr:      -1,
+		Streamed:    m.streamed,
+		StdoutTail:  m.stdoutTail.snapshot(),
+		StderrTail:  m.stderrTail.snapshot(),
+		Exit:        m.exitRes,
+		StdinOffset: m.stdinOffset,
+	}
+	if m.window == 0 {
+		// clients with flow control send again stdin not written
+		hc.StdinPending = append(append([]byte(nil), m.stdinPending...), m.stdinQueue.pending()...)
+	}
+	if m.c.SysProcAttr != nil {
+		hc.Cloneflags = cloneflags(m.c.SysProcAttr)
+	}
+	if m.sandbox != nil {
+		hc.SeccompProfile = m.sandbox.seccompProfile
+		hc.Landlock = m.sandbox.landlock
+	}
+	if m.pid != 0 {
+		// pipes of a command not started are created again by Start
+		hc.Stdin = h.inherit(m.stdin)
+		hc.Stdout = h.inherit(m.stdout)
+		hc.Stderr = h.inherit(m.stderr)
+	} else if f := m.files; f != nil {
+		// passed files can't, they are closed once the process started
+		hc.Files = &handoffFiles{
+			Stdin:  h.inherit(f.stdin),
+			Stdout: h.inherit(f.stdout),
+			Stderr: h.inherit(f.stderr),
+		}
+		for _, extra := range f.extra {
+			hc.Files.Extra = append(hc.Files.Extra, h.inherit(extra))
+		}
+	}
+	return hc
+}
+
+func (h *Handoff) service(s *service) *handoffService {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hs := &handoffService{
+		Spec:       s.spec,
+		Path:       s.path,
+		Env:        s.env,
+		Sandbox:    s.sandbox,
+		LogPath:    s.logPath,
+		State:      s.state,
+		Pid:        s.pid,
+		Restarts:   s.restarts,
+		StartedAt:  s.startedAt,
+		LastStatus: s.lastStatus,
+		LastError:  s.lastError,
+		Output:     -1,
+		Finished:   s.finished(),
+	}
+	select {
+	case <-s.stopCh:
+		hs.Stopping = true
+	default:
+	}
+	if s.pid != 0 && s.output != nil {
+		hs.Output = h.inherit(s.output)
+	}
+	return hs
+}
+
+// AddFile passes f to the new process, which gets it by File(name),
+// f is closed if Exec fails
+func (h *Handoff) AddFile(name string, f *os.File) error {
+	fd, err := fileFd(f)
+	if err != nil {
+		return errors.Wrapf(err, "handoff %s", name)
+	}
+	h.added = append(h.added, f)
+	h.fds = append(h.fds, fd)
+	h.state.Files[name] = fd
+	return nil
+}
+
+// Exec replaces the server with binary path, it only returns on
+// failure, after resuming transfers
+func (h *Handoff) Exec(path string, args []string) error {
+	err := h.exec(path, args)
+	h.Abort()
+	return err
+}
+
+func (h *Handoff) exec(path string, args []string) error {
+	file, err := ioutil.TempFile("", "executor-handoff")
+	if err != nil {
+		return errors.Wrap(err, "create handoff file")
+	}
+	os.Remove(file.Name())
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(&h.state); err != nil {
+		return errors.Wrap(err, "write handoff file")
+	}
+	fd, err := fileFd(file)
+	if err != nil {
+		return err
+	}
+	fds := append([]int{fd}, h.fds...)
+	for _, fd := range fds {
+		if err := setInheritable(fd, true); err != nil {
+			return errors.Wrapf(err, "inherit fd %d", fd)
+		}
+	}
+	defer func() {
+		for _, fd := range fds {
+			setInheritable(fd, false)
+		}
+	}()
+
+	env := os.Environ()
+	for i := range env {
+		if strings.HasPrefix(env[i], handoffEnv+"=") {
+			env = append(env[:i], env[i+1:]...)
+			break
+		}
+	}
+	env = append(env, handoffEnv+"="+strconv.Itoa(fd))
+	log.Infof("Handoff %d commands and %d services to %s", len(h.state.Commands), len(h.state.Services), path)
+	err = syscall.Exec(path, args, env)
+	return errors.Wrapf(err, "exec %s", path)
+}
+
+// Abort resumes transfers of a handoff not executed
+func (h *Handoff) Abort() {
+	for _, f := range h.added {
+		f.Close()
+	}
+	h.added = nil
+	setPipeDeadlines(time.Time{})
+	gate.open()
+	notifyHandoff(false, handoffNoticeTimeout)
+}
+
+// File returns the file added by name by the previous server
+func (h *Handoff) File(name string) *os.File {
+	return h.files[name]
+}
+
+// inherited wraps fd inherited from the previous server, nil for -1
+func inherited(fd int, name string) *os.File {
+	if fd < 0 {
+		return nil
+	}
+	// not to leak into commands started by this server
+	syscall.CloseOnExec(fd)
+	return os.NewFile(uintptr(fd), name)
+}
+
+// RestoreHandoff takes over the commands and services of the previous
+// server, nil if this process isn't an upgrade
+func RestoreHandoff() (*Handoff, error) {
+	val := os.Getenv(handoffEnv)
+	if len(val) == 0 {
+		return nil, nil
+	}
+	os.Unsetenv(handoffEnv)
+	fd, err := strconv.Atoi(val)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", handoffEnv)
+	}
+	file := inherited(fd, "handoff")
+	defer file.Close()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "read handoff file")
+	}
+	h := &Handoff{files: map[string]*os.File{}}
+	if err := json.NewDecoder(file).Decode(&h.state); err != nil {
+		return nil, errors.Wrap(err, "decode handoff file")
+	}
+
+	serverStartedAt = h.state.StartedAt
+	atomic.StoreUint32(&globalSn, h.state.Sn)
+	for id, req := range h.state.Requests {
+		requests.add(id, &dedupEntry{sn: req.Sn, at: req.At, path: req.Path, args: req.Args})
+	}
+	for name, fd := range h.state.Files {
+		h.files[name] = inherited(fd, name)
+	}
+	for token, fds := range h.state.PassedFiles {
+		files := make([]*os.File, len(fds))
+		for i, fd := range fds {
+			files[i] = inherited(fd, "passed")
+		}
+		passedFiles.keep(token, files)
+	}
+	for _, hc := range h.state.Commands {
+		restoreCommand(hc)
+	}
+	for _, hs := range h.state.Services {
+		restoreService(hs)
+	}
+	log.Infof("Handed over %d commands and %d services", len(h.state.Commands), len(h.state.Services))
+	return h, nil
+}
+
+func restoreCommand(hc *handoffCommand) {
+	m := &Commander{
+		c: &exec.Cmd{
+			Path: hc.Path,
+			Args: hc.Args,
+			Env:  hc.Env,
+			Dir:  hc.Dir,
+		},
+		sn:           hc.Sn,
+		name:         commandLabel(hc.Path),
+		wg:           new(sync.WaitGroup),
+		startedAt:    hc.StartedAt,
+		trace:        hc.Trace,
+		dedupId:      hc.DedupId,
+		sandbox:      hc.Sandbox,
+		streamed:     hc.Streamed,
+		stdoutTail:   hc.StdoutTail,
+		stderrTail:   hc.StderrTail,
+		stdinPending: hc.StdinPending,
+		stdinOffset:  hc.StdinOffset,
+		pid:          hc.Pid,
+		exitRes:      hc.Exit,
+	}
+	if m.sandbox != nil {
+		m.sandbox.seccompProfile = hc.SeccompProfile
+		m.sandbox.landlock = hc.Landlock
+	}
+	if hc.Pid == 0 {
+		m.c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		setCloneflagsRaw(m.c.SysProcAttr, hc.Cloneflags)
+		if hf := hc.Files; hf != nil {
+			m.files = &commandFiles{
+				stdin:  inherited(hf.Stdin, "stdin"),
+				stdout: inherited(hf.Stdout, "stdout"),
+				stderr: inherited(hf.Stderr, "stderr"),
+			}
+			for _, fd := range hf.Extra {
+				m.files.extra = append(m.files.extra, inherited(fd, "extra"))
+			}
+			m.c.ExtraFiles = m.files.extra
+		}
+	} else {
+		m.c.Process, _ = os.FindProcess(hc.Pid)
+		m.adopted = hc.Exit == nil
+		if m.adopted && !m.startedAt.IsZero() {
+			observeAdopted()
+		}
+		if stdin := inherited(hc.Stdin, "stdin"); stdin != nil {
+			m.stdin = stdin
+		}
+		if stdout := inherited(hc.Stdout, "stdout"); stdout != nil {
+			m.stdout = stdout
+			m.stdoutCh = make(chan struct{})
+		}
+		if stderr := inherited(hc.Stderr, "stderr"); stderr != nil {
+			m.stderr = stderr
+			m.stderrCh = make(chan struct{})
+		}
+	}
+	m.resume = &resumeWait{timer: time.AfterFunc(resumeTimeout, m.abandon)}
+	cmds.Store(m.sn, m)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const pPid = 1
+
+func handoffSupported() error {
+	return nil
+}
+
+func setInheritable(fd int, inheritable bool) error {
+	flags := unix.FD_CLOEXEC
+	if inheritable {
+		flags = 0
+	}
+	_, err := unix.FcntlInt(uintptr(fd), unix.F_SETFD, flags)
+	return err
+}
+
+// waitExited blocks until pid exits, leaving it to be reaped
+func waitExited(pid int) {
+	// siginfo_t
+	var info [128]byte
+	for {
+		_, _, errno := unix.Syscall6(unix.SYS_WAITID, pPid, uintptr(pid),
+			uintptr(unsafe.Pointer(&info[0])), unix.WEXITED|unix.WNOWAIT, 0, 0)
+		if errno != unix.EINTR {
+			return
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package server
+
+import (
+	"github.com/pkg/errors"
+)
+
+func handoffSupported() error {
+	return errors.New("upgrade is only supported on linux")
+}
+
+func setInheritable(fd int, inheritable bool) error {
+	return handoffSupported()
+}
+
+func waitExited(pid int) {
+}
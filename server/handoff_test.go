@@ -0,0 +1,336 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"yunion.io/x/executor/apis"
+)
+
+// roundTrip encodes state as Exec does and decodes it as RestoreHandoff
+func roundTrip(t *testing.T, state *handoffState) *handoffState {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(state); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &handoffState{}
+	if err := json.NewDecoder(&buf).Decode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// dupFd stands in for fd inherited by the new process
+func dupFd(t *testing.T, fd int) int {
+	if fd < 0 {
+		return fd
+	}
+	nfd, err := syscall.Dup(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nfd
+}
+
+func TestHandoffCommandRoundTrip(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	proc := exec.Command("/bin/sleep", "60")
+	if err := proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer proc.Wait()
+	defer proc.Process.Kill()
+
+	m := &Commander{
+		c:           proc,
+		sn:          1<<31 - 2,
+		name:        "sleep",
+		stdout:      r,
+		startedAt:   time.Now().Truncate(time.Second),
+		trace:       TraceContext{RequestId: "handoff-test"},
+		dedupId:     "handoff-dedup",
+		streamed:    true,
+		stdoutTail:  &outputTail{Sent: 10, Data: []byte("0123456789"), Acked: true, skip: 4},
+		stderrTail:  new(outputTail),
+		stdinOffset: 7,
+		pid:         proc.Process.Pid,
+	}
+	h := &Handoff{}
+	h.state.Commands = append(h.state.Commands, h.command(m))
+	state := roundTrip(t, &h.state)
+	if len(state.Commands) != 1 {
+		t.Fatalf("%d commands handed over", len(state.Commands))
+	}
+	hc := state.Commands[0]
+	if hc.Stdin != -1 || hc.Stderr != -1 {
+		t.Errorf("pipes not passed: stdin %d stderr %d", hc.Stdin, hc.Stderr)
+	}
+	if !reflect.DeepEqual(h.fds, []int{hc.Stdout}) {
+		t.Errorf("inherited fds %v, want stdout %d", h.fds, hc.Stdout)
+	}
+	hc.Stdout = dupFd(t, hc.Stdout)
+
+	restoreCommand(hc)
+	v, ok := cmds.Load(m.sn)
+	if !ok {
+		t.Fatal("command not restored")
+	}
+	restored := v.(*Commander)
+	defer func() {
+		restored.resume.timer.Stop()
+		cmds.Delete(m.sn)
+		commandsRunning.Dec()
+		restored.stdout.Close()
+	}()
+	if restored.pid != m.pid || restored.c.Process == nil || restored.c.Process.Pid != m.pid {
+		t.Errorf("pid: got %d, want %d", restored.pid, m.pid)
+	}
+	if !restored.adopted || !restored.streamed || restored.resume == nil {
+		t.Errorf("adopted %v streamed %v resume %v", restored.adopted, restored.streamed, restored.resume)
+	}
+	if restored.trace.RequestId != "handoff-test" || restored.dedupId != "handoff-dedup" || restored.stdinOffset != 7 || !restored.startedAt.Equal(m.startedAt) {
+		t.Errorf("restored %+v", restored)
+	}
+	// acknowledged output is not passed on
+	if tail := restored.stdoutTail; tail.Sent != 10 || string(tail.Data) != "456789" || !tail.Acked {
+		t.Errorf("stdout tail: %+v", tail)
+	}
+
+	// the inherited pipe reads what the process writes
+	w.Write([]byte("out"))
+	data := make([]byte, 3)
+	if _, err := restored.stdout.Read(data); err != nil || string(data) != "out" {
+		t.Errorf("read inherited stdout: %q %v", data, err)
+	}
+}
+
+func TestHandoffCommandNotStarted(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	extra, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Commander{
+		c:          &exec.Cmd{Path: "/bin/true", Args: []string{"true"}},
+		sn:         1<<31 - 3,
+		stdoutTail: new(outputTail),
+		stdinQueue: newStdinQueue(1<<10, 0),
+		files:      &commandFiles{stdout: w, extra: []*os.File{extra}},
+	}
+	defer m.files.close()
+	m.stdinQueue.push([]byte("queued"))
+	m.stdinPending = []byte("pending ")
+	h := &Handoff{}
+	hc := h.command(m)
+	if hc.Stdout != -1 {
+		t.Errorf("pipes of a command not started passed: %v", h.fds)
+	}
+	// stdin without flow control is written by the new server
+	if string(hc.StdinPending) != "pending queued" {
+		t.Errorf("stdin pending: %q", hc.StdinPending)
+	}
+	// files passed by client are not opened again by Start
+	if hf := hc.Files; hf == nil || hf.Stdin != -1 || hf.Stderr != -1 || len(hf.Extra) != 1 ||
+		!reflect.DeepEqual(h.fds, []int{hf.Stdout, hf.Extra[0]}) {
+		t.Fatalf("passed files %+v, inherited %v", hc.Files, h.fds)
+	}
+
+	state := roundTrip(t, &handoffState{Commands: []*handoffCommand{hc}})
+	hf := state.Commands[0].Files
+	hf.Stdout = dupFd(t, hf.Stdout)
+	hf.Extra[0] = dupFd(t, hf.Extra[0])
+	restoreCommand(state.Commands[0])
+	v, ok := cmds.Load(m.sn)
+	if !ok {
+		t.Fatal("command not restored")
+	}
+	restored := v.(*Commander)
+	defer func() {
+		restored.resume.timer.Stop()
+		cmds.Delete(m.sn)
+		restored.files.close()
+	}()
+	if restored.adopted || restored.c.SysProcAttr == nil || restored.stdout != nil {
+		t.Errorf("not started command restored as %+v", restored)
+	}
+	if string(restored.stdinPending) != "pending queued" {
+		t.Errorf("restored stdin pending: %q", restored.stdinPending)
+	}
+	f := restored.files
+	if f == nil || f.stdin != nil || f.stdout == nil || len(f.extra) != 1 ||
+		!reflect.DeepEqual(restored.c.ExtraFiles, f.extra) {
+		t.Fatalf("restored files %+v", f)
+	}
+	if _, err := f.stdout.Write([]byte("out")); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 3)
+	if _, err := r.Read(data); err != nil || string(data) != "out" {
+		t.Errorf("read of restored stdout: %q %v", data, err)
+	}
+}
+
+func TestHandoffServiceRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "handoff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	proc := exec.Command("/bin/sh", "-c", "sleep 0.2; echo handed over")
+	proc.Stdout = w
+	if err := proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	spec := &apis.ServiceSpec{
+		Name:    "test-handoff",
+		Command: &apis.Command{Path: []byte("/bin/sh")},
+	}
+	s := &service{
+		spec:       spec,
+		path:       "/bin/sh",
+		logPath:    filepath.Join(dir, "test-handoff.log"),
+		stopCh:     make(chan struct{}),
+		state:      ServiceRunning,
+		pid:        proc.Process.Pid,
+		output:     r,
+		restarts:   3,
+		startedAt:  time.Now().Truncate(time.Second),
+		lastStatus: 256,
+		lastError:  "exit status 1",
+	}
+	h := &Handoff{}
+	hs := h.service(s)
+	if hs.Output < 0 || !reflect.DeepEqual(h.fds, []int{hs.Output}) {
+		t.Fatalf("output fd %d, inherited %v", hs.Output, h.fds)
+	}
+	state := roundTrip(t, &handoffState{Services: []*handoffService{hs}})
+	hs = state.Services[0]
+	hs.Output = dupFd(t, hs.Output)
+
+	restoreService(hs)
+	servicesLock.Lock()
+	restored := services[spec.Name]
+	servicesLock.Unlock()
+	defer func() {
+		servicesLock.Lock()
+		delete(services, spec.Name)
+		servicesLock.Unlock()
+	}()
+	if restored == nil {
+		t.Fatal("service not restored")
+	}
+	// the adopted process is reaped, its output logged
+	select {
+	case <-restored.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("adopted service not reaped")
+	}
+	restored.lock.Lock()
+	defer restored.lock.Unlock()
+	if restored.restarts != 3 || restored.state != ServiceExited || !restored.startedAt.Equal(s.startedAt) {
+		t.Errorf("restored service: state %s restarts %d started %v", restored.state, restored.restarts, restored.startedAt)
+	}
+	if got := readLogFile(t, s.logPath); got != "handed over\n" {
+		t.Errorf("service log: got %q", got)
+	}
+}
+
+func TestNotifyHandoff(t *testing.T) {
+	notices := make(chan bool, 3)
+	unregister := noticeHandoff(func(res *apis.ExecResponse) error {
+		notices <- res.GetHandoff()
+		return nil
+	})
+	blocked := make(chan struct{})
+	defer close(blocked)
+	defer noticeHandoff(func(res *apis.ExecResponse) error {
+		// a client not reading doesn't hold up the handoff
+		<-blocked
+		return nil
+	})()
+
+	begin := time.Now()
+	notifyHandoff(true, 100*time.Millisecond)
+	if d := time.Since(begin); d > time.Second {
+		t.Errorf("notified in %s", d)
+	}
+	notifyHandoff(false, 100*time.Millisecond)
+	unregister()
+	notifyHandoff(true, 100*time.Millisecond)
+	got := []bool{<-notices, <-notices}
+	if !reflect.DeepEqual(got, []bool{true, false}) {
+		t.Errorf("notices: got %v", got)
+	}
+	select {
+	case <-notices:
+		t.Errorf("notice after unregistered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHandoffGate(t *testing.T) {
+	g := newHandoffGate()
+	g.acquire()
+	interrupted := make(chan struct{})
+	closed := make(chan error, 1)
+	go func() {
+		closed <- g.close(time.Second, func() { close(interrupted) })
+	}()
+	<-interrupted
+	// holders are waited for
+	select {
+	case err := <-closed:
+		t.Fatalf("closed with a holder: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	acquired := make(chan struct{})
+	go func() {
+		g.acquire()
+		close(acquired)
+	}()
+	g.release()
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-acquired:
+		t.Fatal("acquired while closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	g.open()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire not woken up by open")
+	}
+	// held past the timeout
+	if err := g.close(20*time.Millisecond, func() {}); err == nil {
+		t.Error("closed with a holder left")
+	}
+	g.open()
+	g.release()
+}
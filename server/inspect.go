@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// procStatus returns fields of /proc/<pid>/status
+func procStatus(pid int) (map[string]string, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// firstId parses the real id of Uid and Gid lines
+func firstId(s string) uint32 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(fields[0], 10, 32)
+	return uint32(id)
+}
+
+func statusCaps(s string) []string {
+	mask, _ := strconv.ParseUint(s, 16, 64)
+	return capMaskNames(mask)
+}
+
+func (e *Executor) Inspect(ctx context.Context, sn *apis.Sn) (*apis.InspectResponse, error) {
+	m, err := loadCommander(sn.Sn)
+	if err != nil {
+		return nil, err
+	}
+	info := m.info()
+	if info.Pid == 0 {
+		return nil, errors.Errorf("sn %d not started", sn.Sn)
+	}
+	st, err := procStatus(int(info.Pid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "sn %d", sn.Sn)
+	}
+	res := &apis.InspectResponse{
+		Process:        info,
+		Uid:            firstId(st["Uid"]),
+		Gid:            firstId(st["Gid"]),
+		CapEffective:   statusCaps(st["CapEff"]),
+		CapPermitted:   statusCaps(st["CapPrm"]),
+		CapInheritable: statusCaps(st["CapInh"]),
+		CapBounding:    statusCaps(st["CapBnd"]),
+		CapAmbient:     statusCaps(st["CapAmb"]),
+		NoNewPrivs:     st["NoNewPrivs"] == "1",
+	}
+	if mode, err := strconv.Atoi(st["Seccomp"]); err == nil {
+		res.SeccompMode = int32(mode)
+	}
+	if m.sandbox != nil {
+		res.SeccompProfile = m.sandbox.seccompProfile
+	}
+	if umask, err := strconv.ParseUint(st["Umask"], 8, 32); err == nil {
+		res.Umask = uint32(umask)
+	}
+	res.Cpus = parseCpuList(st["Cpus_allowed_list"])
+	if nice, err := procNice(int(info.Pid)); err == nil {
+		res.Nice = int32(nice)
+	}
+	if data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", info.Pid)); err == nil {
+		adj, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		res.OomScoreAdj = int32(adj)
+	}
+	res.Namespaces = isolatedNamespaces(int(info.Pid))
+	res.Landlock = m.landlockStatus()
+	res.Flow = m.flowStatus()
+	if prio, err := ioprioGet(int(info.Pid)); err == nil {
+		res.IoClass = apis.IoClass(prio >> ioprioClassShift)
+		res.IoPriority = int32(prio & (1<<ioprioClassShift - 1))
+	}
+	return res, nil
+}
+
+var namespaceTypes = []string{"cgroup", "ipc", "mnt", "net", "pid", "user", "uts"}
+
+// isolatedNamespaces are namespaces of pid not shared with the server
+func isolatedNamespaces(pid int) []string {
+	var res []string
+	for _, ns := range namespaceTypes {
+		self, err := os.Readlink("/proc/self/ns/" + ns)
+		if err != nil {
+			continue
+		}
+		other, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/%s", pid, ns))
+		if err == nil && other != self {
+			res = append(res, ns)
+		}
+	}
+	return res
+}
+
+// procNice is the 19th field of /proc/<pid>/stat
+func procNice(pid int) (int, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// comm may contain spaces, fields after it start with state
+	i := strings.LastIndexByte(string(data), ')')
+	fields := strings.Fields(string(data[i+1:]))
+	if i < 0 || len(fields) < 17 {
+		return 0, errors.Errorf("invalid stat of pid %d", pid)
+	}
+	return strconv.Atoi(fields[16])
+}
+
+// parseCpuList parses lists like 0-3,6
+func parseCpuList(s string) []uint32 {
+	var cpus []uint32
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.ParseUint(bounds[1], 10, 32); err != nil {
+				continue
+			}
+		}
+		if last >= maxCpus {
+			last = maxCpus - 1
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, uint32(cpu))
+		}
+	}
+	return cpus
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// isolationSpec is set up by the sandbox helper, running as root in
+// the new namespaces before switching user
+type isolationSpec struct {
+	Loopback bool `json:"loopback,omitempty"`
+	// Proc mounts proc of the new pid namespace on /proc
+	Proc       bool     `json:"proc,omitempty"`
+	ReadOnly   []string `json:"read_only,omitempty"`
+	PrivateTmp bool     `json:"private_tmp,omitempty"`
+}
+
+func isolationMount(in *apis.Isolation) bool {
+	return in.Mount || in.Pid || len(in.ReadOnlyPaths) > 0 || in.PrivateTmp
+}
+
+func isolationEnabled(in *apis.Isolation) bool {
+	return in != nil && (in.Net || isolationMount(in))
+}
+
+func newIsolationSpec(in *apis.Isolation) (*isolationSpec, error) {
+	spec := &isolationSpec{
+		Loopback:   in.Net,
+		Proc:       in.Pid,
+		PrivateTmp: in.PrivateTmp,
+	}
+	for _, path := range in.ReadOnlyPaths {
+		if !filepath.IsAbs(path) {
+			return nil, errors.Errorf("read-only path %q is not absolute", path)
+		}
+		spec.ReadOnly = append(spec.ReadOnly, filepath.Clean(path))
+	}
+	return spec, nil
+}
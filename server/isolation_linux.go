@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"yunion.io/x/executor/apis"
+)
+
+// setCloneflags makes the command start in new namespaces of in
+func setCloneflags(attr *syscall.SysProcAttr, in *apis.Isolation) {
+	if !isolationEnabled(in) {
+		return
+	}
+	if in.Net {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if isolationMount(in) {
+		attr.Cloneflags |= syscall.CLONE_NEWNS
+	}
+	if in.Pid {
+		attr.Cloneflags |= syscall.CLONE_NEWPID
+	}
+}
+
+func cloneflags(attr *syscall.SysProcAttr) uintptr {
+	return attr.Cloneflags
+}
+
+// setCloneflagsRaw restores flags taken by cloneflags
+func setCloneflagsRaw(attr *syscall.SysProcAttr, flags uintptr) {
+	attr.Cloneflags = flags
+}
+
+// setupIsolation configures the new namespaces the helper runs in,
+// read-only paths go first so /tmp and /proc stay writable
+func setupIsolation(spec *isolationSpec) error {
+	if spec.Loopback {
+		if err := loopbackUp(); err != nil {
+			return errors.Wrap(err, "set up loopback")
+		}
+	}
+	if len(spec.ReadOnly) == 0 && !spec.PrivateTmp && !spec.Proc {
+		return nil
+	}
+	// keep mounts from propagating to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return errors.Wrap(err, "make mounts private")
+	}
+	for _, path := range spec.ReadOnly {
+		if err := mountReadOnly(path); err != nil {
+			return errors.Wrapf(err, "read-only %s", path)
+		}
+	}
+	if spec.PrivateTmp {
+		if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+			return errors.Wrap(err, "mount tmpfs on /tmp")
+		}
+	}
+	if spec.Proc {
+		if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+			return errors.Wrap(err, "mount /proc")
+		}
+	}
+	// the working directory may be below a mount made after chdir
+	if wd, err := os.Getwd(); err == nil {
+		os.Chdir(wd)
+	}
+	return nil
+}
+
+// mountReadOnly binds path if it isn't a mount point and remounts it
+// and the mounts below it read-only, the root mount is remounted in
+// place as a bind over it isn't seen through the process root
+func mountReadOnly(path string) error {
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	if !mounts[path] {
+		if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return err
+		}
+		if mounts, err = mountPoints(); err != nil {
+			return err
+		}
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for mp := range mounts {
+		if mp != path && !strings.HasPrefix(mp, prefix) {
+			continue
+		}
+		var st unix.Statfs_t
+		if err := unix.Statfs(mp, &st); err != nil {
+			// e.g. a mount point hidden by another mount
+			continue
+		}
+		// flags of the mount are kept, remount would clear them
+		flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+		for _, f := range [][2]uintptr{
+			{unix.ST_NOSUID, unix.MS_NOSUID},
+			{unix.ST_NODEV, unix.MS_NODEV},
+			{unix.ST_NOEXEC, unix.MS_NOEXEC},
+			{unix.ST_NOATIME, unix.MS_NOATIME},
+			{unix.ST_NODIRATIME, unix.MS_NODIRATIME},
+			{unix.ST_RELATIME, unix.MS_RELATIME},
+		} {
+			if uintptr(st.Flags)&f[0] != 0 {
+				flags |= f[1]
+			}
+		}
+		if err := unix.Mount("", mp, "", flags, ""); err != nil {
+			return errors.Wrapf(err, "remount %s", mp)
+		}
+	}
+	return nil
+}
+
+// mountPoints are the mount points of /proc/self/mountinfo
+func mountPoints() (map[string]bool, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mounts := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 4 {
+			mounts[unescapeMountPath(fields[4])] = true
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes octal escapes like \040 of mountinfo
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// loopbackUp sets IFF_UP on lo of the new network namespace
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	var ifr struct {
+		name  [unix.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(ifr.name[:], "lo")
+	if _, _, errno := syscall.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&ifr))); errno != 0 {
+		return errno
+	}
+	ifr.flags |= unix.IFF_UP
+	if _, _, errno := syscall.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&ifr))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestIsolationClonedForHelper(t *testing.T) {
+	e := &Executor{}
+	in := &apis.Command{
+		Path:      []byte("/bin/true"),
+		Isolation: &apis.Isolation{Net: true, ReadOnlyPaths: []string{"/usr/../etc"}},
+	}
+	spec, err := e.sandboxSpec(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spec.Isolation.Loopback || len(spec.Isolation.ReadOnly) != 1 || spec.Isolation.ReadOnly[0] != "/etc" {
+		t.Errorf("isolation spec: got %+v", spec.Isolation)
+	}
+
+	cmd := exec.Command("/bin/true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := spec.wrap(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if want := uintptr(syscall.CLONE_NEWNET | syscall.CLONE_NEWNS); cmd.SysProcAttr.Cloneflags != want {
+		t.Errorf("cloneflags with helper: got %#x, want %#x", cmd.SysProcAttr.Cloneflags, want)
+	}
+
+	// without the helper nobody sets up the namespaces
+	cmd = &exec.Cmd{Path: "no-such-command", SysProcAttr: &syscall.SysProcAttr{Setsid: true}}
+	if err := spec.wrap(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.SysProcAttr.Cloneflags != 0 {
+		t.Errorf("cloneflags without helper: got %#x", cmd.SysProcAttr.Cloneflags)
+	}
+
+	if _, err := newIsolationSpec(&apis.Isolation{ReadOnlyPaths: []string{"etc"}}); err == nil {
+		t.Errorf("relative read-only path accepted")
+	}
+}
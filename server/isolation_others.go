@@ -0,0 +1,25 @@
+// +build !linux
+
+package server
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+func setCloneflags(attr *syscall.SysProcAttr, in *apis.Isolation) {
+}
+
+func setupIsolation(spec *isolationSpec) error {
+	return errors.New("namespaces are only supported on linux")
+}
+
+func cloneflags(attr *syscall.SysProcAttr) uintptr {
+	return 0
+}
+
+func setCloneflagsRaw(attr *syscall.SysProcAttr, flags uintptr) {
+}
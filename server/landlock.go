@@ -0,0 +1,68 @@
+package server
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// landlockSystemPaths are read and executed by most commands, missing
+// ones are skipped
+var landlockSystemPaths = []string{
+	"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64",
+	"/etc/ld.so.cache", "/etc/ld.so.conf", "/etc/ld.so.conf.d", "/etc/localtime",
+	"/etc/passwd", "/etc/group", "/etc/nsswitch.conf",
+	"/dev/zero", "/dev/urandom",
+}
+
+type landlockRule struct {
+	Path     string `json:"path"`
+	Write    bool   `json:"write,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// landlockSpec is applied by the sandbox helper after switching user
+type landlockSpec struct {
+	Rules    []landlockRule `json:"rules"`
+	Required bool           `json:"required,omitempty"`
+}
+
+func newLandlockSpec(in *apis.Landlock) (*landlockSpec, error) {
+	spec := &landlockSpec{Required: in.Required}
+	add := func(paths []string, write bool) error {
+		for _, path := range paths {
+			if !filepath.IsAbs(path) {
+				return errors.Errorf("landlock path %q is not absolute", path)
+			}
+			spec.Rules = append(spec.Rules, landlockRule{Path: filepath.Clean(path), Write: write})
+		}
+		return nil
+	}
+	if err := add(in.ReadPaths, false); err != nil {
+		return nil, err
+	}
+	if err := add(in.WritePaths, true); err != nil {
+		return nil, err
+	}
+	if in.SystemPaths {
+		for _, path := range landlockSystemPaths {
+			spec.Rules = append(spec.Rules, landlockRule{Path: path, Optional: true})
+		}
+		spec.Rules = append(spec.Rules, landlockRule{Path: "/dev/null", Write: true, Optional: true})
+	}
+	return spec, nil
+}
+
+func landlockEnabled(in *apis.Landlock) bool {
+	return in != nil && (len(in.ReadPaths)+len(in.WritePaths) > 0 || in.SystemPaths)
+}
+
+// landlockStatus is reported by the sandbox helper once started
+func (m *Commander) landlockStatus() *apis.LandlockStatus {
+	if m.sandbox == nil {
+		return nil
+	}
+	return m.sandbox.landlock
+}
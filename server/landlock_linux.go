@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"yunion.io/x/executor/apis"
+)
+
+// landlock syscalls have the same numbers on all architectures
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+)
+
+// file system access rights of linux/landlock.h
+const (
+	landlockAccessExecute = 1 << iota
+	landlockAccessWriteFile
+	landlockAccessReadFile
+	landlockAccessReadDir
+	landlockAccessRemoveDir
+	landlockAccessRemoveFile
+	landlockAccessMakeChar
+	landlockAccessMakeDir
+	landlockAccessMakeReg
+	landlockAccessMakeSock
+	landlockAccessMakeFifo
+	landlockAccessMakeBlock
+	landlockAccessMakeSym
+	// abi 2
+	landlockAccessRefer
+	// abi 3
+	landlockAccessTruncate
+	// abi 5
+	landlockAccessIoctlDev
+)
+
+const (
+	landlockAccessRead = landlockAccessExecute | landlockAccessReadFile | landlockAccessReadDir
+	// rights applying to files, others are only for directories
+	landlockAccessFile = landlockAccessExecute | landlockAccessWriteFile | landlockAccessReadFile |
+		landlockAccessTruncate | landlockAccessIoctlDev
+)
+
+// landlockHandled are the rights restricted by abi
+func landlockHandled(abi int) uint64 {
+	handled := uint64(landlockAccessRefer - 1)
+	if abi >= 2 {
+		handled |= landlockAccessRefer
+	}
+	if abi >= 3 {
+		handled |= landlockAccessTruncate
+	}
+	if abi >= 5 {
+		handled |= landlockAccessIoctlDev
+	}
+	return handled
+}
+
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+func landlockAbi() int {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// applyLandlock restricts the calling thread, which must exec the
+// command, the status tells how much of spec is enforced
+func applyLandlock(spec *landlockSpec) (*apis.LandlockStatus, error) {
+	st := &apis.LandlockStatus{Abi: int32(landlockAbi())}
+	switch {
+	case st.Abi == 0:
+		st.Reason = "landlock is not supported by the kernel"
+	case st.Abi < 3:
+		st.Reason = fmt.Sprintf("truncate is not restricted by landlock abi %d", st.Abi)
+	}
+	if st.Abi == 0 {
+		if spec.Required {
+			return st, errors.New(st.Reason)
+		}
+		return st, nil
+	}
+	if st.Abi < 3 && spec.Required {
+		return st, errors.New(st.Reason)
+	}
+
+	handled := landlockHandled(int(st.Abi))
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&handled)), unsafe.Sizeof(handled), 0)
+	if errno != 0 {
+		return st, errors.Wrap(errno, "create landlock ruleset")
+	}
+	ruleset := int(fd)
+	defer unix.Close(ruleset)
+	for _, rule := range spec.Rules {
+		if err := landlockAddPath(ruleset, handled, rule); err != nil {
+			if rule.Optional && errors.Cause(err) == unix.ENOENT {
+				continue
+			}
+			return st, errors.Wrapf(err, "landlock path %s", rule.Path)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return st, err
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, uintptr(ruleset), 0, 0); errno != 0 {
+		return st, errors.Wrap(errno, "enforce landlock ruleset")
+	}
+	st.Enforced = st.Reason == ""
+	return st, nil
+}
+
+func landlockAddPath(ruleset int, handled uint64, rule landlockRule) error {
+	fd, err := unix.Open(rule.Path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return err
+	}
+	access := uint64(landlockAccessRead)
+	if rule.Write {
+		access = handled
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockAccessFile
+	}
+	attr := landlockPathBeneathAttr{allowedAccess: access & handled, parentFd: int32(fd)}
+	_, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package server
+
+import "testing"
+
+func TestLandlockHandled(t *testing.T) {
+	for _, c := range []struct {
+		abi  int
+		want uint64
+	}{
+		{1, landlockAccessRefer - 1},
+		{2, landlockAccessRefer<<1 - 1},
+		{3, landlockAccessTruncate<<1 - 1},
+		{4, landlockAccessTruncate<<1 - 1},
+		{5, landlockAccessIoctlDev<<1 - 1},
+	} {
+		if got := landlockHandled(c.abi); got != c.want {
+			t.Errorf("abi %d: got %#x, want %#x", c.abi, got, c.want)
+		}
+	}
+}
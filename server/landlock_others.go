@@ -0,0 +1,17 @@
+// +build !linux
+
+package server
+
+import (
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+func applyLandlock(spec *landlockSpec) (*apis.LandlockStatus, error) {
+	st := &apis.LandlockStatus{Reason: "landlock is only supported on linux"}
+	if spec.Required {
+		return st, errors.New(st.Reason)
+	}
+	return st, nil
+}
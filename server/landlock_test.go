@@ -0,0 +1,62 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestNewLandlockSpec(t *testing.T) {
+	spec, err := newLandlockSpec(&apis.Landlock{
+		ReadPaths:  []string{"/etc/", "/usr/../opt"},
+		WritePaths: []string{"/tmp/x/"},
+		Required:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []landlockRule{{Path: "/etc"}, {Path: "/opt"}, {Path: "/tmp/x", Write: true}}
+	if !reflect.DeepEqual(spec.Rules, want) || !spec.Required {
+		t.Errorf("got %+v, want rules %+v", spec, want)
+	}
+
+	spec, err = newLandlockSpec(&apis.Landlock{SystemPaths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Rules) != len(landlockSystemPaths)+1 {
+		t.Fatalf("system paths: got %d rules", len(spec.Rules))
+	}
+	for _, rule := range spec.Rules {
+		if !rule.Optional || rule.Write != (rule.Path == "/dev/null") {
+			t.Errorf("system path rule %+v", rule)
+		}
+	}
+
+	for _, in := range []*apis.Landlock{
+		{ReadPaths: []string{"etc"}},
+		{WritePaths: []string{"./tmp"}},
+	} {
+		if _, err := newLandlockSpec(in); err == nil {
+			t.Errorf("newLandlockSpec(%v) succeeded", in)
+		}
+	}
+}
+
+func TestLandlockEnabled(t *testing.T) {
+	for _, c := range []struct {
+		in   *apis.Landlock
+		want bool
+	}{
+		{nil, false},
+		{&apis.Landlock{}, false},
+		{&apis.Landlock{Required: true}, false},
+		{&apis.Landlock{SystemPaths: true}, true},
+		{&apis.Landlock{WritePaths: []string{"/tmp"}}, true},
+	} {
+		if got := landlockEnabled(c.in); got != c.want {
+			t.Errorf("landlockEnabled(%v): got %v", c.in, got)
+		}
+	}
+}
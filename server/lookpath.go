@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/executor/apis"
+)
+
+func envValue(env []string, key string) string {
+	prefix := key + "="
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], prefix) {
+			return env[i][len(prefix):]
+		}
+	}
+	return ""
+}
+
+func findExecutable(file string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if m := fi.Mode(); !m.IsDir() && m&0111 != 0 {
+		return nil
+	}
+	return os.ErrPermission
+}
+
+// LookPath searches name in PATH of env like exec.LookPath and returns
+// an absolute path, relative paths are resolved against the working
+// directory dir of the command
+func LookPath(name string, env []string, dir string) (string, error) {
+	abs := func(p string) string {
+		if !filepath.IsAbs(p) && dir != "" {
+			p = filepath.Join(dir, p)
+		}
+		if ap, err := filepath.Abs(p); err == nil {
+			return ap
+		}
+		return p
+	}
+	if strings.Contains(name, "/") {
+		path := abs(name)
+		if err := findExecutable(path); err != nil {
+			if os.IsNotExist(err) {
+				err = exec.ErrNotFound
+			}
+			return "", &exec.Error{Name: name, Err: err}
+		}
+		return path, nil
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	for _, d := range filepath.SplitList(envValue(env, "PATH")) {
+		if d == "" {
+			d = "."
+		}
+		path := abs(filepath.Join(d, name))
+		if err := findExecutable(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+func (e *Executor) LookPath(ctx context.Context, req *apis.Command) (*apis.LookPathResponse, error) {
+	env, err := e.CommandEnv(req)
+	if err != nil {
+		return &apis.LookPathResponse{Error: []byte(err.Error())}, nil
+	}
+	path, err := LookPath(string(req.Path), env, string(req.Dir))
+	if err != nil {
+		if ee, ok := err.(*exec.Error); ok {
+			return &apis.LookPathResponse{
+				NotFound: ee.Err == exec.ErrNotFound,
+				Error:    []byte(ee.Err.Error()),
+			}, nil
+		}
+		return &apis.LookPathResponse{Error: []byte(err.Error())}, nil
+	}
+	return &apis.LookPathResponse{Path: []byte(path)}, nil
+}
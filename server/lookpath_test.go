@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a", "b", "a/sub"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, mode := range map[string]os.FileMode{
+		"a/noexec": 0644,
+		"b/noexec": 0755,
+		"a/both":   0755,
+		"b/both":   0755,
+		"b/only":   0755,
+		"a/sub/x":  0755,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "b", "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	path := "PATH=" + a + ":" + b
+
+	for _, c := range []struct {
+		name     string
+		env      []string
+		dir      string
+		want     string
+		notFound bool
+	}{
+		{name: "both", env: []string{path}, want: filepath.Join(a, "both")},
+		{name: "only", env: []string{path}, want: filepath.Join(b, "only")},
+		{name: "noexec", env: []string{path}, want: filepath.Join(b, "noexec")},
+		{name: "dir", env: []string{path}, notFound: true},
+		{name: "missing", env: []string{path}, notFound: true},
+		{name: "both", env: []string{"PATH=/nonexistent", path}, want: filepath.Join(a, "both")},
+		{name: "both", env: []string{"HOME=/"}, notFound: true},
+		{name: "x", env: []string{"PATH=sub"}, dir: a, want: filepath.Join(a, "sub", "x")},
+		{name: "x", env: []string{"PATH=:" + b}, dir: filepath.Join(a, "sub"), want: filepath.Join(a, "sub", "x")},
+		{name: "sub/x", dir: a, want: filepath.Join(a, "sub", "x")},
+		{name: "./sub/../sub/x", dir: a, want: filepath.Join(a, "sub", "x")},
+		{name: filepath.Join(b, "only"), want: filepath.Join(b, "only")},
+		{name: filepath.Join(b, "gone"), notFound: true},
+	} {
+		got, err := LookPath(c.name, c.env, c.dir)
+		if c.notFound {
+			ee, ok := err.(*exec.Error)
+			if !ok || ee.Err != exec.ErrNotFound {
+				t.Errorf("%s in %q: got %q %v, want not found", c.name, c.env, got, err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s in %q dir %q: got %q %v, want %q", c.name, c.env, c.dir, got, err, c.want)
+		}
+	}
+
+	if _, err := LookPath(filepath.Join(a, "noexec"), nil, ""); err == nil {
+		t.Errorf("not executable path found")
+	} else if ee, ok := err.(*exec.Error); !ok || ee.Err != os.ErrPermission {
+		t.Errorf("not executable path: got %v, want permission error", err)
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const metricsNamespace = "executor"
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	commandsStarted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "commands_started_total",
+			Help:      "Number of commands started, by executable basename.",
+		},
+		[]string{"command"},
+	)
+	commandsCompleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "commands_completed_total",
+			Help:      "Number of commands reaped after exit, by executable basename.",
+		},
+		[]string{"command"},
+	)
+	commandsFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "commands_failed_total",
+			Help:      "Number of commands that failed to start, exited non-zero, were signaled or could not be waited.",
+		},
+		[]string{"command", "reason"},
+	)
+	commandExitCodes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "command_exit_codes_total",
+			Help:      "Distribution of command exit codes, signaled commands are reported as signal:<num>.",
+		},
+		[]string{"command", "code"},
+	)
+	commandDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "command_duration_seconds",
+			Help:      "Time from process start to exit.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 16),
+		},
+		[]string{"command"},
+	)
+	commandsRunning = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "commands_running",
+			Help:      "Number of commands currently running.",
+		},
+	)
+	streamBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stream_bytes_total",
+			Help:      "Bytes transferred through command stdio streams.",
+		},
+		[]string{"stream"},
+	)
+	streamStalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stream_stalls_total",
+			Help:      "Output streams closed after the client acknowledged nothing within the stall timeout.",
+		},
+		[]string{"stream"},
+	)
+	rpcDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Latency of gRPC requests handled by the executor, for streams the lifetime of the stream.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "code"},
+	)
+	cmdsSize = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "commands_tracked",
+			Help:      "Number of entries in the command table, including commands not yet started or waited.",
+		},
+		func() float64 { return float64(Len(cmds)) },
+	)
+
+	stdinBytes  = streamBytes.WithLabelValues("stdin")
+	stdoutBytes = streamBytes.WithLabelValues("stdout")
+	stderrBytes = streamBytes.WithLabelValues("stderr")
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		commandsStarted,
+		commandsCompleted,
+		commandsFailed,
+		commandExitCodes,
+		commandDuration,
+		commandsRunning,
+		streamBytes,
+		streamStalls,
+		rpcDuration,
+		cmdsSize,
+	)
+}
+
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// the command label is the executable basename, bounded as clients
+// choose the paths, other names are counted as otherCommandLabel
+const (
+	maxCommandLabels  = 64
+	otherCommandLabel = "other"
+)
+
+type commandLabelSet struct {
+	lock sync.Mutex
+	// allowed is set by SetMetricsCommands, otherwise the first
+	// maxCommandLabels names seen are labelled
+	allowed map[string]bool
+	seen    map[string]bool
+}
+
+var commandLabels = &commandLabelSet{seen: make(map[string]bool)}
+
+// SetMetricsCommands labels metrics of commands only by these
+// executable basenames, others are counted as "other"
+func SetMetricsCommands(names []string) {
+	commandLabels.lock.Lock()
+	defer commandLabels.lock.Unlock()
+	commandLabels.allowed = nil
+	if len(names) == 0 {
+		return
+	}
+	commandLabels.allowed = make(map[string]bool, len(names))
+	for _, name := range names {
+		commandLabels.allowed[name] = true
+	}
+}
+
+func commandLabel(path string) string {
+	name := filepath.Base(path)
+	commandLabels.lock.Lock()
+	defer commandLabels.lock.Unlock()
+	if commandLabels.allowed != nil {
+		if commandLabels.allowed[name] {
+			return name
+		}
+		return otherCommandLabel
+	}
+	if commandLabels.seen[name] {
+		return name
+	}
+	if len(commandLabels.seen) >= maxCommandLabels {
+		return otherCommandLabel
+	}
+	commandLabels.seen[name] = true
+	return name
+}
+
+func observeStartFailed(name string) {
+	commandsFailed.WithLabelValues(name, "start").Inc()
+}
+
+// observeAdopted counts a running command handed over on upgrade,
+// its start was counted by the previous server
+func observeAdopted() {
+	commandsRunning.Inc()
+}
+
+func observeStarted(name string) {
+	commandsStarted.WithLabelValues(name).Inc()
+	commandsRunning.Inc()
+}
+
+func observeExited(name string, started time.Time, ws syscall.WaitStatus, exited bool) {
+	commandsRunning.Dec()
+	commandsCompleted.WithLabelValues(name).Inc()
+	commandDuration.WithLabelValues(name).Observe(time.Since(started).Seconds())
+	if !exited {
+		commandsFailed.WithLabelValues(name, "wait").Inc()
+		return
+	}
+	var code string
+	if ws.Signaled() {
+		code = "signal:" + strconv.Itoa(int(ws.Signal()))
+		commandsFailed.WithLabelValues(name, "signal").Inc()
+	} else {
+		code = strconv.Itoa(ws.ExitStatus())
+		if ws.ExitStatus() != 0 {
+			commandsFailed.WithLabelValues(name, "exit").Inc()
+		}
+	}
+	commandExitCodes.WithLabelValues(name, code).Inc()
+}
+
+func MetricsUnaryInterceptor(
+	ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	rpcDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+func MetricsStreamInterceptor(
+	srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	rpcDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return err
+}
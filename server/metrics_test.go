@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func resetCommandLabels() {
+	commandLabels.lock.Lock()
+	commandLabels.allowed = nil
+	commandLabels.seen = make(map[string]bool)
+	commandLabels.lock.Unlock()
+}
+
+func TestCommandLabelBounded(t *testing.T) {
+	resetCommandLabels()
+	defer resetCommandLabels()
+	for i := 0; i < maxCommandLabels; i++ {
+		name := fmt.Sprintf("cmd%d", i)
+		if got := commandLabel("/usr/bin/" + name); got != name {
+			t.Fatalf("label of %s: got %q", name, got)
+		}
+	}
+	if got := commandLabel("/usr/bin/one-too-many"); got != otherCommandLabel {
+		t.Errorf("label over limit: got %q, want %q", got, otherCommandLabel)
+	}
+	if got := commandLabel("/opt/cmd0"); got != "cmd0" {
+		t.Errorf("label seen before: got %q, want cmd0", got)
+	}
+}
+
+func TestCommandLabelAllowList(t *testing.T) {
+	resetCommandLabels()
+	defer resetCommandLabels()
+	SetMetricsCommands([]string{"sh", "qemu-img"})
+	for _, c := range []struct {
+		path string
+		want string
+	}{
+		{"/bin/sh", "sh"},
+		{"qemu-img", "qemu-img"},
+		{"/usr/bin/random-1234", otherCommandLabel},
+		{"/tmp/sh/evil", otherCommandLabel},
+	} {
+		if got := commandLabel(c.path); got != c.want {
+			t.Errorf("label of %s: got %q, want %q", c.path, got, c.want)
+		}
+	}
+	SetMetricsCommands(nil)
+	if got := commandLabel("/usr/bin/random-1234"); got != "random-1234" {
+		t.Errorf("label without allow list: got %q", got)
+	}
+}
+
+func runningGauge(t *testing.T) float64 {
+	var m dto.Metric
+	if err := commandsRunning.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestRestoreCommandCountsRunning(t *testing.T) {
+	// the adopted process is a child of ours, not one the abandon timer
+	// may kill by mistake
+	proc := exec.Command("/bin/sleep", "60")
+	if err := proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer proc.Wait()
+	defer proc.Process.Kill()
+
+	before := runningGauge(t)
+	hc := &handoffCommand{
+		Sn:        1<<31 - 1,
+		Path:      "/bin/sleep",
+		Args:      []string{"sleep", "60"},
+		Pid:       proc.Process.Pid,
+		StartedAt: time.Now(),
+		// no pipes handed over, fd 0 would be taken
+		Stdin:  -1,
+		Stdout: -1,
+		Stderr: -1,
+	}
+	restoreCommand(hc)
+	v, ok := cmds.Load(hc.Sn)
+	if !ok {
+		t.Fatal("command not restored")
+	}
+	v.(*Commander).resume.timer.Stop()
+	defer func() {
+		cmds.Delete(hc.Sn)
+		commandsRunning.Dec()
+	}()
+	if got := runningGauge(t); got != before+1 {
+		t.Errorf("running after adopt: got %v, want %v", got, before+1)
+	}
+}
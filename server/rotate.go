@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"yunion.io/x/log"
+)
+
+// rotatingLog appends to path, once it reaches maxBytes it is renamed
+// to path.1, shifting older ones up to path.<maxFiles>
+type rotatingLog struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	lock    sync.Mutex
+	file    *os.File
+	size    int64
+	failing bool
+}
+
+func openRotatingLog(path string, maxBytes int64, maxFiles int) (*rotatingLog, error) {
+	l := &rotatingLog{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *rotatingLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = fi.Size()
+	return nil
+}
+
+func (l *rotatingLog) rotate() error {
+	l.file.Close()
+	l.file = nil
+	for i := l.maxFiles - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return l.open()
+}
+
+// Write never fails, output is dropped while the log can't be written,
+// a failing writer would break the pipe of the service
+func (l *rotatingLog) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.write(p); err != nil {
+		if !l.failing {
+			log.Errorf("write %s: %s", l.path, err)
+		}
+		l.failing = true
+	} else {
+		l.failing = false
+	}
+	return len(p), nil
+}
+
+func (l *rotatingLog) write(p []byte) error {
+	for len(p) > 0 {
+		if l.file == nil {
+			// reopen after a failed rotation
+			if err := l.open(); err != nil {
+				return err
+			}
+		}
+		if l.size >= l.maxBytes {
+			if err := l.rotate(); err != nil {
+				return err
+			}
+		}
+		chunk := p
+		if room := l.maxBytes - l.size; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		n, err := l.file.Write(chunk)
+		l.size += int64(n)
+		if err != nil {
+			return err
+		}
+		p = p[n:]
+	}
+	return nil
+}
+
+func (l *rotatingLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
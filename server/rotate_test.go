@@ -0,0 +1,104 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readLogFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingLogRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "svc.log")
+
+	l, err := openRotatingLog(path, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a write over the limit is split at it
+	l.Write([]byte("abcdef"))
+	if got := readLogFile(t, path); got != "ef" {
+		t.Errorf("current log: got %q, want ef", got)
+	}
+	if got := readLogFile(t, path+".1"); got != "abcd" {
+		t.Errorf("rotated log: got %q, want abcd", got)
+	}
+
+	// older logs shift up, the oldest over maxFiles is dropped
+	l.Write([]byte("ghij"))
+	l.Write([]byte("klmn"))
+	l.Close()
+	for _, c := range []struct {
+		path string
+		want string
+	}{
+		{path, "mn"},
+		{path + ".1", "ijkl"},
+		{path + ".2", "efgh"},
+	} {
+		if got := readLogFile(t, c.path); got != c.want {
+			t.Errorf("%s: got %q, want %q", filepath.Base(c.path), got, c.want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("log over maxFiles kept: %v", err)
+	}
+}
+
+func TestRotatingLogReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "svc.log")
+
+	// size of the existing log counts
+	if err := ioutil.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openRotatingLog(path, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Write([]byte("de"))
+	if got := readLogFile(t, path+".1"); got != "abcd" {
+		t.Errorf("rotated log: got %q, want abcd", got)
+	}
+
+	// output is dropped while the log can't be opened, not failed
+	l.Close()
+	os.RemoveAll(dir)
+	if n, err := l.Write([]byte("lost")); n != 4 || err != nil {
+		t.Errorf("write to failing log: got %d %v", n, err)
+	}
+	if !l.failing {
+		t.Errorf("log not failing without dir")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	l.Write([]byte("back"))
+	l.Close()
+	if l.failing {
+		t.Errorf("log still failing after dir is back")
+	}
+	if got := readLogFile(t, path); got != "back" {
+		t.Errorf("reopened log: got %q, want back", got)
+	}
+	if l.size != 4 {
+		t.Errorf("size of reopened log: got %d, want 4", l.size)
+	}
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// Commands with sandbox settings are started as the server binary with
+// argv[0] sandboxInitArg, it applies the settings to itself and execs the
+// command, go can't run code between fork and exec
+const (
+	sandboxInitArg = "executor-sandbox-init"
+	sandboxEnv     = "EXECUTOR_SANDBOX"
+	// the status pipe follows ExtraFiles of the command, fd 3 if none
+	sandboxStatusFd = 3
+)
+
+func init() {
+	if len(os.Args) > 0 && os.Args[0] == sandboxInitArg {
+		sandboxInit()
+	}
+}
+
+// sandboxSpec is passed to the helper in sandboxEnv
+type sandboxSpec struct {
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	// Sched is applied first, with capabilities of the server
+	Sched *schedAttrs `json:"sched,omitempty"`
+	// DropBounding are dropped from the bounding set first
+	DropBounding []uint `json:"drop_bounding,omitempty"`
+	// Isolation is set up in new namespaces cloned by the server
+	Isolation *isolationSpec `json:"isolation,omitempty"`
+	// User switches credentials keeping capabilities for Caps
+	User *sandboxUser `json:"user,omitempty"`
+	Caps *capSets     `json:"caps,omitempty"`
+	// Landlock is applied as the user with no_new_privs set
+	Landlock *landlockSpec `json:"landlock,omitempty"`
+	// Seccomp is installed last with no_new_privs set
+	Seccomp []sockFilter `json:"seccomp,omitempty"`
+	// ExtraFiles passed to the command come before the status pipe
+	ExtraFiles int `json:"extra_files,omitempty"`
+
+	seccompProfile string
+	// isolation namespaces are cloned only for the helper, which sets
+	// them up
+	isolation *apis.Isolation
+	// landlock is reported by the helper on start
+	landlock *apis.LandlockStatus
+}
+
+type sandboxUser struct {
+	Uid    uint32   `json:"uid"`
+	Gid    uint32   `json:"gid"`
+	Groups []uint32 `json:"groups"`
+}
+
+// capSets are set after switching user, effective is permitted
+type capSets struct {
+	Permitted   uint64 `json:"permitted"`
+	Inheritable uint64 `json:"inheritable"`
+	Ambient     []uint `json:"ambient"`
+}
+
+// sandboxStatus is written by the helper to the status pipe, which is
+// closed on exec, an error means the command didn't start
+type sandboxStatus struct {
+	Error    string               `json:"error,omitempty"`
+	Landlock *apis.LandlockStatus `json:"landlock,omitempty"`
+}
+
+func (e *Executor) sandboxSpec(in *apis.Command) (*sandboxSpec, error) {
+	caps := in.Capabilities
+	if caps != nil && len(caps.Keep)+len(caps.Drop)+len(caps.Ambient) == 0 {
+		caps = nil
+	}
+	var sched *schedAttrs
+	if in.Sched != nil {
+		var err error
+		if sched, err = schedSpec(in.Sched); err != nil {
+			return nil, err
+		}
+	}
+	if len(in.SeccompProfile) == 0 && len(in.User) == 0 && caps == nil && sched == nil &&
+		!isolationEnabled(in.Isolation) && !landlockEnabled(in.Landlock) {
+		return nil, nil
+	}
+	spec := &sandboxSpec{Sched: sched}
+	if isolationEnabled(in.Isolation) {
+		var err error
+		if spec.Isolation, err = newIsolationSpec(in.Isolation); err != nil {
+			return nil, err
+		}
+		spec.isolation = in.Isolation
+	}
+	if len(in.User) > 0 {
+		u, err := lookupUser(in.User)
+		if err != nil {
+			return nil, err
+		}
+		spec.User = u
+	}
+	if caps != nil {
+		if err := capsSpec(caps, spec); err != nil {
+			return nil, err
+		}
+	}
+	if landlockEnabled(in.Landlock) {
+		var err error
+		if spec.Landlock, err = newLandlockSpec(in.Landlock); err != nil {
+			return nil, err
+		}
+	}
+	if len(in.SeccompProfile) > 0 {
+		profile, err := e.seccompProfile(in.SeccompProfile)
+		if err != nil {
+			return nil, err
+		}
+		spec.seccompProfile = in.SeccompProfile
+		spec.Seccomp, err = profile.compile()
+		if err != nil {
+			return nil, errors.Wrapf(err, "seccomp profile %s", in.SeccompProfile)
+		}
+	}
+	return spec, nil
+}
+
+// lookupUser resolves a user name or uid, an unknown uid runs with
+// the same gid and no groups
+func lookupUser(name string) (*sandboxUser, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		id, perr := strconv.ParseUint(name, 10, 32)
+		if perr != nil {
+			return nil, errors.Wrapf(err, "user %s", name)
+		}
+		if u, err = user.LookupId(name); err != nil {
+			return &sandboxUser{Uid: uint32(id), Gid: uint32(id)}, nil
+		}
+	}
+	su := &sandboxUser{}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "uid of %s", name)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "gid of %s", name)
+	}
+	su.Uid, su.Gid = uint32(uid), uint32(gid)
+	groups, _ := u.GroupIds()
+	for _, g := range groups {
+		if id, err := strconv.ParseUint(g, 10, 32); err == nil {
+			su.Groups = append(su.Groups, uint32(id))
+		}
+	}
+	return su, nil
+}
+
+// wrap makes cmd start through the helper in new namespaces of the
+// isolation, a command not found is left to fail on Start
+func (spec *sandboxSpec) wrap(cmd *exec.Cmd) error {
+	if !strings.Contains(cmd.Path, "/") {
+		return nil
+	}
+	spec.Path = cmd.Path
+	spec.Args = cmd.Args
+	spec.ExtraFiles = len(cmd.ExtraFiles)
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, sandboxEnv+"="+base64.StdEncoding.EncodeToString(data))
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{sandboxInitArg}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	setCloneflags(cmd.SysProcAttr, spec.isolation)
+	return nil
+}
+
+// startSandboxed starts cmd, with spec waits until the helper has
+// applied it and exec'ed the command
+func startSandboxed(cmd *exec.Cmd, spec *sandboxSpec) error {
+	if spec == nil || cmd.Path != "/proc/self/exe" {
+		return cmd.Start()
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	cmd.ExtraFiles = append(cmd.ExtraFiles[:len(cmd.ExtraFiles):len(cmd.ExtraFiles)], w)
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+	var status sandboxStatus
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for dec.More() {
+		var st sandboxStatus
+		if err := dec.Decode(&st); err != nil {
+			break
+		}
+		if st.Error != "" {
+			status.Error = st.Error
+		}
+		if st.Landlock != nil {
+			spec.landlock = st.Landlock
+		}
+	}
+	if status.Error != "" {
+		cmd.Wait()
+		return errors.New(status.Error)
+	}
+	return nil
+}
+
+// sandboxInit runs in the helper process and never returns
+func sandboxInit() {
+	runtime.LockOSThread()
+	var status *os.File
+	fail := func(err error) {
+		if status != nil {
+			json.NewEncoder(status).Encode(&sandboxStatus{Error: err.Error()})
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", sandboxInitArg, err)
+		os.Exit(127)
+	}
+
+	var spec sandboxSpec
+	data, err := base64.StdEncoding.DecodeString(os.Getenv(sandboxEnv))
+	if err == nil {
+		err = json.Unmarshal(data, &spec)
+	}
+	if err != nil {
+		fail(errors.Wrap(err, "decode sandbox spec"))
+	}
+	statusFd := sandboxStatusFd + spec.ExtraFiles
+	status = os.NewFile(uintptr(statusFd), "sandbox-status")
+	syscall.CloseOnExec(statusFd)
+	env := os.Environ()
+	for i := range env {
+		if strings.HasPrefix(env[i], sandboxEnv+"=") {
+			env = append(env[:i], env[i+1:]...)
+			break
+		}
+	}
+
+	if spec.Sched != nil {
+		if err := applySched(spec.Sched); err != nil {
+			fail(err)
+		}
+	}
+	if spec.Isolation != nil {
+		if err := setupIsolation(spec.Isolation); err != nil {
+			fail(err)
+		}
+	}
+	if err := applyCredentials(&spec); err != nil {
+		fail(err)
+	}
+	if spec.Landlock != nil {
+		st, err := applyLandlock(spec.Landlock)
+		if err != nil {
+			fail(err)
+		}
+		json.NewEncoder(status).Encode(&sandboxStatus{Landlock: st})
+	}
+	// exec arguments are prepared before the filter, which is installed
+	// right before exec, the go runtime may need denied syscalls
+	argv0, err := syscall.BytePtrFromString(spec.Path)
+	if err != nil {
+		fail(errors.Wrapf(err, "exec %s", spec.Path))
+	}
+	argv, err := syscall.SlicePtrFromStrings(spec.Args)
+	if err != nil {
+		fail(errors.Wrapf(err, "exec %s", spec.Path))
+	}
+	envv, err := syscall.SlicePtrFromStrings(env)
+	if err != nil {
+		fail(errors.Wrapf(err, "exec %s", spec.Path))
+	}
+	if len(spec.Seccomp) > 0 {
+		debug.SetGCPercent(-1)
+		if err := installSeccomp(spec.Seccomp); err != nil {
+			fail(errors.Wrap(err, "install seccomp filter"))
+		}
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_EXECVE,
+		uintptr(unsafe.Pointer(argv0)),
+		uintptr(unsafe.Pointer(&argv[0])),
+		uintptr(unsafe.Pointer(&envv[0])))
+	fail(errors.Wrapf(errno, "exec %s", spec.Path))
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// installSeccomp sets no_new_privs and installs filter on the calling
+// thread, which must exec the command
+func installSeccomp(filter []sockFilter) error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	prog := make([]unix.SockFilter, len(filter))
+	for i, f := range filter {
+		prog[i] = unix.SockFilter{Code: f.Code, Jt: f.Jt, Jf: f.Jf, K: f.K}
+	}
+	fprog := unix.SockFprog{Len: uint16(len(prog)), Filter: &prog[0]}
+	_, _, errno := syscall.RawSyscall(unix.SYS_PRCTL, unix.PR_SET_SECCOMP,
+		unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&fprog)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+const linuxCapabilityVersion3 = 0x20080522
+
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+func capget() (permitted uint64, err error) {
+	hdr := capHeader{version: linuxCapabilityVersion3}
+	var data [2]capData
+	_, _, errno := syscall.RawSyscall(unix.SYS_CAPGET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return uint64(data[0].permitted) | uint64(data[1].permitted)<<32, nil
+}
+
+// capset sets effective and permitted to permitted, limited to
+// what the process has
+func capset(permitted, inheritable uint64) error {
+	current, err := capget()
+	if err != nil {
+		return err
+	}
+	permitted &= current
+	inheritable &= current
+	hdr := capHeader{version: linuxCapabilityVersion3}
+	data := [2]capData{
+		{effective: uint32(permitted), permitted: uint32(permitted), inheritable: uint32(inheritable)},
+		{effective: uint32(permitted >> 32), permitted: uint32(permitted >> 32), inheritable: uint32(inheritable >> 32)},
+	}
+	_, _, errno := syscall.RawSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// applyCredentials drops the bounding set, switches user and sets
+// capabilities on the calling thread, in this order as the first two
+// need capabilities dropped by the later ones
+func applyCredentials(spec *sandboxSpec) error {
+	for _, c := range spec.DropBounding {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil {
+			return errors.Wrapf(err, "drop %s from bounding set", capMaskNames(1 << c)[0])
+		}
+	}
+	if u := spec.User; u != nil {
+		if spec.Caps != nil {
+			if err := unix.Prctl(unix.PR_SET_KEEPCAPS, 1, 0, 0, 0); err != nil {
+				return errors.Wrap(err, "keep capabilities")
+			}
+		}
+		groups := u.Groups
+		if len(groups) == 0 {
+			groups = []uint32{u.Gid}
+		}
+		_, _, errno := syscall.RawSyscall(unix.SYS_SETGROUPS, uintptr(len(groups)), uintptr(unsafe.Pointer(&groups[0])), 0)
+		if errno != 0 {
+			return errors.Wrap(errno, "setgroups")
+		}
+		if err := unix.Setresgid(int(u.Gid), int(u.Gid), int(u.Gid)); err != nil {
+			return errors.Wrap(err, "setgid")
+		}
+		if err := unix.Setresuid(int(u.Uid), int(u.Uid), int(u.Uid)); err != nil {
+			return errors.Wrap(err, "setuid")
+		}
+	}
+	if caps := spec.Caps; caps != nil {
+		if err := capset(caps.Permitted, caps.Inheritable); err != nil {
+			return errors.Wrap(err, "set capabilities")
+		}
+		for _, c := range caps.Ambient {
+			if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_RAISE, uintptr(c), 0, 0); err != nil {
+				return errors.Wrapf(err, "raise ambient %s", capMaskNames(1 << c)[0])
+			}
+		}
+	}
+	return nil
+}
+
+const ioprioWhoProcess = 1
+
+func ioprioGet(pid int) (int, error) {
+	prio, _, errno := syscall.RawSyscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(prio), nil
+}
+
+// applySched sets scheduling attributes, nice, io priority and
+// affinity are per thread and inherited by exec of the calling thread
+func applySched(attrs *schedAttrs) error {
+	if attrs.Nice != nil {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, *attrs.Nice); err != nil {
+			return errors.Wrapf(err, "set nice %d", *attrs.Nice)
+		}
+	}
+	if attrs.IoPrio != nil {
+		_, _, errno := syscall.RawSyscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(*attrs.IoPrio))
+		if errno != 0 {
+			return errors.Wrap(errno, "set io priority")
+		}
+	}
+	if len(attrs.Cpus) > 0 {
+		var set unix.CPUSet
+		for _, cpu := range attrs.Cpus {
+			set.Set(int(cpu))
+		}
+		if err := unix.SchedSetaffinity(0, &set); err != nil {
+			return errors.Wrap(err, "set cpu affinity")
+		}
+	}
+	if attrs.OomScoreAdj != nil {
+		if err := ioutil.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(*attrs.OomScoreAdj)), 0644); err != nil {
+			return errors.Wrap(err, "set oom_score_adj")
+		}
+	}
+	if attrs.Umask != nil {
+		syscall.Umask(*attrs.Umask)
+	}
+	return nil
+}
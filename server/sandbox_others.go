@@ -0,0 +1,26 @@
+// +build !linux
+
+package server
+
+import (
+	"github.com/pkg/errors"
+)
+
+func installSeccomp(filter []sockFilter) error {
+	return errors.New("seccomp is only supported on linux")
+}
+
+func applyCredentials(spec *sandboxSpec) error {
+	if spec.User != nil || spec.Caps != nil || len(spec.DropBounding) > 0 {
+		return errors.New("credentials are only supported on linux")
+	}
+	return nil
+}
+
+func applySched(attrs *schedAttrs) error {
+	return errors.New("scheduling attributes are only supported on linux")
+}
+
+func ioprioGet(pid int) (int, error) {
+	return 0, errors.New("ioprio is only supported on linux")
+}
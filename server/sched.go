@@ -0,0 +1,72 @@
+package server
+
+import (
+	"github.com/pkg/errors"
+
+	"yunion.io/x/executor/apis"
+)
+
+// maxCpus is the size of the affinity mask
+const maxCpus = 1024
+
+// schedAttrs are applied by the sandbox helper before switching user,
+// nil fields are inherited
+type schedAttrs struct {
+	Nice *int `json:"nice,omitempty"`
+	// IoPrio is the ioprio_set value, class and priority
+	IoPrio      *int   `json:"ioprio,omitempty"`
+	Cpus        []uint `json:"cpus,omitempty"`
+	OomScoreAdj *int   `json:"oom_score_adj,omitempty"`
+	Umask       *int   `json:"umask,omitempty"`
+}
+
+const ioprioClassShift = 13
+
+func schedSpec(in *apis.Sched) (*schedAttrs, error) {
+	attrs := &schedAttrs{}
+	intp := func(v int32) *int {
+		i := int(v)
+		return &i
+	}
+	if in.HasNice {
+		if in.Nice < -20 || in.Nice > 19 {
+			return nil, errors.Errorf("nice %d out of -20..19", in.Nice)
+		}
+		attrs.Nice = intp(in.Nice)
+	}
+	switch in.IoClass {
+	case apis.IoClass_IO_CLASS_NONE:
+	case apis.IoClass_IO_CLASS_REALTIME, apis.IoClass_IO_CLASS_BEST_EFFORT, apis.IoClass_IO_CLASS_IDLE:
+		prio := in.IoPriority
+		if in.IoClass == apis.IoClass_IO_CLASS_IDLE {
+			prio = 0
+		} else if prio < 0 || prio > 7 {
+			return nil, errors.Errorf("io priority %d out of 0..7", prio)
+		}
+		attrs.IoPrio = intp(int32(in.IoClass)<<ioprioClassShift | prio)
+	default:
+		return nil, errors.Errorf("unknown io class %d", in.IoClass)
+	}
+	for _, cpu := range in.Cpus {
+		if cpu >= maxCpus {
+			return nil, errors.Errorf("cpu %d out of range", cpu)
+		}
+		attrs.Cpus = append(attrs.Cpus, uint(cpu))
+	}
+	if in.HasOomScoreAdj {
+		if in.OomScoreAdj < -1000 || in.OomScoreAdj > 1000 {
+			return nil, errors.Errorf("oom_score_adj %d out of -1000..1000", in.OomScoreAdj)
+		}
+		attrs.OomScoreAdj = intp(in.OomScoreAdj)
+	}
+	if in.HasUmask {
+		if in.Umask > 0777 {
+			return nil, errors.Errorf("invalid umask %o", in.Umask)
+		}
+		attrs.Umask = intp(int32(in.Umask))
+	}
+	if attrs.Nice == nil && attrs.IoPrio == nil && len(attrs.Cpus) == 0 && attrs.OomScoreAdj == nil && attrs.Umask == nil {
+		return nil, nil
+	}
+	return attrs, nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestSchedSpec(t *testing.T) {
+	attrs, err := schedSpec(&apis.Sched{})
+	if attrs != nil || err != nil {
+		t.Errorf("empty sched: got %+v %v", attrs, err)
+	}
+
+	attrs, err = schedSpec(&apis.Sched{
+		HasNice: true, Nice: -5,
+		IoClass: apis.IoClass_IO_CLASS_IDLE, IoPriority: 3,
+		Cpus:     []uint32{1, 3},
+		HasUmask: true, Umask: 077,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *attrs.Nice != -5 || *attrs.IoPrio != 3<<ioprioClassShift || *attrs.Umask != 077 || attrs.OomScoreAdj != nil {
+		t.Errorf("got %+v", attrs)
+	}
+	if !reflect.DeepEqual(attrs.Cpus, []uint{1, 3}) {
+		t.Errorf("cpus: got %v", attrs.Cpus)
+	}
+
+	for _, in := range []*apis.Sched{
+		{HasNice: true, Nice: 20},
+		{IoClass: apis.IoClass_IO_CLASS_BEST_EFFORT, IoPriority: 8},
+		{IoClass: 9},
+		{Cpus: []uint32{maxCpus}},
+		{HasOomScoreAdj: true, OomScoreAdj: -1001},
+		{HasUmask: true, Umask: 01000},
+	} {
+		if _, err := schedSpec(in); err == nil {
+			t.Errorf("schedSpec(%v) succeeded", in)
+		}
+	}
+}
+
+func TestParseCpuListOfProc(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want []uint32
+	}{
+		{"0-2,5", []uint32{0, 1, 2, 5}},
+		{" 7 ", []uint32{7}},
+		{"x,1", []uint32{1}},
+		{"", nil},
+	} {
+		if got := parseCpuList(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %v, want %v", c.in, got, c.want)
+		}
+	}
+	// huge ranges stop at the affinity mask
+	if got := parseCpuList("0-4294967295"); len(got) != maxCpus {
+		t.Errorf("huge range: got %d cpus", len(got))
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/log"
+)
+
+const (
+	SeccompActionAllow = "allow"
+	// SeccompActionErrno fails the syscall with EPERM
+	SeccompActionErrno = "errno"
+	// SeccompActionKill kills the process
+	SeccompActionKill = "kill"
+)
+
+// SeccompProfile is a syscall filter installed in commands before exec,
+// Allow lists take effect with a default action other than allow, Deny
+// lists with the default allow action
+type SeccompProfile struct {
+	DefaultAction string   `json:"default_action"`
+	Allow         []string `json:"allow"`
+	Deny          []string `json:"deny"`
+	// DenyAction applies to Deny, errno if empty
+	DenyAction string `json:"deny_action"`
+
+	// errno of denied syscalls, EPERM if zero
+	errno      syscall.Errno
+	denyErrnos map[string]syscall.Errno
+	args       []seccompArgRule
+}
+
+// seccompArgRule denies a syscall by one of its arguments, only the
+// lower 32 bits are compared
+type seccompArgRule struct {
+	syscall string
+	arg     int
+	// jset matches arg & value != 0, otherwise arg != value
+	jset  bool
+	value uint32
+	errno syscall.Errno
+}
+
+// syscalls allow-list profiles always allow, the sandbox helper needs
+// them between installing the filter and exec, go runtime threads may
+// allocate, sleep or preempt meanwhile and a failed exec is reported
+var seccompExecSyscalls = []string{
+	"execve", "exit", "exit_group", "futex", "rt_sigreturn", "rt_sigprocmask", "sigaltstack",
+	"sched_yield", "nanosleep", "clock_gettime", "mmap", "munmap", "madvise",
+	"getpid", "tgkill", "write", "close",
+}
+
+var builtinSeccompProfiles = map[string]*SeccompProfile{
+	// sockets other than unix sockets, io_uring could bypass it
+	"no-network": {
+		DefaultAction: SeccompActionAllow,
+		Deny:          []string{"io_uring_setup"},
+		args: []seccompArgRule{
+			{syscall: "socket", arg: 0, value: syscall.AF_UNIX, errno: syscall.EACCES},
+		},
+	},
+	// files can be read and written through inherited fds only
+	"read-only-fs-syscalls": {
+		DefaultAction: SeccompActionAllow,
+		Deny: []string{
+			"creat", "truncate", "unlink", "unlinkat", "rename", "renameat", "renameat2",
+			"mkdir", "mkdirat", "rmdir", "link", "linkat", "symlink", "symlinkat",
+			"mknod", "mknodat", "chmod", "fchmod", "fchmodat", "fchmodat2",
+			"chown", "fchown", "lchown", "fchownat",
+			"setxattr", "lsetxattr", "fsetxattr", "removexattr", "lremovexattr", "fremovexattr",
+			"utime", "utimes", "utimensat", "futimesat", "open_by_handle_at",
+			"mount", "umount2", "swapon", "swapoff", "acct", "quotactl",
+			"io_uring_setup", "openat2",
+		},
+		errno: syscall.EROFS,
+		args: []seccompArgRule{
+			{syscall: "open", arg: 1, jset: true, value: seccompWriteFlags, errno: syscall.EROFS},
+			{syscall: "openat", arg: 2, jset: true, value: seccompWriteFlags, errno: syscall.EROFS},
+		},
+		// flags are in a struct, make libc fall back to openat
+		denyErrnos: map[string]syscall.Errno{"openat2": syscall.ENOSYS},
+	},
+}
+
+const seccompWriteFlags = syscall.O_WRONLY | syscall.O_RDWR | syscall.O_CREAT | syscall.O_TRUNC
+
+// LoadSeccompProfiles reads named profiles from a json object of
+// name to SeccompProfile, they override builtin profiles of same name
+func LoadSeccompProfiles(path string) (map[string]*SeccompProfile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[string]*SeccompProfile{}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", path)
+	}
+	for name, p := range profiles {
+		if err := p.validate(); err != nil {
+			return nil, errors.Wrapf(err, "seccomp profile %s", name)
+		}
+	}
+	return profiles, nil
+}
+
+func seccompAction(action string, errno syscall.Errno) (uint32, error) {
+	switch action {
+	case SeccompActionAllow:
+		return seccompRetAllow, nil
+	case SeccompActionErrno, "":
+		if errno == 0 {
+			errno = syscall.EPERM
+		}
+		return seccompRetErrno | uint32(errno), nil
+	case SeccompActionKill:
+		return seccompRetKillProcess, nil
+	}
+	return 0, errors.Errorf("unknown action %q", action)
+}
+
+func (p *SeccompProfile) validate() error {
+	if p.DefaultAction == "" {
+		p.DefaultAction = SeccompActionAllow
+	}
+	if _, err := seccompAction(p.DefaultAction, 0); err != nil {
+		return err
+	}
+	if _, err := seccompAction(p.DenyAction, 0); err != nil {
+		return err
+	}
+	for _, name := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, ok := syscallNumbers[name]; !ok {
+			// e.g. open doesn't exist on arm64
+			log.Warningf("syscall %s not on this architecture, ignored", name)
+		}
+	}
+	return nil
+}
+
+// bpf instructions of seccomp filters, see linux/filter.h and seccomp.h
+const (
+	bpfLdWAbs = 0x20
+	bpfJeqK   = 0x15
+	bpfJgeK   = 0x35
+	bpfJsetK  = 0x45
+	bpfRetK   = 0x06
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArgs = 16
+
+	// syscalls of the x32 abi have this bit set on x86_64
+	x32SyscallBit = 0x40000000
+)
+
+type sockFilter struct {
+	Code uint16 `json:"c"`
+	Jt   uint8  `json:"t"`
+	Jf   uint8  `json:"f"`
+	K    uint32 `json:"k"`
+}
+
+func bpfStmt(code uint16, k uint32) sockFilter {
+	return sockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) sockFilter {
+	return sockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// compile translates the profile to a bpf program, syscalls unknown on
+// this architecture are skipped
+func (p *SeccompProfile) compile() ([]sockFilter, error) {
+	if seccompAuditArch == 0 {
+		return nil, errors.New("seccomp is not supported on this platform")
+	}
+	defaultRet, err := seccompAction(p.DefaultAction, p.errno)
+	if err != nil {
+		return nil, err
+	}
+	denyRet, err := seccompAction(p.DenyAction, p.errno)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := []sockFilter{
+		bpfStmt(bpfLdWAbs, seccompDataArch),
+		bpfJump(bpfJeqK, seccompAuditArch, 1, 0),
+		bpfStmt(bpfRetK, seccompRetKillProcess),
+		bpfStmt(bpfLdWAbs, seccompDataNr),
+		bpfJump(bpfJgeK, x32SyscallBit, 0, 1),
+		bpfStmt(bpfRetK, seccompRetKillProcess),
+	}
+	for _, rule := range p.args {
+		nr, ok := syscallNumbers[rule.syscall]
+		if !ok {
+			continue
+		}
+		ret, _ := seccompAction(SeccompActionErrno, rule.errno)
+		cmp := bpfJump(bpfJeqK, rule.value, 1, 0)
+		if rule.jset {
+			cmp = bpfJump(bpfJsetK, rule.value, 0, 1)
+		}
+		prog = append(prog,
+			bpfJump(bpfJeqK, nr, 0, 4),
+			bpfStmt(bpfLdWAbs, uint32(seccompDataArgs+8*rule.arg)),
+			cmp,
+			bpfStmt(bpfRetK, ret),
+			bpfStmt(bpfLdWAbs, seccompDataNr),
+		)
+	}
+	rule := func(names []string, ret uint32) {
+		for _, name := range names {
+			nr, ok := syscallNumbers[name]
+			if !ok {
+				continue
+			}
+			action := ret
+			if errno, ok := p.denyErrnos[name]; ok {
+				action = seccompRetErrno | uint32(errno)
+			}
+			prog = append(prog,
+				bpfJump(bpfJeqK, nr, 0, 1),
+				bpfStmt(bpfRetK, action),
+			)
+		}
+	}
+	if defaultRet == seccompRetAllow {
+		rule(p.Deny, denyRet)
+	} else {
+		rule(seccompExecSyscalls, seccompRetAllow)
+		rule(p.Allow, seccompRetAllow)
+	}
+	prog = append(prog, bpfStmt(bpfRetK, defaultRet))
+	return prog, nil
+}
+
+func (e *Executor) seccompProfile(name string) (*SeccompProfile, error) {
+	if p, ok := e.SeccompProfiles[name]; ok {
+		return p, nil
+	}
+	if p, ok := builtinSeccompProfiles[name]; ok {
+		return p, nil
+	}
+	return nil, errors.Errorf("unknown seccomp profile %q", name)
+}
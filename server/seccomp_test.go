@@ -0,0 +1,181 @@
+// +build linux,amd64 linux,arm64
+
+package server
+
+import (
+	"syscall"
+	"testing"
+)
+
+// runFilter interprets the instructions compile emits for a syscall,
+// it returns the action
+func runFilter(t *testing.T, prog []sockFilter, arch uint32, name string, args ...uint32) uint32 {
+	nr, ok := syscallNumbers[name]
+	if !ok {
+		t.Fatalf("no syscall %s", name)
+	}
+	var acc uint32
+	for pc := 0; pc < len(prog); pc++ {
+		ins := prog[pc]
+		switch ins.Code {
+		case bpfLdWAbs:
+			switch {
+			case ins.K == seccompDataNr:
+				acc = nr
+			case ins.K == seccompDataArch:
+				acc = arch
+			case ins.K >= seccompDataArgs:
+				i := int(ins.K-seccompDataArgs) / 8
+				acc = 0
+				if i < len(args) {
+					acc = args[i]
+				}
+			default:
+				t.Fatalf("load of offset %d", ins.K)
+			}
+		case bpfJeqK, bpfJgeK, bpfJsetK:
+			var cond bool
+			switch ins.Code {
+			case bpfJeqK:
+				cond = acc == ins.K
+			case bpfJgeK:
+				cond = acc >= ins.K
+			default:
+				cond = acc&ins.K != 0
+			}
+			if cond {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case bpfRetK:
+			return ins.K
+		default:
+			t.Fatalf("unknown instruction %#x at %d", ins.Code, pc)
+		}
+	}
+	t.Fatalf("filter of %s fell off the end", name)
+	return 0
+}
+
+func TestSeccompCompileDeny(t *testing.T) {
+	p := &SeccompProfile{Deny: []string{"mount", "no_such_syscall"}, DenyAction: SeccompActionKill}
+	if err := p.validate(); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := p.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		want uint32
+	}{
+		{"mount", seccompRetKillProcess},
+		{"read", seccompRetAllow},
+		{"execve", seccompRetAllow},
+	} {
+		if got := runFilter(t, prog, seccompAuditArch, c.name); got != c.want {
+			t.Errorf("%s: got %#x, want %#x", c.name, got, c.want)
+		}
+	}
+	// other architectures are killed
+	if got := runFilter(t, prog, seccompAuditArch+1, "read"); got != seccompRetKillProcess {
+		t.Errorf("foreign arch: got %#x", got)
+	}
+	// unknown names are skipped, not compiled to a rule
+	if len(prog) != 6+2+1 {
+		t.Errorf("program of one known syscall has %d instructions", len(prog))
+	}
+}
+
+func TestSeccompCompileAllow(t *testing.T) {
+	p := &SeccompProfile{DefaultAction: SeccompActionErrno, Allow: []string{"read"}, errno: syscall.EACCES}
+	prog, err := p.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errno := seccompRetErrno | uint32(syscall.EACCES)
+	for _, c := range []struct {
+		name string
+		want uint32
+	}{
+		{"read", seccompRetAllow},
+		{"openat", errno},
+		{"socket", errno},
+	} {
+		if got := runFilter(t, prog, seccompAuditArch, c.name); got != c.want {
+			t.Errorf("%s: got %#x, want %#x", c.name, got, c.want)
+		}
+	}
+	// the helper can always exec and report a failed exec
+	for _, name := range seccompExecSyscalls {
+		if _, ok := syscallNumbers[name]; !ok {
+			continue
+		}
+		if got := runFilter(t, prog, seccompAuditArch, name); got != seccompRetAllow {
+			t.Errorf("exec syscall %s: got %#x", name, got)
+		}
+	}
+}
+
+func TestSeccompCompileArgs(t *testing.T) {
+	p := builtinSeccompProfiles["no-network"]
+	prog, err := p.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eacces := seccompRetErrno | uint32(syscall.EACCES)
+	for _, c := range []struct {
+		name string
+		args []uint32
+		want uint32
+	}{
+		{"socket", []uint32{syscall.AF_UNIX}, seccompRetAllow},
+		{"socket", []uint32{syscall.AF_INET}, eacces},
+		{"socket", []uint32{syscall.AF_INET6}, eacces},
+		{"io_uring_setup", nil, seccompRetErrno | uint32(syscall.EPERM)},
+		{"connect", []uint32{syscall.AF_INET}, seccompRetAllow},
+	} {
+		if got := runFilter(t, prog, seccompAuditArch, c.name, c.args...); got != c.want {
+			t.Errorf("%s %v: got %#x, want %#x", c.name, c.args, got, c.want)
+		}
+	}
+
+	p = builtinSeccompProfiles["read-only-fs-syscalls"]
+	if prog, err = p.compile(); err != nil {
+		t.Fatal(err)
+	}
+	erofs := seccompRetErrno | uint32(syscall.EROFS)
+	for _, c := range []struct {
+		name string
+		args []uint32
+		want uint32
+	}{
+		{"openat", []uint32{0, 0, syscall.O_RDONLY}, seccompRetAllow},
+		{"openat", []uint32{0, 0, syscall.O_WRONLY}, erofs},
+		{"openat", []uint32{0, 0, syscall.O_RDONLY | syscall.O_CREAT}, erofs},
+		{"unlinkat", nil, erofs},
+		{"openat2", nil, seccompRetErrno | uint32(syscall.ENOSYS)},
+		{"read", nil, seccompRetAllow},
+	} {
+		if got := runFilter(t, prog, seccompAuditArch, c.name, c.args...); got != c.want {
+			t.Errorf("%s %v: got %#x, want %#x", c.name, c.args, got, c.want)
+		}
+	}
+}
+
+func TestSeccompProfileValidate(t *testing.T) {
+	for _, p := range []*SeccompProfile{
+		{DefaultAction: "trap"},
+		{DenyAction: "log"},
+	} {
+		if err := p.validate(); err == nil {
+			t.Errorf("profile %+v validated", p)
+		}
+	}
+	p := &SeccompProfile{}
+	if err := p.validate(); err != nil || p.DefaultAction != SeccompActionAllow {
+		t.Errorf("empty profile: %v, default action %q", err, p.DefaultAction)
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/internal/chunk"
 	"yunion.io/x/log"
 )
 
@@ -37,13 +40,52 @@ type Commander struct {
 	// stream apis.Executor_ExecCommandServer
 
 	c      *exec.Cmd
+	sn     uint32
+	name   string
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	stderr io.ReadCloser
+	// files passed by client, closed once the process started
+	files *commandFiles
 
 	wg       *sync.WaitGroup
 	stdoutCh chan struct{}
 	stderrCh chan struct{}
+
+	startedAt time.Time
+	trace     TraceContext
+	// dedupId is the id deduplicating the command, released
+	// if the command fails to start
+	dedupId string
+	sandbox *sandboxSpec
+	// streamed commands run on an Exec stream, their output tails are
+	// kept for clients resuming after an upgrade
+	streamed   bool
+	stdoutTail *outputTail
+	stderrTail *outputTail
+	// stdinPending is stdin not written yet when handed over
+	stdinPending []byte
+	// window is granted to the client of the Exec stream, 0 without
+	// flow control
+	window       uint32
+	stdinQueue   *stdinQueue
+	stdoutWindow *flowWindow
+	stderrWindow *flowWindow
+	// stdinOffset counts stdin written or dropped after the process
+	// stopped reading it, guarded by lock
+	stdinOffset uint64
+	// adopted processes were started by the previous server and are
+	// reaped by pid instead of exec.Cmd
+	adopted bool
+	// resume is set on commands handed over by the previous server
+	// until their client comes back
+	resume *resumeWait
+
+	// guards pid, startedAt and watchers for List and Attach
+	lock     sync.Mutex
+	pid      int
+	watchers map[*watcher]struct{}
+	exitRes  *apis.WaitResponse
 }
 
 func BytesArrayToStrArray(ba [][]byte) []string {
@@ -67,61 +109,238 @@ func NewCommander(in *apis.Command) *Commander {
 	}
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	return &Commander{
-		c:  cmd,
-		wg: new(sync.WaitGroup),
+		c:    cmd,
+		name: commandLabel(cmd.Path),
+		wg:   new(sync.WaitGroup),
 	}
 }
 
-type Executor struct{}
+type Executor struct {
+	// InjectTraceEnv exports caller provided trace context to child
+	// processes as TRACEPARENT and EXECUTOR_REQUEST_ID
+	InjectTraceEnv bool
+	// DefaultEnv is merged into the environment of commands
+	// running in inherit and login env modes, in default mode only
+	// when the command has no env of its own
+	DefaultEnv []string
+	// LoginProfile is sourced to build the environment of commands
+	// running in login env mode
+	LoginProfile string
+	// ServiceLogDir keeps rotating logs of services,
+	// default is DefaultServiceLogDir
+	ServiceLogDir string
+	// SeccompProfiles are named profiles commands may reference,
+	// in addition to the builtin ones
+	SeccompProfiles map[string]*SeccompProfile
+	// ChunkSize is the max size of output messages, default is
+	// apis.DefaultChunkSize
+	ChunkSize int
+	// CoalesceDelay holds output after a short read for up to the delay
+	// to fill a chunk, 0 sends output as soon as it is read
+	CoalesceDelay time.Duration
+	// MaxBuffered caps bytes of a command buffered for its Exec stream,
+	// a third for each of stdin, stdout and stderr, default is
+	// apis.DefaultMaxBuffered
+	MaxBuffered int
+	// StallTimeout fails output no client acknowledged for so long,
+	// 0 waits forever
+	StallTimeout time.Duration
+}
+
+func (e *Executor) newCommander(ctx context.Context, req *apis.Command, streamed bool, files *commandFiles) (*Commander, error) {
+	// a command created during a handoff would be lost
+	gate.acquire()
+	defer gate.release()
+	dedupId := dedupIdFromIncoming(ctx)
+	if dedupId != "" {
+		if prev, dup := requests.claim(dedupId, req); dup {
+			log.Warningf("Duplicate exec %s", dedupId)
+			return nil, duplicateRequestError(dedupId, prev, req)
+		}
+	}
+	env, err := e.CommandEnv(req)
+	if err != nil {
+		if dedupId != "" {
+			requests.release(dedupId)
+		}
+		return nil, errors.Wrap(err, "prepare env")
+	}
+	in := req
+	if env != nil && !strings.Contains(string(req.Path), "/") {
+		// resolve with PATH of the command instead of the server
+		if path, err := LookPath(string(req.Path), env, string(req.Dir)); err == nil {
+			resolved := *req
+			resolved.Path = []byte(path)
+			in = &resolved
+		}
+	}
+	sandbox, err := e.sandboxSpec(req)
+	if err != nil {
+		if dedupId != "" {
+			requests.release(dedupId)
+		}
+		return nil, err
+	}
+	cm := NewCommander(in)
+	cm.c.Env = env
+	if files != nil {
+		cm.files = files
+		cm.c.ExtraFiles = files.extra
+	}
+	cm.trace = TraceContextFromIncoming(ctx)
+	if e.InjectTraceEnv && !cm.trace.IsZero() {
+		if cm.c.Env == nil {
+			cm.c.Env = os.Environ()
+		}
+		cm.c.Env = append(cm.c.Env, cm.trace.Env()...)
+	}
+	if sandbox != nil {
+		if err := sandbox.wrap(cm.c); err != nil {
+			if dedupId != "" {
+				requests.release(dedupId)
+			}
+			return nil, errors.Wrap(err, "prepare sandbox")
+		}
+		cm.sandbox = sandbox
+	}
+	if streamed {
+		cm.streamed = true
+		cm.stdoutTail = new(outputTail)
+		cm.stderrTail = new(outputTail)
+	}
+	cm.sn = NewSN()
+	log.Infof("%d/%d Exec %s%s", cm.sn, Len(cmds), req.String(), cm.trace)
+	cmds.Store(cm.sn, cm)
+	if dedupId != "" {
+		cm.dedupId = dedupId
+		requests.set(dedupId, cm.sn)
+	}
+	return cm, nil
+}
 
 func (e *Executor) ExecCommand(ctx context.Context, req *apis.Command) (*apis.Sn, error) {
-	cm := NewCommander(req)
-	sn := NewSN()
-	log.Infof("%d/%d Exec %s", sn, Len(cmds), req.String())
-	cmds.Store(sn, cm)
-	return &apis.Sn{Sn: sn}, nil
+	if id := dedupIdFromIncoming(ctx); id != "" {
+		// retry of a lost response, hand out the same command
+		if sn, ok := requests.lookup(id, req); ok {
+			if _, ok := cmds.Load(sn); ok {
+				log.Infof("%d Exec retried %s", sn, id)
+				return &apis.Sn{Sn: sn}, nil
+			}
+		}
+	}
+	cm, err := e.newCommander(ctx, req, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &apis.Sn{Sn: cm.sn}, nil
 }
 
-func (e *Executor) Start(ctx context.Context, req *apis.StartInput) (*apis.StartResponse, error) {
-	icm, ok := cmds.Load(req.Sn)
-	if !ok {
-		return nil, errors.Errorf("unknown sn %d", req.Sn)
+func (m *Commander) start(in *apis.StartInput) error {
+	err := m.startProcess(in)
+	if err != nil && m.dedupId != "" {
+		// never ran, a retry of the request may run it
+		requests.release(m.dedupId)
 	}
+	return err
+}
+
+func (m *Commander) startProcess(in *apis.StartInput) error {
+	gate.acquire()
+	defer gate.release()
 	var (
-		m   = icm.(*Commander)
-		err error
+		err            error
+		stdinReader    *os.File
+		combinedWriter *os.File
 	)
-	if req.HasStdin {
-		m.stdin, err = m.c.StdinPipe()
+	if f := m.files; f != nil {
+		// passed instead of pipes, the child holds its own copies
+		defer f.close()
+		if f.stdin != nil {
+			m.c.Stdin = f.stdin
+		}
+		if f.stdout != nil {
+			m.c.Stdout = f.stdout
+		}
+		if f.stderr != nil {
+			m.c.Stderr = f.stderr
+		}
+	}
+	if in.HasStdin {
+		// own pipe instead of StdinPipe, its fd is handed over on upgrade
+		var pw *os.File
+		stdinReader, pw, err = os.Pipe()
 		if err != nil {
-			return &apis.StartResponse{
-				Success: false,
-				Error:   []byte(err.Error()),
-			}, nil
+			return err
 		}
+		m.c.Stdin = stdinReader
+		m.stdin = pw
 	}
-	if req.HasStdout {
-		m.stdout, err = m.c.StdoutPipe()
+	if in.CombinedOutput {
+		var pr *os.File
+		pr, combinedWriter, err = os.Pipe()
 		if err != nil {
-			return &apis.StartResponse{
-				Success: false,
-				Error:   []byte(err.Error()),
-			}, nil
+			return err
 		}
+		m.c.Stdout = combinedWriter
+		m.c.Stderr = combinedWriter
+		m.stdout = pr
+	} else {
+		if in.HasStdout {
+			m.stdout, err = m.c.StdoutPipe()
+			if err != nil {
+				return err
+			}
+		}
+		if in.HasStderr {
+			m.stderr, err = m.c.StderrPipe()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = startSandboxed(m.c, m.sandbox)
+	// child holds its own copies
+	if stdinReader != nil {
+		stdinReader.Close()
+	}
+	if combinedWriter != nil {
+		combinedWriter.Close()
+	}
+	if err != nil {
+		if m.stdin != nil {
+			m.stdin.Close()
+		}
+		if m.stdout != nil && in.CombinedOutput {
+			m.stdout.Close()
+		}
+		log.Errorf("%d Start failed: %s%s", m.sn, err, m.trace)
+		observeStartFailed(m.name)
+		return err
+	}
+	m.lock.Lock()
+	m.startedAt = time.Now()
+	m.pid = m.c.Process.Pid
+	m.lock.Unlock()
+	observeStarted(m.name)
+	log.Infof("%d Started pid %d%s", m.sn, m.c.Process.Pid, m.trace)
+	return nil
+}
+
+func (e *Executor) Start(ctx context.Context, req *apis.StartInput) (*apis.StartResponse, error) {
+	icm, ok := cmds.Load(req.Sn)
+	if !ok {
+		return nil, errors.Errorf("unknown sn %d", req.Sn)
+	}
+	m := icm.(*Commander)
+	m.claimLegacy()
+	if req.HasStdout {
 		m.stdoutCh = make(chan struct{})
 	}
 	if req.HasStderr {
-		m.stderr, err = m.c.StderrPipe()
-		if err != nil {
-			return &apis.StartResponse{
-				Success: false,
-				Error:   []byte(err.Error()),
-			}, nil
-		}
 		m.stderrCh = make(chan struct{})
 	}
-
-	if err := m.c.Start(); err != nil {
+	if err := m.start(req); err != nil {
 		return &apis.StartResponse{
 			Success: false,
 			Error:   []byte(err.Error()),
@@ -129,25 +348,40 @@ func (e *Executor) Start(ctx context.Context, req *apis.StartInput) (*apis.Start
 	}
 
 	return &apis.StartResponse{
-		Success: true,
-		Error:   nil,
+		Success:  true,
+		Error:    nil,
+		Sn:       req.Sn,
+		Landlock: m.landlockStatus(),
 	}, nil
 }
 
-func (e *Executor) Wait(ctx context.Context, in *apis.Sn) (*apis.WaitResponse, error) {
-	icm, ok := cmds.Load(in.Sn)
-	if !ok {
-		return nil, errors.Errorf("unknown sn %d", in.Sn)
+// wait waits process exit, pipes are closed after wait returns,
+// so all reads from stdout and stderr must have completed
+func (m *Commander) wait() *apis.WaitResponse {
+	m.lock.Lock()
+	res, pid, startedAt := m.exitRes, m.pid, m.startedAt
+	m.lock.Unlock()
+	if res != nil {
+		// reaped before the handoff
+		return res
 	}
-	var (
-		m   = icm.(*Commander)
-		err error
-	)
+	if pid != 0 {
+		// reaped under the gate so the status can't be lost by a handoff
+		waitExited(pid)
+	}
+	gate.acquire()
+	defer gate.release()
 
-	err = m.c.Wait()
+	var err error
+	if m.adopted {
+		err = m.waitAdopted()
+	} else {
+		err = m.c.Wait()
+	}
 	var (
 		exitStatus uint32
 		errContent string
+		exited     = true
 	)
 	if err != nil {
 		if exiterr, ok := err.(*exec.ExitError); ok {
@@ -160,10 +394,82 @@ func (e *Executor) Wait(ctx context.Context, in *apis.Sn) (*apis.WaitResponse, e
 		} else {
 			// command not found or io problem or wait was already called
 			errContent = err.Error()
+			exited = false
 		}
 	} else {
 		exitStatus = 0
 	}
+	if exited {
+		log.Infof("%d Exited status %d%s", m.sn, exitStatus, m.trace)
+	} else {
+		log.Errorf("%d Wait failed: %s%s", m.sn, errContent, m.trace)
+	}
+	if !startedAt.IsZero() {
+		observeExited(m.name, startedAt, syscall.WaitStatus(exitStatus), exited)
+	}
+	res = &apis.WaitResponse{
+		ExitStatus: exitStatus,
+		ErrContent: []byte(errContent),
+	}
+	m.closeWatchers(res)
+	return res
+}
+
+// waitAdopted reaps a process started by the previous server and
+// closes its pipes like exec.Cmd.Wait
+func (m *Commander) waitAdopted() error {
+	state, err := m.c.Process.Wait()
+	for _, f := range []io.Closer{m.stdin, m.stdout, m.stderr} {
+		if f != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !state.Success() {
+		return &exec.ExitError{ProcessState: state}
+	}
+	return nil
+}
+
+// writeStdin writes data to stdin of the process, a write interrupted by
+// a handoff leaves the rest to the next server
+func (m *Commander) writeStdin(data []byte) (int, error) {
+	for {
+		gate.acquire()
+		if len(m.stdinPending) > 0 {
+			// handed over by the previous server
+			data = append(m.stdinPending, data...)
+			m.stdinPending = nil
+		}
+		if len(data) == 0 {
+			gate.release()
+			return 0, nil
+		}
+		n, err := m.stdin.Write(data)
+		m.addStdinOffset(n)
+		if err != nil && os.IsTimeout(err) {
+			// left to the next server if the handoff goes on
+			m.stdinPending = data[n:]
+			data = nil
+			gate.release()
+			continue
+		}
+		gate.release()
+		return n, err
+	}
+}
+
+func (e *Executor) Wait(ctx context.Context, in *apis.Sn) (*apis.WaitResponse, error) {
+	icm, ok := cmds.Load(in.Sn)
+	if !ok {
+		return nil, errors.Errorf("unknown sn %d", in.Sn)
+	}
+	m := icm.(*Commander)
+	m.claimLegacy()
+
+	res := m.wait()
 	if m.stdout != nil {
 		<-m.stdoutCh
 	}
@@ -173,10 +479,7 @@ func (e *Executor) Wait(ctx context.Context, in *apis.Sn) (*apis.WaitResponse, e
 
 	m.wg.Wait()
 	cmds.Delete(in.Sn)
-	return &apis.WaitResponse{
-		ExitStatus: exitStatus,
-		ErrContent: []byte(errContent),
-	}, nil
+	return res, nil
 }
 
 func (e *Executor) Kill(ctx context.Context, req *apis.Sn) (*apis.Error, error) {
@@ -186,6 +489,7 @@ func (e *Executor) Kill(ctx context.Context, req *apis.Sn) (*apis.Error, error)
 	}
 
 	m := icm.(*Commander)
+	log.Infof("%d Kill%s", req.Sn, m.trace)
 	err := m.c.Process.Kill()
 	if err != nil {
 		return &apis.Error{Error: []byte(err.Error())}, nil
@@ -207,8 +511,12 @@ func (e *Executor) SendInput(s apis.Executor_SendInputServer) error {
 				if m.stdin == nil {
 					return errors.New("Process stdin not init")
 				}
+				m.claimLegacy()
 			}
 			if m != nil {
+				if _, e := m.writeStdin(nil); e != nil {
+					return errors.Wrap(e, "write stdin")
+				}
 				if e := m.stdin.Close(); e != nil {
 					return errors.Wrap(e, "close stdin")
 				}
@@ -228,8 +536,10 @@ func (e *Executor) SendInput(s apis.Executor_SendInputServer) error {
 			if m.stdin == nil {
 				return errors.New("Process stdin not init")
 			}
+			m.claimLegacy()
 		}
-		_, err = m.stdin.Write(input.Input)
+		n, err := m.writeStdin(input.Input)
+		stdinBytes.Add(float64(n))
 		if err != nil {
 			return s.SendAndClose(&apis.Error{
 				Error: []byte(err.Error()),
@@ -243,13 +553,9 @@ func (e *Executor) FetchStdout(sn *apis.Sn, s apis.Executor_FetchStdoutServer) e
 	if !ok {
 		return errors.Errorf("unknown sn %d", sn.Sn)
 	}
-	var (
-		m    = icm.(*Commander)
-		data = make([]byte, 4096)
-		err  error
-		n    int
-	)
+	m := icm.(*Commander)
 
+	m.claimLegacy()
 	if m.stdout == nil {
 		return errors.New("Process stdout not init")
 	} else {
@@ -259,20 +565,23 @@ func (e *Executor) FetchStdout(sn *apis.Sn, s apis.Executor_FetchStdoutServer) e
 	m.wg.Add(1)
 	defer m.wg.Done()
 	s.Send(&apis.Stdout{Start: true})
-	for {
-		n, err = m.stdout.Read(data)
-		if err == io.EOF {
-			return s.Send(&apis.Stdout{Closed: true})
-		} else if pe, ok := err.(*os.PathError); ok && pe.Err == os.ErrClosed {
-			return s.Send(&apis.Stdout{Closed: true})
-		} else if err != nil {
-			return s.Send(&apis.Stdout{RuntimeError: []byte(err.Error())})
-		}
-		err = s.Send(&apis.Stdout{Stdout: data[:n]})
-		if err != nil {
-			return err
-		}
+	data := chunk.Get(e.chunkSize())
+	defer chunk.Put(data)
+	err, sendErr := pumpPipe(m.stdout, data, e.CoalesceDelay, nil, func(data []byte) {
+		stdoutBytes.Add(float64(len(data)))
+		m.broadcast(stdoutFrame(data))
+	}, func(data []byte) error {
+		return s.Send(&apis.Stdout{Stdout: data})
+	})
+	if sendErr != nil {
+		return sendErr
 	}
+	if err == io.EOF {
+		return s.Send(&apis.Stdout{Closed: true})
+	} else if pe, ok := err.(*os.PathError); ok && pe.Err == os.ErrClosed {
+		return s.Send(&apis.Stdout{Closed: true})
+	}
+	return s.Send(&apis.Stdout{RuntimeError: []byte(err.Error())})
 }
 
 func (e *Executor) FetchStderr(sn *apis.Sn, s apis.Executor_FetchStderrServer) error {
@@ -280,13 +589,9 @@ func (e *Executor) FetchStderr(sn *apis.Sn, s apis.Executor_FetchStderrServer) e
 	if !ok {
 		return errors.Errorf("unknown sn %d", sn.Sn)
 	}
-	var (
-		m    = icm.(*Commander)
-		data = make([]byte, 4096)
-		err  error
-		n    int
-	)
+	m := icm.(*Commander)
 
+	m.claimLegacy()
 	if m.stderr == nil {
 		return errors.New("Process stderr not init")
 	} else {
@@ -296,18 +601,21 @@ func (e *Executor) FetchStderr(sn *apis.Sn, s apis.Executor_FetchStderrServer) e
 	m.wg.Add(1)
 	defer m.wg.Done()
 	s.Send(&apis.Stderr{Start: true})
-	for {
-		n, err = m.stderr.Read(data)
-		if err == io.EOF {
-			return s.Send(&apis.Stderr{Closed: true})
-		} else if pe, ok := err.(*os.PathError); ok && pe.Err == os.ErrClosed {
-			return s.Send(&apis.Stderr{Closed: true})
-		} else if err != nil {
-			return s.Send(&apis.Stderr{RuntimeError: []byte(err.Error())})
-		}
-		err = s.Send(&apis.Stderr{Stderr: data[:n]})
-		if err != nil {
-			return err
-		}
+	data := chunk.Get(e.chunkSize())
+	defer chunk.Put(data)
+	err, sendErr := pumpPipe(m.stderr, data, e.CoalesceDelay, nil, func(data []byte) {
+		stderrBytes.Add(float64(len(data)))
+		m.broadcast(stderrFrame(data))
+	}, func(data []byte) error {
+		return s.Send(&apis.Stderr{Stderr: data})
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err == io.EOF {
+		return s.Send(&apis.Stderr{Closed: true})
+	} else if pe, ok := err.(*os.PathError); ok && pe.Err == os.ErrClosed {
+		return s.Send(&apis.Stderr{Closed: true})
 	}
+	return s.Send(&apis.Stderr{RuntimeError: []byte(err.Error())})
 }
@@ -0,0 +1,547 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/internal/chunk"
+	"yunion.io/x/log"
+)
+
+const (
+	ServiceStarting = "starting"
+	ServiceRunning  = "running"
+	ServiceBackoff  = "backoff"
+	ServiceStopping = "stopping"
+	ServiceStopped  = "stopped"
+	// ServiceExited is a service finished without restart by its policy
+	ServiceExited = "exited"
+	// ServiceFailed is a service given up after max restarts
+	ServiceFailed = "failed"
+)
+
+const (
+	DefaultServiceLogDir = "/var/log/executor"
+
+	defaultServiceBackoff     = time.Second
+	defaultServiceMaxBackoff  = time.Minute
+	defaultServiceLogMaxBytes = 10 << 20
+	defaultServiceLogMaxFiles = 5
+	defaultServiceStopTimeout = 10 * time.Second
+)
+
+var serviceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+var (
+	servicesLock sync.Mutex
+	services     = map[string]*service{}
+)
+
+// service supervises a command, restarting it by the restart policy,
+// services live as long as the server process
+type service struct {
+	spec    *apis.ServiceSpec
+	path    string
+	env     []string
+	sandbox *sandboxSpec
+	logPath string
+	log     *rotatingLog
+
+	stopCh chan struct{}
+	done   chan struct{}
+
+	lock  sync.Mutex
+	state string
+	pid   int
+	// output is the read end of the output pipe of the process
+	output *os.File
+	// adopted is the process handed over by the previous server
+	adopted    *os.Process
+	restarts   uint32
+	startedAt  time.Time
+	lastStatus uint32
+	lastError  string
+}
+
+func durationMs(ms int64, def time.Duration) time.Duration {
+	if ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return def
+}
+
+func (s *service) backoff() time.Duration {
+	return durationMs(s.spec.BackoffMs, defaultServiceBackoff)
+}
+
+func (s *service) maxBackoff() time.Duration {
+	return durationMs(s.spec.MaxBackoffMs, defaultServiceMaxBackoff)
+}
+
+func (s *service) setState(state string) {
+	s.lock.Lock()
+	s.state = state
+	s.lock.Unlock()
+}
+
+func (s *service) status() *apis.ServiceStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := &apis.ServiceStatus{
+		Name:           s.spec.Name,
+		State:          s.state,
+		Restarts:       s.restarts,
+		LastExitStatus: s.lastStatus,
+		LastError:      []byte(s.lastError),
+		LogPath:        s.logPath,
+		Pid:            int32(s.pid),
+		Spec:           s.spec,
+	}
+	if !s.startedAt.IsZero() {
+		st.StartedAt = s.startedAt.Unix()
+	}
+	return st
+}
+
+func (s *service) finished() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// runOnce starts the command and waits for it, failed tells whether
+// it exited non-zero, got killed or could not start
+func (s *service) runOnce() (failed bool) {
+	if s.adopted != nil {
+		proc := s.adopted
+		s.adopted = nil
+		return s.waitProcess(proc)
+	}
+	cmd := exec.Command(s.path, BytesArrayToStrArray(s.spec.Command.Args)...)
+	cmd.Env = s.env
+	cmd.Dir = string(s.spec.Command.Dir)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if s.sandbox != nil {
+		if err := s.sandbox.wrap(cmd); err != nil {
+			s.lock.Lock()
+			s.lastError = err.Error()
+			s.lock.Unlock()
+			return true
+		}
+	}
+	// own pipe instead of copying by exec.Cmd, it is handed over on upgrade
+	output, w, err := os.Pipe()
+	if err != nil {
+		s.lock.Lock()
+		s.lastError = err.Error()
+		s.lock.Unlock()
+		return true
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	// started under lock, stop sees either no process or its pid
+	gate.acquire()
+	s.lock.Lock()
+	select {
+	case <-s.stopCh:
+		s.lock.Unlock()
+		gate.release()
+		output.Close()
+		w.Close()
+		return false
+	default:
+	}
+	s.state = ServiceStarting
+	s.startedAt = time.Now()
+	err = startSandboxed(cmd, s.sandbox)
+	w.Close()
+	if err != nil {
+		s.lastError = err.Error()
+		s.lock.Unlock()
+		gate.release()
+		output.Close()
+		log.Errorf("service %s start failed: %s", s.spec.Name, err)
+		return true
+	}
+	s.state = ServiceRunning
+	s.pid = cmd.Process.Pid
+	s.output = output
+	s.lock.Unlock()
+	gate.release()
+	log.Infof("service %s started pid %d", s.spec.Name, cmd.Process.Pid)
+	// exec.Cmd has nothing to release with a file as output
+	return s.waitProcess(cmd.Process)
+}
+
+// waitProcess copies output of the running process to the log until
+// the process exits
+func (s *service) waitProcess(proc *os.Process) (failed bool) {
+	s.lock.Lock()
+	output := s.output
+	s.lock.Unlock()
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		data := chunk.Get(apis.DefaultChunkSize)
+		defer chunk.Put(data)
+		pumpPipe(output, data, 0, nil, func(data []byte) {
+			s.log.Write(data)
+		}, nil)
+	}()
+
+	// reaped under the gate so the status can't be lost by a handoff
+	waitExited(proc.Pid)
+	gate.acquire()
+	state, err := proc.Wait()
+	var ws syscall.WaitStatus
+	s.lock.Lock()
+	s.pid = 0
+	s.lastError = ""
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		ws = state.Sys().(syscall.WaitStatus)
+		s.lastStatus = uint32(ws)
+	}
+	s.lock.Unlock()
+	gate.release()
+
+	<-copied
+	s.lock.Lock()
+	s.output = nil
+	s.lock.Unlock()
+	output.Close()
+	if err != nil {
+		log.Errorf("service %s wait failed: %s", s.spec.Name, err)
+		return true
+	}
+	log.Infof("service %s exited status %d", s.spec.Name, ws)
+	return ws != 0
+}
+
+func (s *service) run() {
+	defer close(s.done)
+	defer s.log.Close()
+
+	backoff := s.backoff()
+	for {
+		startAt := time.Now()
+		failed := s.runOnce()
+		select {
+		case <-s.stopCh:
+			s.setState(ServiceStopped)
+			return
+		default:
+		}
+
+		switch s.spec.Restart {
+		case apis.RestartPolicy_RESTART_ALWAYS:
+		case apis.RestartPolicy_RESTART_ON_FAILURE:
+			if !failed {
+				s.setState(ServiceExited)
+				return
+			}
+		default:
+			s.setState(ServiceExited)
+			return
+		}
+		s.lock.Lock()
+		restarts := s.restarts
+		s.lock.Unlock()
+		if s.spec.MaxRestarts > 0 && restarts >= s.spec.MaxRestarts {
+			log.Errorf("service %s given up after %d restarts", s.spec.Name, restarts)
+			s.setState(ServiceFailed)
+			return
+		}
+		if time.Since(startAt) > s.maxBackoff() {
+			// ran stable for a while
+			backoff = s.backoff()
+		}
+
+		s.setState(ServiceBackoff)
+		log.Infof("service %s restart in %s", s.spec.Name, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-s.stopCh:
+			s.setState(ServiceStopped)
+			return
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff() {
+			backoff = s.maxBackoff()
+		}
+		s.lock.Lock()
+		s.restarts++
+		s.lock.Unlock()
+	}
+}
+
+// signal sends sig to the process group of the running process
+func (s *service) signal(sig syscall.Signal) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pid == 0 {
+		return false
+	}
+	syscall.Kill(-s.pid, sig)
+	return true
+}
+
+// stop terminates the process group with SIGTERM, then SIGKILL after
+// the stop timeout, and waits for the supervisor to return
+func (s *service) stop() {
+	gate.acquire()
+	s.lock.Lock()
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+	if s.state == ServiceRunning || s.state == ServiceStarting {
+		s.state = ServiceStopping
+	}
+	s.lock.Unlock()
+	gate.release()
+
+	s.signal(syscall.SIGTERM)
+	select {
+	case <-s.done:
+		return
+	case <-time.After(durationMs(s.spec.StopTimeoutMs, defaultServiceStopTimeout)):
+	}
+	if s.signal(syscall.SIGKILL) {
+		log.Warningf("service %s not stopped in time, killed", s.spec.Name)
+	}
+	<-s.done
+}
+
+func serviceLogLimits(spec *apis.ServiceSpec) (maxBytes int64, maxFiles int) {
+	maxBytes = spec.LogMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultServiceLogMaxBytes
+	}
+	maxFiles = int(spec.LogMaxFiles)
+	if maxFiles <= 0 {
+		maxFiles = defaultServiceLogMaxFiles
+	}
+	return maxBytes, maxFiles
+}
+
+func openServiceLog(path string, spec *apis.ServiceSpec) (*rotatingLog, error) {
+	maxBytes, maxFiles := serviceLogLimits(spec)
+	rl, err := openRotatingLog(path, maxBytes, maxFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "open service log")
+	}
+	return rl, nil
+}
+
+// restoreService takes over a service of the previous server, with its
+// running process
+func restoreService(hs *handoffService) {
+	s := &service{
+		spec:       hs.Spec,
+		path:       hs.Path,
+		env:        hs.Env,
+		sandbox:    hs.Sandbox,
+		logPath:    hs.LogPath,
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+		state:      hs.State,
+		restarts:   hs.Restarts,
+		startedAt:  hs.StartedAt,
+		lastStatus: hs.LastStatus,
+		lastError:  hs.LastError,
+	}
+	servicesLock.Lock()
+	services[hs.Spec.Name] = s
+	servicesLock.Unlock()
+	if hs.Finished {
+		close(s.done)
+		return
+	}
+	if hs.Pid != 0 {
+		s.pid = hs.Pid
+		s.adopted, _ = os.FindProcess(hs.Pid)
+		s.output = inherited(hs.Output, hs.Spec.Name)
+		if s.output == nil {
+			// output already closed by the process
+			s.output, _ = os.Open(os.DevNull)
+		}
+	}
+	rl, err := openServiceLog(hs.LogPath, hs.Spec)
+	if err != nil {
+		// the process is ours anyway, the log is opened again on write
+		log.Errorf("service %s: %s", hs.Spec.Name, err)
+		maxBytes, maxFiles := serviceLogLimits(hs.Spec)
+		rl = &rotatingLog{path: hs.LogPath, maxBytes: maxBytes, maxFiles: maxFiles, failing: true}
+	}
+	s.log = rl
+	if hs.Stopping {
+		close(s.stopCh)
+	}
+	go s.run()
+	if hs.Stopping {
+		go s.stop()
+	}
+}
+
+func (e *Executor) serviceLogDir() string {
+	if len(e.ServiceLogDir) > 0 {
+		return e.ServiceLogDir
+	}
+	return DefaultServiceLogDir
+}
+
+func (e *Executor) newService(spec *apis.ServiceSpec) (*service, error) {
+	if !serviceNameRegexp.MatchString(spec.Name) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid service name %q", spec.Name)
+	}
+	if spec.Command == nil || len(spec.Command.Path) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing command")
+	}
+	env, err := e.CommandEnv(spec.Command)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare env")
+	}
+	path := string(spec.Command.Path)
+	if !strings.Contains(path, "/") {
+		if env == nil {
+			env = os.Environ()
+		}
+		resolved, err := LookPath(path, env, string(spec.Command.Dir))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", path, err)
+		}
+		path = resolved
+	}
+
+	sandbox, err := e.sandboxSpec(spec.Command)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	dir := e.serviceLogDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "create service log dir")
+	}
+	logPath := filepath.Join(dir, spec.Name+".log")
+	rl, err := openServiceLog(logPath, spec)
+	if err != nil {
+		return nil, err
+	}
+	return &service{
+		spec:    spec,
+		path:    path,
+		env:     env,
+		sandbox: sandbox,
+		logPath: logPath,
+		log:     rl,
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+		state:   ServiceStarting,
+	}, nil
+}
+
+func loadService(name string) (*service, error) {
+	servicesLock.Lock()
+	defer servicesLock.Unlock()
+	s, ok := services[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", name)
+	}
+	return s, nil
+}
+
+// runningService returns the error of starting name again while a
+// service of it is running, the caller holds servicesLock
+func runningService(name string) error {
+	if prev, ok := services[name]; ok && !prev.finished() {
+		return status.Errorf(codes.AlreadyExists, "service %q is %s", name, prev.status().State)
+	}
+	return nil
+}
+
+func (e *Executor) StartService(ctx context.Context, spec *apis.ServiceSpec) (*apis.ServiceStatus, error) {
+	servicesLock.Lock()
+	err := runningService(spec.Name)
+	servicesLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// looking up path and opening the log are done without locks
+	s, err := e.newService(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// a service started during a handoff would be lost
+	gate.acquire()
+	defer gate.release()
+	servicesLock.Lock()
+	defer servicesLock.Unlock()
+	// checked again, the same service may be started meanwhile
+	if err := runningService(spec.Name); err != nil {
+		s.log.Close()
+		return nil, err
+	}
+	log.Infof("service %s Start %s restart %s%s", spec.Name, spec.Command.String(), spec.Restart, TraceContextFromIncoming(ctx))
+	services[spec.Name] = s
+	go s.run()
+	return s.status(), nil
+}
+
+func (e *Executor) StopService(ctx context.Context, req *apis.ServiceName) (*apis.ServiceStatus, error) {
+	s, err := loadService(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("service %s Stop%s", req.Name, TraceContextFromIncoming(ctx))
+	s.stop()
+	servicesLock.Lock()
+	if services[req.Name] == s {
+		delete(services, req.Name)
+	}
+	servicesLock.Unlock()
+	return s.status(), nil
+}
+
+func (e *Executor) GetService(ctx context.Context, req *apis.ServiceName) (*apis.ServiceStatus, error) {
+	s, err := loadService(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(), nil
+}
+
+func (e *Executor) ListServices(ctx context.Context, _ *apis.Empty) (*apis.ServiceListResponse, error) {
+	servicesLock.Lock()
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := &apis.ServiceListResponse{}
+	for _, name := range names {
+		res.Services = append(res.Services, services[name].status())
+	}
+	servicesLock.Unlock()
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestStartServiceOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	e := &Executor{ServiceLogDir: dir}
+	spec := &apis.ServiceSpec{
+		Name: "test-start-once",
+		Command: &apis.Command{
+			Path: []byte("/bin/sleep"),
+			Args: [][]byte{[]byte("60")},
+		},
+		StopTimeoutMs: 100,
+	}
+	defer e.StopService(context.Background(), &apis.ServiceName{Name: spec.Name})
+
+	// concurrent starts of the same service, only one wins
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = e.StartService(context.Background(), spec)
+		}(i)
+	}
+	wg.Wait()
+	started := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			started++
+		case status.Code(err) != codes.AlreadyExists:
+			t.Errorf("start: %v", err)
+		}
+	}
+	if started != 1 {
+		t.Errorf("service started %d times", started)
+	}
+}
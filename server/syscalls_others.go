@@ -0,0 +1,8 @@
+// +build !linux linux,!amd64,!arm64
+
+package server
+
+// seccomp is unsupported without syscall numbers of the architecture
+const seccompAuditArch = 0
+
+var syscallNumbers = map[string]uint32{}
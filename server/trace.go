@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"yunion.io/x/executor/apis"
+)
+
+const maxRequestIdLength = 128
+
+// version-traceid-parentid-flags, https://www.w3.org/TR/trace-context/
+var traceparentRegexp = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+type TraceContext struct {
+	Traceparent string
+	RequestId   string
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if vals := md.Get(key); len(vals) > 0 {
+		return strings.TrimSpace(vals[0])
+	}
+	return ""
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceContextFromIncoming extracts traceparent and request id from
+// incoming grpc metadata, malformed values are dropped
+func TraceContextFromIncoming(ctx context.Context) TraceContext {
+	var tc TraceContext
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return tc
+	}
+	if tp := strings.ToLower(firstMetadata(md, apis.MetadataTraceparent)); traceparentRegexp.MatchString(tp) {
+		tc.Traceparent = tp
+	}
+	if id := firstMetadata(md, apis.MetadataRequestId); len(id) <= maxRequestIdLength && isPrintable(id) {
+		tc.RequestId = id
+	}
+	return tc
+}
+
+func (tc TraceContext) IsZero() bool {
+	return tc.Traceparent == "" && tc.RequestId == ""
+}
+
+// String formats trace context as log fields, empty if nothing provided
+func (tc TraceContext) String() string {
+	var s string
+	if tc.RequestId != "" {
+		s += fmt.Sprintf(" request_id=%s", tc.RequestId)
+	}
+	if tc.Traceparent != "" {
+		s += fmt.Sprintf(" traceparent=%s", tc.Traceparent)
+	}
+	return s
+}
+
+func (tc TraceContext) Env() []string {
+	var env []string
+	if tc.Traceparent != "" {
+		env = append(env, apis.EnvTraceparent+"="+tc.Traceparent)
+	}
+	if tc.RequestId != "" {
+		env = append(env, apis.EnvRequestId+"="+tc.RequestId)
+	}
+	return env
+}
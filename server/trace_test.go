@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"yunion.io/x/executor/apis"
+)
+
+func TestTraceContextFromIncoming(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for _, c := range []struct {
+		name string
+		md   metadata.MD
+		want TraceContext
+	}{
+		{"none", nil, TraceContext{}},
+		{"both", metadata.Pairs(apis.MetadataTraceparent, tp, apis.MetadataRequestId, "req-1"),
+			TraceContext{Traceparent: tp, RequestId: "req-1"}},
+		{"upper case traceparent", metadata.Pairs(apis.MetadataTraceparent, strings.ToUpper(tp)),
+			TraceContext{Traceparent: tp}},
+		{"padded", metadata.Pairs(apis.MetadataTraceparent, " "+tp+" ", apis.MetadataRequestId, " req-1 "),
+			TraceContext{Traceparent: tp, RequestId: "req-1"}},
+		{"short trace id", metadata.Pairs(apis.MetadataTraceparent, "00-4bf92f35-00f067aa0ba902b7-01"),
+			TraceContext{}},
+		{"extra field", metadata.Pairs(apis.MetadataTraceparent, tp+"-00"),
+			TraceContext{}},
+		{"not hex", metadata.Pairs(apis.MetadataTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01"),
+			TraceContext{}},
+		{"request id too long", metadata.Pairs(apis.MetadataRequestId, strings.Repeat("a", maxRequestIdLength+1)),
+			TraceContext{}},
+		{"request id at limit", metadata.Pairs(apis.MetadataRequestId, strings.Repeat("a", maxRequestIdLength)),
+			TraceContext{RequestId: strings.Repeat("a", maxRequestIdLength)}},
+		{"request id not printable", metadata.Pairs(apis.MetadataRequestId, "req\x1b[31m"),
+			TraceContext{}},
+		{"first value wins", metadata.Pairs(apis.MetadataRequestId, "a", apis.MetadataRequestId, "b"),
+			TraceContext{RequestId: "a"}},
+	} {
+		ctx := context.Background()
+		if c.md != nil {
+			ctx = metadata.NewIncomingContext(ctx, c.md)
+		}
+		if got := TraceContextFromIncoming(ctx); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestTraceContextFormat(t *testing.T) {
+	tc := TraceContext{Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", RequestId: "req-1"}
+	if got, want := tc.String(), " request_id=req-1 traceparent="+tc.Traceparent; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+	if got, want := tc.Env(), []string{apis.EnvTraceparent + "=" + tc.Traceparent, apis.EnvRequestId + "=req-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Env: got %q, want %q", got, want)
+	}
+	var zero TraceContext
+	if !zero.IsZero() || zero.String() != "" || zero.Env() != nil {
+		t.Errorf("zero trace context: %q %q", zero.String(), zero.Env())
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"yunion.io/x/executor/apis"
+	"yunion.io/x/executor/client"
+)
+
+const (
+	usageServiceStart  = "service start [-restart always|on-failure|never] [-backoff D] [-max-backoff D] [-max-restarts N] [-log-max-bytes N] [-log-max-files N] [-stop-timeout D] [-seccomp PROFILE] [-user U] [-cap-keep CAPS] [-cap-drop CAPS] [-cap-ambient CAPS] [-nice N] [-ionice CLASS[:PRIO]] [-cpus LIST] [-oom-score-adj N] [-umask MASK] [-unshare net,mount,pid] [-read-only PATH]... [-private-tmp] [-safe] [-landlock-read PATH]... [-landlock-write PATH]... [-landlock-system] [-landlock-required] [-env K=V]... [-dir D] NAME -- cmd args..."
+	usageServiceStop   = "service stop NAME..."
+	usageServiceStatus = "service status NAME"
+	usageServiceLs     = "service ls"
+)
+
+var restartPolicies = map[string]apis.RestartPolicy{
+	"never":      apis.RestartPolicy_RESTART_NEVER,
+	"always":     apis.RestartPolicy_RESTART_ALWAYS,
+	"on-failure": apis.RestartPolicy_RESTART_ON_FAILURE,
+}
+
+func cliService(e *client.Executor, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage:")
+		for _, usage := range []string{usageServiceStart, usageServiceStop, usageServiceStatus, usageServiceLs} {
+			fmt.Fprintf(os.Stderr, "  executor %s\n", usage)
+		}
+		return 2
+	}
+	switch args[0] {
+	case "start":
+		return cliServiceStart(e, args[1:])
+	case "stop":
+		return cliServiceStop(e, args[1:])
+	case "status":
+		return cliServiceStatus(e, args[1:])
+	case "ls", "list":
+		return cliServiceLs(e, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown service subcommand %q\n", args[0])
+	return 2
+}
+
+func cliServiceStart(e *client.Executor, args []string) int {
+	var (
+		env         envFlag
+		dir         string
+		restart     string
+		backoff     time.Duration
+		maxBackoff  time.Duration
+		maxRestarts uint
+		logMaxBytes int64
+		logMaxFiles uint
+		stopTimeout time.Duration
+		seccomp     string
+		user        string
+		caps        capsFlags
+		sched       schedFlags
+		iso         isolationFlags
+		ll          landlockFlags
+	)
+	fs := newFlagSet("service start", usageServiceStart)
+	fs.Var(&env, "env", "KEY=VALUE added to the server environment, can be repeated")
+	fs.StringVar(&dir, "dir", "", "working directory")
+	fs.StringVar(&restart, "restart", "on-failure", "restart policy, always, on-failure or never")
+	fs.DurationVar(&backoff, "backoff", time.Second, "delay before the first restart, doubled on every restart")
+	fs.DurationVar(&maxBackoff, "max-backoff", time.Minute, "max delay between restarts")
+	fs.UintVar(&maxRestarts, "max-restarts", 0, "give up after restarts, 0 is unlimited")
+	fs.Int64Var(&logMaxBytes, "log-max-bytes", 10<<20, "rotate log over this size")
+	fs.UintVar(&logMaxFiles, "log-max-files", 5, "rotated logs kept")
+	fs.DurationVar(&stopTimeout, "stop-timeout", 10*time.Second, "time between SIGTERM and SIGKILL on stop")
+	fs.StringVar(&seccomp, "seccomp", "", "seccomp profile of the server")
+	fs.StringVar(&user, "user", "", "run as user, name or uid")
+	caps.register(fs)
+	sched.register(fs)
+	iso.register(fs)
+	ll.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	// flags end at NAME, -- before the command is optional
+	command := fs.Args()
+	if len(command) > 1 && command[1] == "--" {
+		command = append(command[:1], command[2:]...)
+	}
+	policy, ok := restartPolicies[restart]
+	if !ok || len(command) < 2 {
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown restart policy %q\n", restart)
+		}
+		fs.Usage()
+		return 2
+	}
+	attrs, err := sched.sched()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	isolation, err := iso.isolation()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	in := &apis.Command{
+		Path:           []byte(command[1]),
+		Dir:            []byte(dir),
+		SeccompProfile: seccomp,
+		User:           user,
+		Capabilities:   caps.capabilities(),
+		Sched:          attrs,
+		Isolation:      isolation,
+		Landlock:       ll.landlock(),
+	}
+	for _, arg := range command[2:] {
+		in.Args = append(in.Args, []byte(arg))
+	}
+	if len(env) > 0 {
+		for _, kv := range env {
+			in.Env = append(in.Env, []byte(kv))
+		}
+		in.EnvMode = apis.EnvMode_ENV_INHERIT_OVERRIDE
+	}
+	st, err := e.StartService(context.Background(), &apis.ServiceSpec{
+		Name:          command[0],
+		Command:       in,
+		Restart:       policy,
+		BackoffMs:     int64(backoff / time.Millisecond),
+		MaxBackoffMs:  int64(maxBackoff / time.Millisecond),
+		MaxRestarts:   uint32(maxRestarts),
+		LogMaxBytes:   logMaxBytes,
+		LogMaxFiles:   uint32(logMaxFiles),
+		StopTimeoutMs: int64(stopTimeout / time.Millisecond),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s %s, log %s\n", st.Name, st.State, st.LogPath)
+	return 0
+}
+
+func cliServiceStop(e *client.Executor, args []string) int {
+	fs := newFlagSet("service stop", usageServiceStop)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	ret := 0
+	for _, name := range fs.Args() {
+		st, err := e.StopService(context.Background(), name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ret = 1
+			continue
+		}
+		fmt.Printf("%s %s\n", st.Name, st.State)
+	}
+	return ret
+}
+
+func cliServiceStatus(e *client.Executor, args []string) int {
+	fs := newFlagSet("service status", usageServiceStatus)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	st, err := e.GetService(context.Background(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("name:        %s\n", st.Name)
+	fmt.Printf("state:       %s\n", st.State)
+	fmt.Printf("pid:         %d\n", st.Pid)
+	fmt.Printf("restarts:    %d\n", st.Restarts)
+	if st.StartedAt > 0 {
+		fmt.Printf("started:     %s\n", time.Unix(st.StartedAt, 0).Format(time.RFC3339))
+	}
+	fmt.Printf("last exit:   %s\n", serviceExit(st))
+	fmt.Printf("restart:     %s\n", restartName(st.Spec.GetRestart()))
+	fmt.Printf("command:     %s\n", serviceCommand(st))
+	fmt.Printf("log:         %s\n", st.LogPath)
+	return 0
+}
+
+func cliServiceLs(e *client.Executor, args []string) int {
+	fs := newFlagSet("service ls", usageServiceLs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	list, err := e.ListServices(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tPID\tRESTARTS\tLAST_EXIT\tCOMMAND")
+	for _, st := range list {
+		pid := "-"
+		if st.Pid > 0 {
+			pid = fmt.Sprint(st.Pid)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", st.Name, st.State, pid, st.Restarts, serviceExit(st), serviceCommand(st))
+	}
+	w.Flush()
+	return 0
+}
+
+func restartName(policy apis.RestartPolicy) string {
+	for name, p := range restartPolicies {
+		if p == policy {
+			return name
+		}
+	}
+	return policy.String()
+}
+
+func serviceExit(st *apis.ServiceStatus) string {
+	if len(st.LastError) > 0 {
+		return string(st.LastError)
+	}
+	if st.Restarts == 0 && st.Pid > 0 {
+		// first process still running
+		return "-"
+	}
+	ws := syscall.WaitStatus(st.LastExitStatus)
+	if ws.Signaled() {
+		return fmt.Sprintf("signal %d", ws.Signal())
+	}
+	return fmt.Sprint(ws.ExitStatus())
+}
+
+func serviceCommand(st *apis.ServiceStatus) string {
+	cmd := st.Spec.GetCommand()
+	if cmd == nil {
+		return ""
+	}
+	parts := []string{string(cmd.Path)}
+	for _, arg := range cmd.Args {
+		parts = append(parts, string(arg))
+	}
+	return strings.Join(parts, " ")
+}